@@ -0,0 +1,599 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+	"github.com/lfsc09/k-test-n-stress/mocker"
+	"github.com/spf13/cobra"
+)
+
+// Formats of the datasets read (and written) by the mask command.
+const (
+	maskFormatJson   = "json"
+	maskFormatNdjson = "ndjson"
+	maskFormatCsv    = "csv"
+	maskFormatTsv    = "tsv"
+)
+
+// Returns the valid values of --input-format.
+func maskFormats() []string {
+	return []string{maskFormatJson, maskFormatNdjson, maskFormatCsv, maskFormatTsv}
+}
+
+// Longest NDJSON line accepted.
+const maskMaxLineSize = 64 * mb
+
+var maskPhoneRegex = regexp.MustCompile(`^\+?[\d\s().-]+$`)
+
+// Kind of the phone numbers, which aren't detected when inferring templates.
+const maskKindPhone = "phone"
+
+// Mock functions replacing each kind of detected value, when a rule doesn't say otherwise.
+var maskKindFunctions = map[string]string{
+	inferKindEmail:    inferKindFunctions[inferKindEmail],
+	maskKindPhone:     "{{ Person.phoneNumber }}",
+	inferKindCpf:      inferKindFunctions[inferKindCpf],
+	inferKindCnpj:     inferKindFunctions[inferKindCnpj],
+	inferKindIpv4:     inferKindFunctions[inferKindIpv4],
+	inferKindIpv6:     inferKindFunctions[inferKindIpv6],
+	inferKindUuid:     inferKindFunctions[inferKindUuid],
+	inferKindUrl:      inferKindFunctions[inferKindUrl],
+	inferKindDate:     inferKindFunctions[inferKindDate],
+	inferKindDateTime: inferKindFunctions[inferKindDateTime],
+}
+
+// Returns the kinds of values a rule can target, the first ones being masked by default (unless --detect=false).
+func maskKinds() []string {
+	return []string{inferKindEmail, maskKindPhone, inferKindCpf, inferKindCnpj, inferKindIpv4, inferKindIpv6, inferKindUuid, inferKindUrl, inferKindDate, inferKindDateTime}
+}
+
+// Kinds of values masked without a rule, as they are personal data.
+func maskDetectedKinds() []string {
+	return maskKinds()[:6]
+}
+
+func NewMaskCmd(opts *CommandOptions) *cobra.Command {
+	maskCmd := &cobra.Command{
+		Use:   "mask <input>",
+		Short: "Anonymize a dataset by replacing its values with mock data",
+		Long: `Anonymize a dataset (JSON, NDJSON or CSV) by replacing its personal data with mock data, keeping its structure.
+
+Rules:
+
+* Add --rule "<target>={{ mock function }}" to replace the values of a target. (e.g. --rule '$..name={{ Person.name }}')
+* Targets are JSON paths ("$.customer.email", "$.items[*].name", "$..email" at any depth, CSV columns being "$.column"), or kinds of values: email, phone, cpf, cnpj, ipv4, ipv6, uuid, url, date or datetime.
+* Rules of kinds can leave the mock function out, to use the one of the kind. (e.g. --rule uuid)
+* Replacements can also be raw values. (e.g. --rule '$..password=********')
+* Add --rules to read the rules from a JSON or YAML file, as an object of targets and mock functions.
+* Emails, phones, CPFs, CNPJs and IPs are replaced even without rules, unless --detect=false is added.
+
+Consistent replacements:
+
+* The same value is always replaced by the same mock value (for the same mock function), so relations between records are kept.
+* Replacements are derived from --key, so masking again with the same key gives the same dataset. (Without it, a random key is used)
+
+Examples:
+  ktns mask customers.json
+  ktns mask orders.ndjson --rule '$..name={{ Person.name }}' --rule '$.notes=REDACTED' --key "$MASK_KEY"
+  ktns mask users.csv --rules mask-rules.yaml --detect=false --out-file masked/users.csv
+	`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ruleFlags, _ := cmd.Flags().GetStringArray("rule")
+			rulesFile, _ := cmd.Flags().GetString("rules")
+			detect, _ := cmd.Flags().GetBool("detect")
+			key, _ := cmd.Flags().GetString("key")
+			inputFormat, _ := cmd.Flags().GetString("input-format")
+			outFile, _ := cmd.Flags().GetString("out-file")
+			toStdout, _ := cmd.Flags().GetBool("stdout")
+			force, _ := cmd.Flags().GetBool("force")
+
+			inPath := args[0]
+			if inputFormat == "" {
+				inputFormat = maskFormatOf(inPath)
+				if inputFormat == "" {
+					return fmt.Errorf("unable to tell the format of '%s', pass --input-format", inPath)
+				}
+			}
+			if !slices.Contains(maskFormats(), inputFormat) {
+				return fmt.Errorf("invalid --input-format '%s' (must be one of '%s')", inputFormat, strings.Join(maskFormats(), "', '"))
+			}
+
+			if outFile != "" && toStdout {
+				return fmt.Errorf("provide only one of the two options: --out-file or --stdout")
+			}
+
+			var rules []maskRule
+			for _, ruleFlag := range ruleFlags {
+				target, function, _ := strings.Cut(ruleFlag, "=")
+				rule, err := newMaskRule(target, function)
+				if err != nil {
+					return fmt.Errorf("invalid --rule '%s' '%w'", ruleFlag, err)
+				}
+				rules = append(rules, rule)
+			}
+			if rulesFile != "" {
+				fileRules, err := loadMaskRules(rulesFile)
+				if err != nil {
+					return fmt.Errorf("invalid --rules '%w'", err)
+				}
+				rules = append(rules, fileRules...)
+			}
+
+			// Without a key, the replacements are only consistent within this run
+			keyBytes := []byte(key)
+			if key == "" {
+				keyBytes = make([]byte, 32)
+				if _, err := rand.Read(keyBytes); err != nil {
+					return fmt.Errorf("failed to generate a key '%w'", err)
+				}
+			}
+			masker := newMasker(rules, detect, keyBytes)
+
+			in, err := os.Open(inPath)
+			if err != nil {
+				return fmt.Errorf("failed to read the input '%w'", err)
+			}
+			defer in.Close()
+
+			var out io.Writer = opts.Out
+			var file *os.File
+			if !toStdout {
+				if outFile == "" {
+					outFile = maskOutputName(inPath)
+				}
+				var outPath string
+				var mu sync.Mutex
+				createdDirs := make(map[string]bool, 1)
+				file, err = openOutputFile(&outputOptions{file: outFile, force: force}, inPath, &outPath, &mu, &createdDirs)
+				if err != nil {
+					return fmt.Errorf("%w", err)
+				}
+				out = file
+			}
+
+			switch inputFormat {
+			case maskFormatJson:
+				err = masker.maskJson(in, out)
+			case maskFormatNdjson:
+				err = masker.maskNdjson(in, out)
+			case maskFormatCsv:
+				err = masker.maskCsv(in, out, ',')
+			case maskFormatTsv:
+				err = masker.maskCsv(in, out, '\t')
+			}
+			if file != nil {
+				err = closeOutputFile(file, err)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to mask '%s' '%w'", inPath, err)
+			}
+			return nil
+		},
+	}
+
+	maskCmd.Flags().StringArray("rule", nil, "pass a rule as \"<JSON path or kind>=<mock function>\" (e.g. '$..email={{ Internet.email }}'), can be repeated")
+	maskCmd.Flags().String("rules", "", "pass a JSON or YAML file with an object of rules (targets and mock functions)")
+	maskCmd.Flags().Bool("detect", true, "if set, emails, phones, CPFs, CNPJs and IPs are replaced even without rules")
+	maskCmd.Flags().String("key", "", "pass the key the replacements are derived from, so the same values are always replaced by the same mock values (defaults to a random key)")
+	maskCmd.Flags().String("input-format", "", "pass the format of the input: 'json', 'ndjson', 'csv' or 'tsv' (defaults to the one of its extension)")
+	maskCmd.Flags().String("out-file", "", "pass the file where the masked dataset will be written, defaults to \"<input>.masked.<extension>\" next to the input")
+	maskCmd.Flags().Bool("stdout", false, "if set, the masked dataset is printed instead of written to a file")
+	maskCmd.Flags().Bool("force", false, "if set, an existing output file is overwritten")
+
+	return maskCmd
+}
+
+// Returns the format of a dataset by its extension, or an empty string when it isn't known.
+func maskFormatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return maskFormatJson
+	case ".ndjson", ".jsonl":
+		return maskFormatNdjson
+	case ".csv":
+		return maskFormatCsv
+	case ".tsv":
+		return maskFormatTsv
+	default:
+		return ""
+	}
+}
+
+// Returns the name of the masked dataset, next to the input (e.g. "data/users.csv" -> "data/users.masked.csv").
+func maskOutputName(inPath string) string {
+	extension := filepath.Ext(inPath)
+	return strings.TrimSuffix(inPath, extension) + ".masked" + extension
+}
+
+// A rule replacing the values of a JSON path (or of a kind of values) with a template value.
+type maskRule struct {
+	path     []maskPathSegment
+	kind     string
+	function string
+}
+
+func newMaskRule(target string, function string) (maskRule, error) {
+	target = strings.TrimSpace(target)
+	if strings.HasPrefix(target, "$") {
+		path, err := parseMaskPath(target)
+		if err != nil {
+			return maskRule{}, err
+		}
+		if function == "" {
+			return maskRule{}, fmt.Errorf("rules of JSON paths need a mock function (e.g. '%s={{ Person.name }}')", target)
+		}
+		return maskRule{path: path, function: function}, nil
+	}
+	if !slices.Contains(maskKinds(), target) {
+		return maskRule{}, fmt.Errorf("the target must be a JSON path starting with '$' or one of '%s'", strings.Join(maskKinds(), "', '"))
+	}
+	if function == "" {
+		function = maskKindFunctions[target]
+	}
+	return maskRule{kind: target, function: function}, nil
+}
+
+// Reads the rules of a JSON or YAML file, an object of targets and mock functions.
+func loadMaskRules(path string) ([]maskRule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	document, err := engine.UnmarshalTemplateFile(path, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s '%w'", engine.TemplateLanguage(path), err)
+	}
+	rulesMap, ok := document.(*engine.OrderedMap)
+	if !ok {
+		return nil, fmt.Errorf("the rules must be an object of targets and mock functions")
+	}
+	var rules []maskRule
+	for _, target := range rulesMap.Keys() {
+		value, _ := rulesMap.Get(target)
+		function, ok := value.(string)
+		if !ok && value != nil {
+			return nil, fmt.Errorf("the mock function of '%s' must be a string", target)
+		}
+		rule, err := newMaskRule(target, function)
+		if err != nil {
+			return nil, fmt.Errorf("'%s': %w", target, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// A segment of a JSON path: a key, an index, any key or index ("*"), or any amount of levels ("..").
+type maskPathSegment struct {
+	key        string
+	index      int
+	isIndex    bool
+	isWildcard bool
+	isDescent  bool
+}
+
+// Parses a JSON path, such as "$.customer.email", "$.items[*].name", "$['first name']" or "$..email".
+func parseMaskPath(path string) ([]maskPathSegment, error) {
+	var segments []maskPathSegment
+	rest := strings.TrimPrefix(path, "$")
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			segments = append(segments, maskPathSegment{isDescent: true})
+			rest = rest[2:]
+			if rest == "" {
+				return nil, fmt.Errorf("invalid JSON path '%s' (it can't end with '..')", path)
+			}
+			// The key after ".." has no dot of its own (e.g. "$..email"), unlike brackets (e.g. "$..[0]")
+			if !strings.HasPrefix(rest, "[") {
+				rest = "." + rest
+			}
+		case strings.HasPrefix(rest, "."):
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			if name == "" {
+				return nil, fmt.Errorf("invalid JSON path '%s' (empty key)", path)
+			}
+			if name == "*" {
+				segments = append(segments, maskPathSegment{isWildcard: true})
+			} else {
+				segments = append(segments, maskPathSegment{key: name})
+			}
+			rest = rest[end+1:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path '%s' (unclosed '[')", path)
+			}
+			inside := strings.TrimSpace(rest[1:end])
+			switch {
+			case inside == "*":
+				segments = append(segments, maskPathSegment{isWildcard: true})
+			case len(inside) >= 2 && (inside[0] == '\'' || inside[0] == '"') && inside[len(inside)-1] == inside[0]:
+				segments = append(segments, maskPathSegment{key: inside[1 : len(inside)-1]})
+			default:
+				index, err := strconv.Atoi(inside)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid JSON path '%s' (invalid index '%s')", path, inside)
+				}
+				segments = append(segments, maskPathSegment{index: index, isIndex: true})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSON path '%s' (expected '.' or '[' at '%s')", path, rest)
+		}
+	}
+	return segments, nil
+}
+
+// Returns whether a JSON path matches a path of a document (made of keys and indexes).
+func matchMaskPath(segments []maskPathSegment, path []any) bool {
+	if len(segments) == 0 {
+		return len(path) == 0
+	}
+	segment := segments[0]
+	if segment.isDescent {
+		for idx := 0; idx <= len(path); idx++ {
+			if matchMaskPath(segments[1:], path[idx:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	switch step := path[0].(type) {
+	case string:
+		if segment.isIndex || (!segment.isWildcard && segment.key != step) {
+			return false
+		}
+	case int:
+		if !segment.isWildcard && (!segment.isIndex || segment.index != step) {
+			return false
+		}
+	}
+	return matchMaskPath(segments[1:], path[1:])
+}
+
+// Replaces the values of records with mock values, always the same for the same value and mock function.
+type masker struct {
+	rules  []maskRule
+	detect bool
+	key    []byte
+	mocker *mocker.Mock
+}
+
+func newMasker(rules []maskRule, detect bool, key []byte) *masker {
+	return &masker{rules: rules, detect: detect, key: key, mocker: mocker.NewWithSeed(0)}
+}
+
+// Masks a value of a record, `path` being where it is in the record and `function` the one of a rule matching one of
+// its parents (values inside a matched object or array are all replaced).
+func (m *masker) mask(value any, path []any, function string) (any, error) {
+	for _, rule := range m.rules {
+		if rule.path != nil && matchMaskPath(rule.path, path) {
+			function = rule.function
+			break
+		}
+	}
+
+	switch typedValue := value.(type) {
+	case *engine.OrderedMap:
+		for _, objKey := range typedValue.Keys() {
+			objValue, _ := typedValue.Get(objKey)
+			masked, err := m.mask(objValue, append(path, objKey), function)
+			if err != nil {
+				return nil, err
+			}
+			typedValue.Set(objKey, masked)
+		}
+		return typedValue, nil
+	case []any:
+		for idx, item := range typedValue {
+			masked, err := m.mask(item, append(path, idx), function)
+			if err != nil {
+				return nil, err
+			}
+			typedValue[idx] = masked
+		}
+		return typedValue, nil
+	case nil:
+		return nil, nil
+	case string:
+		// Empty values (e.g. empty CSV cells) have nothing to hide
+		if typedValue == "" {
+			return typedValue, nil
+		}
+	}
+
+	if function == "" {
+		text, ok := value.(string)
+		if !ok {
+			return value, nil
+		}
+		function = m.kindFunction(text)
+		if function == "" {
+			return value, nil
+		}
+	}
+	return m.replace(value, function)
+}
+
+// Returns the mock function of the kind of a text (by its rule, or the default one when detecting personal data).
+func (m *masker) kindFunction(text string) string {
+	kind := inferStringKind(text)
+	if kind == inferKindText || kind == inferKindDigits {
+		digits := strings.Count(strings.Map(func(char rune) rune {
+			if char >= '0' && char <= '9' {
+				return '0'
+			}
+			return -1
+		}, text), "0")
+		// Phones have a country code or separators, so plain numbers (e.g. ids) are not taken for phones
+		if maskPhoneRegex.MatchString(text) && digits >= 8 && digits <= 15 && strings.ContainsAny(text, "+ ()-") {
+			kind = maskKindPhone
+		}
+	}
+	for _, rule := range m.rules {
+		if rule.kind == kind {
+			return rule.function
+		}
+	}
+	if m.detect && slices.Contains(maskDetectedKinds(), kind) {
+		return maskKindFunctions[kind]
+	}
+	return ""
+}
+
+// Replaces a value by the template value of a rule, generated with a seed derived from the key, the value and the
+// template value (so the same value is replaced by the same mock value).
+func (m *masker) replace(value any, function string) (any, error) {
+	original, ok := value.(string)
+	if !ok {
+		encoded, _ := json.Marshal(value)
+		original = string(encoded)
+	}
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write([]byte(function))
+	mac.Write([]byte{0})
+	mac.Write([]byte(original))
+	m.mocker.Reseed(int64(binary.BigEndian.Uint64(mac.Sum(nil)[:8])))
+
+	replaced, err := engine.ProcessTemplate(function, m.mocker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process '%s' '%w'", function, err)
+	}
+	switch value.(type) {
+	case json.Number:
+		return engine.CoerceSchemaValue(replaced, "number"), nil
+	case bool:
+		return engine.CoerceSchemaValue(replaced, "boolean"), nil
+	default:
+		return replaced, nil
+	}
+}
+
+// Masks a JSON document, written back indented.
+func (m *masker) maskJson(in io.Reader, out io.Writer) error {
+	content, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	document, err := engine.UnmarshalOrderedNumbers(content)
+	if err != nil {
+		return fmt.Errorf("failed to parse JSON '%w'", err)
+	}
+	masked, err := m.mask(document, nil, "")
+	if err != nil {
+		return err
+	}
+	prettyJSON, err := json.MarshalIndent(masked, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling JSON '%w'", err)
+	}
+	_, err = out.Write(append(prettyJSON, '\n'))
+	return err
+}
+
+// Masks a record per line, without reading the whole input.
+func (m *masker) maskNdjson(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*kb), maskMaxLineSize)
+	writer := bufio.NewWriter(out)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		record, err := engine.UnmarshalOrderedNumbers(scanner.Bytes())
+		if err != nil {
+			return fmt.Errorf("line %d: failed to parse JSON '%w'", line, err)
+		}
+		masked, err := m.mask(record, nil, "")
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		encoded, err := json.Marshal(masked)
+		if err != nil {
+			return fmt.Errorf("line %d: error marshalling JSON '%w'", line, err)
+		}
+		writer.Write(encoded)
+		writer.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// Masks a CSV (or TSV) file, each row being a record whose columns are the keys of its header.
+func (m *masker) maskCsv(in io.Reader, out io.Writer, delimiter rune) error {
+	reader := csv.NewReader(in)
+	reader.Comma = delimiter
+	writer := csv.NewWriter(out)
+	writer.Comma = delimiter
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse CSV '%w'", err)
+	}
+	// The rules match the columns by name, so each name must be a single column
+	columns := make(map[string]bool, len(header))
+	for _, column := range header {
+		if columns[column] {
+			return fmt.Errorf("failed to parse CSV 'duplicate column \"%s\" in the header'", column)
+		}
+		columns[column] = true
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse CSV '%w'", err)
+		}
+		record := engine.NewOrderedMap()
+		for idx, column := range header {
+			record.Set(column, row[idx])
+		}
+		if _, err := m.mask(record, nil, ""); err != nil {
+			line, _ := reader.FieldPos(0)
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		for idx, column := range header {
+			value, _ := record.Get(column)
+			row[idx] = fmt.Sprint(value)
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
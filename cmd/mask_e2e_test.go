@@ -0,0 +1,129 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lfsc09/k-test-n-stress/cmd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MaskCmdE2ETestSuite struct {
+	suite.Suite
+}
+
+func TestMaskCmdTestSuite(t *testing.T) {
+	suite.Run(t, new(MaskCmdE2ETestSuite))
+}
+
+// Test the command line interface (CLI) of the application. (Specifically for the mask command)
+func (suite *MaskCmdE2ETestSuite) executeCommand(args ...string) (string, error) {
+	outBuf := new(bytes.Buffer)
+
+	opts := &cmd.CommandOptions{
+		Out: outBuf,
+	}
+
+	rootCmd := cmd.NewRootCmd(opts)
+
+	rootCmd.SetArgs(args)
+	err := rootCmd.Execute()
+	return outBuf.String(), err
+}
+
+// Writes a file into a temporary directory, returning its path.
+func (suite *MaskCmdE2ETestSuite) writeInput(name string, content string) string {
+	path := filepath.Join(suite.T().TempDir(), name)
+	suite.Require().NoError(os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func (suite *MaskCmdE2ETestSuite) TestCLIShouldRaiseError_InvalidFlags() {
+	inPath := suite.writeInput("customers.json", `[]`)
+	txtPath := suite.writeInput("customers.txt", "")
+	tests := []struct {
+		testName      string
+		input         []string
+		expectedError string
+	}{
+		{
+			testName:      "unknown extension",
+			input:         []string{"mask", txtPath},
+			expectedError: "unable to tell the format of '" + txtPath + "', pass --input-format",
+		},
+		{
+			testName:      "invalid --input-format",
+			input:         []string{"mask", inPath, "--input-format", "xml"},
+			expectedError: "invalid --input-format 'xml' (must be one of 'json', 'ndjson', 'csv', 'tsv')",
+		},
+		{
+			testName:      "invalid --rule",
+			input:         []string{"mask", inPath, "--rule", "ssn={{ Person.cpf }}"},
+			expectedError: "invalid --rule 'ssn={{ Person.cpf }}' 'the target must be a JSON path starting with '$' or one of 'email', 'phone', 'cpf', 'cnpj', 'ipv4', 'ipv6', 'uuid', 'url', 'date', 'datetime''",
+		},
+		{
+			testName:      "both --out-file and --stdout",
+			input:         []string{"mask", inPath, "--out-file", "masked.json", "--stdout"},
+			expectedError: "provide only one of the two options: --out-file or --stdout",
+		},
+	}
+	for _, test := range tests {
+		_, err := suite.executeCommand(test.input...)
+		assert.EqualError(suite.T(), err, test.expectedError, test.testName)
+	}
+}
+
+func (suite *MaskCmdE2ETestSuite) TestCLIShouldMaskNdjsonConsistently() {
+	testName := "Should mask NDJSON records, replacing the same values by the same mock values"
+	inPath := suite.writeInput("orders.ndjson", strings.Join([]string{
+		`{"id":1,"customer":{"name":"Ada","email":"ada@example.com"},"total":10.5}`,
+		``,
+		`{"id":2,"customer":{"name":"Ada","email":"ada@example.com"},"total":7}`,
+	}, "\n"))
+	args := []string{"mask", inPath, "--rule", "$..name={{ Person.name }}", "--key", "secret"}
+	_, err := suite.executeCommand(args...)
+	assert.NoError(suite.T(), err, testName)
+
+	outPath := strings.TrimSuffix(inPath, ".ndjson") + ".masked.ndjson"
+	content, err := os.ReadFile(outPath)
+	assert.NoError(suite.T(), err, testName)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(suite.T(), lines, 2, testName)
+
+	var first, second map[string]any
+	assert.NoError(suite.T(), json.Unmarshal([]byte(lines[0]), &first), testName)
+	assert.NoError(suite.T(), json.Unmarshal([]byte(lines[1]), &second), testName)
+	assert.Equal(suite.T(), 1.0, first["id"], testName)
+	assert.Equal(suite.T(), 10.5, first["total"], testName)
+	assert.NotEqual(suite.T(), "ada@example.com", first["customer"].(map[string]any)["email"], testName)
+	assert.NotEqual(suite.T(), "Ada", first["customer"].(map[string]any)["name"], testName)
+	assert.Equal(suite.T(), first["customer"], second["customer"], testName)
+
+	_, err = suite.executeCommand(args...)
+	assert.EqualError(suite.T(), err, "'"+outPath+"' already exists (use --force to overwrite it)", testName)
+
+	stdOut, err := suite.executeCommand(append(args, "--stdout")...)
+	assert.NoError(suite.T(), err, testName)
+	assert.Equal(suite.T(), string(content), stdOut, "masking again with the same key gives the same dataset")
+}
+
+func (suite *MaskCmdE2ETestSuite) TestCLIShouldMaskCsvWithRulesFile() {
+	testName := "Should mask a CSV file with the rules of a YAML file"
+	inPath := suite.writeInput("users.csv", "id,email,document\n7,ada@example.com,123.456.789-09\n")
+	rulesPath := suite.writeInput("rules.yaml", "$.id: \"{{ Regex.regex:/[0-9]{3}/ }}\"\ncpf:\n")
+	stdOut, err := suite.executeCommand("mask", inPath, "--rules", rulesPath, "--detect=false", "--stdout")
+	assert.NoError(suite.T(), err, testName)
+
+	lines := strings.Split(strings.TrimSpace(stdOut), "\n")
+	assert.Len(suite.T(), lines, 2, testName)
+	fields := strings.Split(lines[1], ",")
+	assert.Regexp(suite.T(), `^[0-9]{3}$`, fields[0], testName)
+	assert.Equal(suite.T(), "ada@example.com", fields[1], "emails are kept without --detect")
+	assert.Regexp(suite.T(), `^\d{3}\.\d{3}\.\d{3}-\d{2}$`, fields[2], testName)
+	assert.NotEqual(suite.T(), "123.456.789-09", fields[2], testName)
+}
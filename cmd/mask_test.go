@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MaskTestSuite struct {
+	suite.Suite
+}
+
+func TestMaskTestSuite(t *testing.T) {
+	suite.Run(t, new(MaskTestSuite))
+}
+
+func (suite *MaskTestSuite) TestMatchMaskPath() {
+	tests := []struct {
+		testName string
+		path     string
+		input    []any
+		expected bool
+	}{
+		{testName: "root", path: "$", input: nil, expected: true},
+		{testName: "key", path: "$.customer.email", input: []any{"customer", "email"}, expected: true},
+		{testName: "other key", path: "$.customer.email", input: []any{"customer", "name"}, expected: false},
+		{testName: "shorter path", path: "$.customer.email", input: []any{"customer"}, expected: false},
+		{testName: "index", path: "$.items[1]", input: []any{"items", 1}, expected: true},
+		{testName: "other index", path: "$.items[1]", input: []any{"items", 0}, expected: false},
+		{testName: "wildcard index", path: "$.items[*].name", input: []any{"items", 3, "name"}, expected: true},
+		{testName: "wildcard key", path: "$.*.name", input: []any{"customer", "name"}, expected: true},
+		{testName: "quoted key", path: "$['first name']", input: []any{"first name"}, expected: true},
+		{testName: "descent at the root", path: "$..email", input: []any{"email"}, expected: true},
+		{testName: "descent at any depth", path: "$..email", input: []any{0, "contacts", 2, "email"}, expected: true},
+		{testName: "descent in the middle", path: "$.orders..id", input: []any{"orders", 0, "items", 1, "id"}, expected: true},
+		{testName: "descent not matching", path: "$.orders..id", input: []any{"customers", 0, "id"}, expected: false},
+	}
+
+	for _, test := range tests {
+		segments, err := parseMaskPath(test.path)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", test.testName)
+		assert.Equal(suite.T(), test.expected, matchMaskPath(segments, test.input), "Test case '%s' failed", test.testName)
+	}
+}
+
+func (suite *MaskTestSuite) TestNewMaskRule_InvalidInputs() {
+	tests := []struct {
+		testName      string
+		target        string
+		function      string
+		expectedError string
+	}{
+		{
+			testName:      "unknown kind",
+			target:        "ssn",
+			function:      "{{ Person.name }}",
+			expectedError: "the target must be a JSON path starting with '$' or one of 'email', 'phone', 'cpf', 'cnpj', 'ipv4', 'ipv6', 'uuid', 'url', 'date', 'datetime'",
+		},
+		{
+			testName:      "path without function",
+			target:        "$.name",
+			expectedError: "rules of JSON paths need a mock function (e.g. '$.name={{ Person.name }}')",
+		},
+		{
+			testName:      "unclosed bracket",
+			target:        "$.items[0",
+			function:      "x",
+			expectedError: "invalid JSON path '$.items[0' (unclosed '[')",
+		},
+		{
+			testName:      "invalid index",
+			target:        "$.items[first]",
+			function:      "x",
+			expectedError: "invalid JSON path '$.items[first]' (invalid index 'first')",
+		},
+		{
+			testName:      "ending with descent",
+			target:        "$..",
+			function:      "x",
+			expectedError: "invalid JSON path '$..' (it can't end with '..')",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := newMaskRule(test.target, test.function)
+		assert.EqualError(suite.T(), err, test.expectedError, "Test case '%s' failed", test.testName)
+	}
+}
+
+func (suite *MaskTestSuite) TestMasker_KindFunction() {
+	tests := []struct {
+		testName string
+		input    string
+		expected string
+	}{
+		{testName: "email", input: "ada@example.com", expected: "{{ Internet.email }}"},
+		{testName: "phone", input: "+55 (11) 98765-4321", expected: "{{ Person.phoneNumber }}"},
+		{testName: "cpf", input: "123.456.789-09", expected: "{{ Person.cpf }}"},
+		{testName: "ip", input: "10.0.0.1", expected: "{{ Internet.ipv4 }}"},
+		{testName: "plain number is not a phone", input: "1234567890", expected: ""},
+		{testName: "uuid is not personal data", input: "0b6a5c7e-8f3d-4e21-9a3b-2c1d0e9f8a7b", expected: ""},
+		{testName: "text", input: "Hello world", expected: ""},
+	}
+
+	masker := newMasker(nil, true, []byte("key"))
+	for _, test := range tests {
+		assert.Equal(suite.T(), test.expected, masker.kindFunction(test.input), "Test case '%s' failed", test.testName)
+	}
+
+	uuidRule, err := newMaskRule("uuid", "")
+	suite.Require().NoError(err)
+	masker = newMasker([]maskRule{uuidRule}, false, []byte("key"))
+	assert.Equal(suite.T(), "{{ UUID.uuidv4 }}", masker.kindFunction("0b6a5c7e-8f3d-4e21-9a3b-2c1d0e9f8a7b"))
+	assert.Equal(suite.T(), "", masker.kindFunction("ada@example.com"))
+}
+
+func (suite *MaskTestSuite) TestMasker_ConsistentReplacements() {
+	input := `{"user":"ada@example.com","name":"Ada","age":36,"friends":[{"email":"ada@example.com","name":"Ada"}],"notes":{"a":"x","b":["y"]},"empty":"","missing":null}`
+	nameRule, err := newMaskRule("$..name", "{{ Person.name }}")
+	suite.Require().NoError(err)
+	ageRule, err := newMaskRule("$.age", "{{ Number.number::18:99 }}")
+	suite.Require().NoError(err)
+	notesRule, err := newMaskRule("$.notes", "REDACTED")
+	suite.Require().NoError(err)
+	rules := []maskRule{nameRule, ageRule, notesRule}
+
+	maskWith := func(key string) map[string]any {
+		var out bytes.Buffer
+		suite.Require().NoError(newMasker(rules, true, []byte(key)).maskJson(strings.NewReader(input), &out))
+		var values map[string]any
+		suite.Require().NoError(json.Unmarshal(out.Bytes(), &values))
+		return values
+	}
+
+	masked := maskWith("secret")
+	friend := masked["friends"].([]any)[0].(map[string]any)
+	assert.NotEqual(suite.T(), "ada@example.com", masked["user"])
+	assert.Equal(suite.T(), masked["user"], friend["email"], "the same email is replaced by the same value")
+	assert.NotEqual(suite.T(), "Ada", masked["name"])
+	assert.Equal(suite.T(), masked["name"], friend["name"], "the same name is replaced by the same value")
+	assert.IsType(suite.T(), float64(0), masked["age"], "numbers are kept as numbers")
+	assert.Equal(suite.T(), map[string]any{"a": "REDACTED", "b": []any{"REDACTED"}}, masked["notes"], "values inside a matched path are replaced")
+	assert.Equal(suite.T(), "", masked["empty"])
+	assert.Nil(suite.T(), masked["missing"])
+
+	assert.Equal(suite.T(), masked, maskWith("secret"), "the same key masks the same way")
+	assert.NotEqual(suite.T(), masked["user"], maskWith("other")["user"], "another key masks another way")
+}
+
+func (suite *MaskTestSuite) TestMasker_KeepsUnmaskedNumbers() {
+	input := `{"id":9007199254740993,"price":1.50,"ratio":1e3,"email":"ada@example.com"}`
+	masker := newMasker(nil, true, []byte("key"))
+
+	var out bytes.Buffer
+	assert.NoError(suite.T(), masker.maskJson(strings.NewReader(input), &out))
+	assert.Contains(suite.T(), out.String(), `"id": 9007199254740993,`, "integers larger than 2^53 are kept")
+	assert.Contains(suite.T(), out.String(), `"price": 1.50,`)
+	assert.Contains(suite.T(), out.String(), `"ratio": 1e3,`)
+	assert.NotContains(suite.T(), out.String(), "ada@example.com")
+
+	out.Reset()
+	assert.NoError(suite.T(), masker.maskNdjson(strings.NewReader(input+"\n"), &out))
+	assert.True(suite.T(), strings.HasPrefix(out.String(), `{"id":9007199254740993,"price":1.50,"ratio":1e3,"email":`), "integers larger than 2^53 are kept")
+}
+
+func (suite *MaskTestSuite) TestMasker_Csv() {
+	input := "id,email,city\n1,ada@example.com,London\n2,,\"Paris, FR\"\n"
+	cityRule, err := newMaskRule("$.city", "{{ Address.city }}")
+	suite.Require().NoError(err)
+
+	var out bytes.Buffer
+	assert.NoError(suite.T(), newMasker([]maskRule{cityRule}, true, []byte("key")).maskCsv(strings.NewReader(input), &out, ','))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(suite.T(), lines, 3)
+	assert.Equal(suite.T(), "id,email,city", lines[0])
+	assert.True(suite.T(), strings.HasPrefix(lines[1], "1,"))
+	assert.NotContains(suite.T(), lines[1], "ada@example.com")
+	assert.NotContains(suite.T(), lines[1], "London")
+	assert.True(suite.T(), strings.HasPrefix(lines[2], "2,,"))
+}
+
+func (suite *MaskTestSuite) TestMasker_CsvDuplicateColumns() {
+	input := "id,email,email\n1,ada@example.com,bob@example.com\n"
+
+	var out bytes.Buffer
+	err := newMasker(nil, true, []byte("key")).maskCsv(strings.NewReader(input), &out, ',')
+	assert.EqualError(suite.T(), err, "failed to parse CSV 'duplicate column \"email\" in the header'")
+	assert.Empty(suite.T(), out.String(), "nothing is written")
+}
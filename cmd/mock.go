@@ -1,32 +1,39 @@
 package cmd
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
 	"github.com/lfsc09/k-test-n-stress/mocker"
-	"github.com/mohae/deepcopy"
 	"github.com/spf13/cobra"
 	"github.com/vbauerster/mpb/v8"
 	"github.com/vbauerster/mpb/v8/decor"
 )
 
-var filenameNumberRegex = regexp.MustCompile(`^[^\[\]\s]+\[(\d+)\]\.template\.json$`)
-var objKeyNumberRegex = regexp.MustCompile(`^[^\[\]\s]+\[(\d+)\]$`)
+var filenameNumberRegex = regexp.MustCompile(`^[^\[\]\s]+\[(\d+)\](?:\.(?:` + strings.Join(outputFormats(), "|") + `))?\.template\.(?:json|yaml|yml|json5|jsonc)$`)
 
 func NewMockCmd(opts *CommandOptions) *cobra.Command {
 	mockCmd := &cobra.Command{
 		Use:   "mock",
 		Short: "Generate mock data based from an object string or from template files",
-		Long: `Generate mock data based on --parse-str, --parse-json or --parse-files options.
+		Long: `Generate mock data based on --parse-str, --parse-json, --parse-files, --from-schema or --from-openapi options.
 	
+* Template files can be written in JSON (.template.json), YAML (.template.yaml or .template.yml) or JSON5 (.template.json5 or .template.jsonc, with comments and trailing commas).
+
 * Add --preserve-folder-structure to keep the folder structure of the input files. (Only works with --parse-files)
 
   e.g.: Having a template folder structure like this:
@@ -50,7 +57,7 @@ Mock functions:
 
 Controling the number of generated data:
 
-* Add --generate to specify the number of root objects to generate. (Only works with --parse-json)
+* Add --generate to specify the number of root objects to generate. (Only works with --parse-json, --from-schema or --from-openapi)
 * When using --parse-files, specify the desired number of root objects in the template file's name, between brackets.
 
   e.g.: A template file named "employees[5].template.json" will generate an array of 5 employees.
@@ -69,6 +76,17 @@ Controling the number of generated data:
     { "name": ... }
   ]
 
+* A template can also carry its own settings in a "$meta" section: the "count" of root objects, the "output" file name (inside --out-dir), the "format", the "seed" and the "locale".
+  They take the place of the ones in the template file's name (and of --format and --seed). With --parse-json, the flags passed take the place of them instead.
+
+  e.g.: { "$meta": { "count": 50, "output": "staff", "format": "csv", "seed": 42 }, "name": "{{ Person.name }}" }
+
+* Add --count to change a count without renaming files, either of a template by its name (e.g. --count company=100), or of one of its keys by their path (e.g. --count company.employees=50). (Only works with --parse-files)
+* Add --scale to multiply every count of the templates, in their names, "$meta" and keys (e.g. --scale 10x). (Only works with --parse-files or --parse-json, and not applied to --count and --generate)
+* Add --target-size to generate root objects until each file reaches that size (e.g. --target-size 50MB), instead of the counts of the templates. The size achieved by each file is printed at the end.
+* Add --record-size to pad every record to about that size in the output format (e.g. --record-size 8KB), with a "_padding" key of filler characters.
+* Add --shard-size to split the records of each template in files of at most that many records (e.g. "employees-0001.json", "employees-0002.json"), or --shards to split them in that many files (as evenly as possible, fewer when there are fewer records). A manifest (e.g. "employees.manifest.json") lists the files, their records and their SHA-256 checksums.
+
 * For inner objects, also pass the desired number between brackets in the object's "key".
 
   e.g.:
@@ -103,22 +121,130 @@ Controling the number of generated data:
     "phones": [ "...", "...", "...", "...", "..." ]
   }
 
+* Add more numbers between brackets to generate arrays of arrays. (e.g., { "matrix[3][4]": "{{ Number.number }}" } will generate 3 arrays of 4 numbers)
+* Arrays in the template can hold objects, values or other arrays, and the template itself can be an array instead of an object.
+
+* To generate keys with mock functions, use the mock function as the "key", optionally followed by the number of keys between brackets.
+
+  e.g.:
+  {
+    "{{ UUID.uuidv4 }}[3]": { "name": "{{ Person.name }}" }
+  }
+
+  Will generate an object with 3 random uuids as keys.
+
+Reusing templates:
+
+* Use { "$include": "path/to/file.template.json" } to reuse the content of another template file (relative to the including file).
+* Use { "$ref": "#/$defs/name" } to reuse a definition from the template's "$defs" section. (Other keys of the object are merged over the reused content)
+
+  e.g.:
+  {
+    "$defs": { "address": { "city": "{{ Address.city }}" } },
+    "addresses[3]": { "$ref": "#/$defs/address" }
+  }
+
+Conditional fields and variants:
+
+* Use "$if" with "$then" and/or "$else" to merge a branch into the object, based on a condition. (e.g. "$if": "{{ $.type }} == pj", where {{ $.type }} is a value of the record)
+* Use "$oneOf": [ ... ] to pick one of the alternatives. (Each alternative may have a "$weight")
+
+Referencing other templates:
+
+* When using --parse-files, pick values generated by another template with {{ Ref.pick:template.key }}. (e.g. {{ Ref.pick:company.id }} picks an "id" generated by "company[10].template.json")
+* Add a cardinality rule with {{ Ref.pick:company.id:unique }} (one-to-one) or {{ Ref.pick:company.id:each }} (each value at least once).
+
+JSON Schema:
+
+* Add --from-schema to generate valid instances of a JSON Schema (written in any of the template file formats).
+* Types, "enum", "const", "minimum"/"maximum", "minLength"/"maxLength", "pattern", "required", "oneOf"/"anyOf", "allOf" and "$ref" (to the same or other files) are followed, and formats such as "email", "uuid" or "date-time" generate matching values.
+* Add an "x-ktns" annotation to a schema to pin its value to a mock function. (e.g. { "type": "string", "x-ktns": "{{ Person.name }}" })
+
+OpenAPI:
+
+* Add --from-openapi with --operation (an operationId, or a method and path such as "POST /users") to generate requests and responses of an operation of an OpenAPI 3 document.
+* Each record has the "method", the "path" (with its path parameters), the "query" parameters, the request "body" and the "response" (with its "status" and "body").
+* Add --openapi-part request, query or response to generate only the request bodies, the query parameters or the response bodies. Add --response-status to pick the response (the first successful one by default).
+* Schemas are generated as with --from-schema, leaving "readOnly" properties out of requests and "writeOnly" ones out of responses.
+
+Reproducible data:
+
+* Add --seed to always generate the same data, whatever the --parallelism used. (Without it, every run generates different data)
+* Add --parallelism to limit how many records are generated at the same time. (Records of every template file share the same workers)
+
+Output:
+
+* The generated data is written to the "out" directory, or to the directory passed with --out-dir.
+* Add --out-file to write the result of --parse-json (or --from-schema and --from-openapi) to a specific file, or --stdout to print it instead.
+* Records are written as they are generated, so big amounts don't need to fit in memory. Add --format ndjson to write a record per line (files end with ".ndjson" instead of ".json").
+* Add --format csv (or tsv) to write a row per record, with nested objects in dotted columns (e.g. "address.city"). Arrays are joined in a single column, unless --csv-arrays index (a column per item) or explode (a row per item) is added.
+* Add --format sql to write INSERT statements into the table of each template (or --table), quoted for the --dialect (postgres, mysql, sqlite or mssql). Nested objects and arrays are inserted as JSON texts.
+* Add --format yaml, xml or toml for these formats. With --parse-files, a template can ask for its own format in its name (e.g. "settings.toml.template.json").
+* Existing files are never overwritten, unless --force is added. Add --clean to remove the output directory before writing.
+* When using --parse-files, a failed template doesn't stop the others, and the failed ones are listed at the end. Add --fail-fast to stop at the first failure.
+* Add --watch to keep watching the template files of --parse-files, generating again the changed ones (and the ones referencing them), until interrupted. Failed templates are listed without stopping the watch.
+
 Examples:
   ktns mock --parse-str '{{ Person.name }}'
   ktns mock --parse-str 'Hello my name is {{ Person.name }}, I am {{ Number.number::1:100 }} years old'
   ktns mock --parse-json '{ "name": "{{ Person.name }}", "age": "{{ Number.number::1:100 }}" }'
   ktns mock --parse-json '{ "phones[2]": "{{ Person.phoneNumber }}" }' --generate 5
   ktns mock --parse-files "*.template.json"
+  ktns mock --parse-files "test/templates" --format ndjson
+  ktns mock --parse-files "test/templates" --format csv --csv-arrays explode
+  ktns mock --parse-files "company[100].template.json" --format sql --dialect mysql --sql-transaction
   ktns mock --parse-files "test/templates/*.template.json"
   ktns mock --parse-files "test/templates" --preserve-folder-structure
+  ktns mock --parse-files "test/templates" --out-dir "fixtures" --clean
+  ktns mock --parse-json '{ "name": "{{ Person.name }}" }' --stdout
+  ktns mock --from-schema "user.schema.json" --generate 10
+  ktns mock --from-openapi "api.yaml" --operation createUser --openapi-part request --generate 10
+  ktns mock --parse-files "test/templates" --seed 42 --parallelism 4
+  ktns mock --parse-files "test/templates" --scale 10x --count company=5 --count company.employees=200
+  ktns mock --parse-files "test/templates" --target-size 50MB --record-size 8KB
+  ktns mock --parse-files "test/templates" --watch
+  ktns mock --parse-files "employees[100000].template.json" --format ndjson --shard-size 10000
 	`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			list, _ := cmd.Flags().GetBool("list")
 			parseStr, _ := cmd.Flags().GetString("parse-str")
 			parseJson, _ := cmd.Flags().GetString("parse-json")
 			parseFiles, _ := cmd.Flags().GetString("parse-files")
+			fromSchema, _ := cmd.Flags().GetString("from-schema")
+			fromOpenapi, _ := cmd.Flags().GetString("from-openapi")
+			operation, _ := cmd.Flags().GetString("operation")
+			openapiPart, _ := cmd.Flags().GetString("openapi-part")
+			responseStatus, _ := cmd.Flags().GetString("response-status")
 			preserveFolderStructure, _ := cmd.Flags().GetBool("preserve-folder-structure")
 			generate, _ := cmd.Flags().GetInt("generate")
+			countFlags, _ := cmd.Flags().GetStringArray("count")
+			scaleFlag, _ := cmd.Flags().GetString("scale")
+			watch, _ := cmd.Flags().GetBool("watch")
+			watchInterval, _ := cmd.Flags().GetDuration("watch-interval")
+			targetSizeFlag, _ := cmd.Flags().GetString("target-size")
+			recordSizeFlag, _ := cmd.Flags().GetString("record-size")
+			shardSize, _ := cmd.Flags().GetInt("shard-size")
+			shards, _ := cmd.Flags().GetInt("shards")
+			outDir, _ := cmd.Flags().GetString("out-dir")
+			outFile, _ := cmd.Flags().GetString("out-file")
+			toStdout, _ := cmd.Flags().GetBool("stdout")
+			clean, _ := cmd.Flags().GetBool("clean")
+			force, _ := cmd.Flags().GetBool("force")
+			failFast, _ := cmd.Flags().GetBool("fail-fast")
+			parallelism, _ := cmd.Flags().GetInt("parallelism")
+			seed, _ := cmd.Flags().GetInt64("seed")
+			format, _ := cmd.Flags().GetString("format")
+			csvDelimiter, _ := cmd.Flags().GetString("csv-delimiter")
+			csvArrays, _ := cmd.Flags().GetString("csv-arrays")
+			csvArraySeparator, _ := cmd.Flags().GetString("csv-array-separator")
+			csvQuote, _ := cmd.Flags().GetString("csv-quote")
+			table, _ := cmd.Flags().GetString("table")
+			dialect, _ := cmd.Flags().GetString("dialect")
+			sqlBatchSize, _ := cmd.Flags().GetInt("sql-batch-size")
+			sqlTransaction, _ := cmd.Flags().GetBool("sql-transaction")
+			xmlRoot, _ := cmd.Flags().GetString("xml-root")
+			xmlItem, _ := cmd.Flags().GetString("xml-item")
+			xmlAttrPrefix, _ := cmd.Flags().GetString("xml-attr-prefix")
 
 			if list {
 				mocker := mocker.New()
@@ -126,9 +252,9 @@ Examples:
 				return nil
 			}
 
-			runningParseStr, runningParseJson, runningParseFiles := false, false, false
+			runningParseStr, runningParseJson, runningParseFiles, runningFromSchema, runningFromOpenapi := false, false, false, false, false
 
-			// Check if --parse-json, --parse-files or --parse-str is provided
+			// Check if --parse-json, --parse-files, --parse-str, --from-schema or --from-openapi is provided
 			parseCheck := 0
 			if parseStr != "" {
 				parseCheck++
@@ -142,10 +268,18 @@ Examples:
 				parseCheck++
 				runningParseFiles = true
 			}
+			if fromSchema != "" {
+				parseCheck++
+				runningFromSchema = true
+			}
+			if fromOpenapi != "" {
+				parseCheck++
+				runningFromOpenapi = true
+			}
 			if parseCheck == 0 {
 				return fmt.Errorf("nothing to be parsed, ask for help -h or --help")
 			} else if parseCheck > 1 {
-				return fmt.Errorf("provide only one of the three options: --parse-json, --parse-files or --parse-str")
+				return fmt.Errorf("provide only one of the five options: --parse-json, --parse-files, --parse-str, --from-schema or --from-openapi")
 			}
 
 			if runningParseFiles && len(args) > 0 {
@@ -156,364 +290,604 @@ Examples:
 				return fmt.Errorf("--preserve-folder-structure option is only available when using --parse-files")
 			}
 
-			if generate > 1 && !runningParseJson {
-				return fmt.Errorf("--generate option is only available when using --parse-json")
+			// --from-schema and --from-openapi generate and write the records as --parse-json does
+			runningSingleTemplate := runningParseJson || runningFromSchema || runningFromOpenapi
+
+			if generate > 1 && !runningSingleTemplate {
+				return fmt.Errorf("--generate option is only available when using --parse-json, --from-schema or --from-openapi")
 			}
 
 			if generate <= 0 {
 				return fmt.Errorf("--generate option must be greater than 0")
 			}
 
-			// Clean previous output directory
-			if err := os.RemoveAll("out"); err != nil {
-				return fmt.Errorf("failed to remove previous output directory '%w'", err)
+			if outFile != "" && !runningSingleTemplate {
+				return fmt.Errorf("--out-file option is only available when using --parse-json, --from-schema or --from-openapi")
 			}
 
-			mpbHandler := mpb.New(
-				mpb.WithWidth(60),
-				mpb.WithOutput(os.Stdout),
-				mpb.WithAutoRefresh(),
-			)
-
-			if runningParseStr {
-				// Process the string
-				mocker := mocker.New()
-				mockedStr := processStr(parseStr, mocker)
+			if toStdout && !runningSingleTemplate {
+				return fmt.Errorf("--stdout option is only available when using --parse-json, --from-schema or --from-openapi")
+			}
 
-				// Print the mocked string to STDOUT
-				fmt.Fprintf(opts.Out, "%s\n", mockedStr)
+			if outFile != "" && toStdout {
+				return fmt.Errorf("provide only one of the two options: --out-file or --stdout")
 			}
 
-			// Parse string json object from `--parse-json`
-			if runningParseJson {
-				outPath := ""
-				bar := giveMeABar("CLI", &outPath, 4, mpbHandler)
+			if runningFromOpenapi && operation == "" {
+				return fmt.Errorf("--operation option is required when using --from-openapi")
+			}
 
-				// Parse the string object content (STEP)
-				var parseMap map[string]any
-				if err := json.Unmarshal([]byte(parseJson), &parseMap); err != nil {
-					return fmt.Errorf("failed to parse JSON from the provided --parse-json '%w'", err)
+			if !runningFromOpenapi {
+				for _, openapiFlag := range []string{"operation", "openapi-part", "response-status"} {
+					if cmd.Flags().Changed(openapiFlag) {
+						return fmt.Errorf("--%s option is only available when using --from-openapi", openapiFlag)
+					}
 				}
-				bar.Increment()
+			}
 
-				// Process the parsed map (STEP)
-				mocker := mocker.New()
-				parseMaps := make([]map[string]any, generate)
-				for i := range generate {
-					cpParseMap := deepcopy.Copy(parseMap).(map[string]any)
-					if err := processJsonMap(cpParseMap, mocker); err != nil {
-						return fmt.Errorf("%w", err)
+			if !slices.Contains(engine.OpenapiParts(), openapiPart) {
+				return fmt.Errorf("invalid --openapi-part '%s' (must be one of '%s')", openapiPart, strings.Join(engine.OpenapiParts(), "', '"))
+			}
+
+			if !slices.Contains(outputFormats(), format) {
+				return fmt.Errorf("invalid --format '%s' (must be one of '%s')", format, strings.Join(outputFormats(), "', '"))
+			}
+
+			// With --parse-files, any template can ask for a format in its name (e.g. "company[10].csv.template.json")
+			if format != formatCsv && format != formatTsv && !runningParseFiles {
+				for _, csvFlag := range []string{"csv-delimiter", "csv-arrays", "csv-array-separator", "csv-quote"} {
+					if cmd.Flags().Changed(csvFlag) {
+						return fmt.Errorf("--%s option is only available when using --format csv or tsv (or --parse-files)", csvFlag)
 					}
-					parseMaps[i] = deepcopy.Copy(cpParseMap).(map[string]any)
 				}
-				bar.Increment()
+			}
 
-				// Sanitize the parsed map (STEP)
-				for i := range parseMaps {
-					sanitizeJsonMap(parseMaps[i])
+			// The delimiter defaults to the one of the format, and "\t" can be passed for a tab
+			delimiter := ','
+			if format == formatTsv {
+				delimiter = '\t'
+			}
+			if cmd.Flags().Changed("csv-delimiter") {
+				csvDelimiter = strings.ReplaceAll(csvDelimiter, `\t`, "\t")
+				delimiterRunes := []rune(csvDelimiter)
+				if len(delimiterRunes) != 1 || strings.ContainsAny(csvDelimiter, "\"\r\n") {
+					return fmt.Errorf("--csv-delimiter option must be a single character (other than a quote or a line break)")
 				}
-				bar.Increment()
+				delimiter = delimiterRunes[0]
+			}
 
-				// Write the processed map to a file (STEP)
-				var mu sync.Mutex
-				createdDirs := make(map[string]bool, 1)
-				if err := toFile(false, "mocked-data.json", &outPath, "", &parseMaps, &mu, &createdDirs); err != nil {
-					return fmt.Errorf("%w", err)
-				}
-				bar.Increment()
+			if !slices.Contains(csvArrayModes(), csvArrays) {
+				return fmt.Errorf("invalid --csv-arrays '%s' (must be one of '%s')", csvArrays, strings.Join(csvArrayModes(), "', '"))
 			}
 
-			// Parse object from `--parse-files` files
-			if runningParseFiles {
-				foundTemplateFiles, err := findTemplateFiles(parseFiles)
-				if err != nil {
-					return fmt.Errorf("failed to find template files from the provided --parse-files '%w'", err)
+			if !slices.Contains(csvQuoteModes(), csvQuote) {
+				return fmt.Errorf("invalid --csv-quote '%s' (must be one of '%s')", csvQuote, strings.Join(csvQuoteModes(), "', '"))
+			}
+
+			if format != formatSql && !runningParseFiles {
+				for _, sqlFlag := range []string{"table", "dialect", "sql-batch-size", "sql-transaction"} {
+					if cmd.Flags().Changed(sqlFlag) {
+						return fmt.Errorf("--%s option is only available when using --format sql (or --parse-files)", sqlFlag)
+					}
 				}
-				if len(foundTemplateFiles) == 0 {
-					return fmt.Errorf("no template files found in the provided --parse-files '%s'", parseFiles)
+			}
+
+			if !slices.Contains(sqlDialects(), dialect) {
+				return fmt.Errorf("invalid --dialect '%s' (must be one of '%s')", dialect, strings.Join(sqlDialects(), "', '"))
+			}
+
+			if sqlBatchSize <= 0 {
+				return fmt.Errorf("--sql-batch-size option must be greater than 0")
+			}
+
+			if dialect == sqlDialectMssql && sqlBatchSize > mssqlMaxBatchSize {
+				return fmt.Errorf("--sql-batch-size option must be at most %d for the mssql dialect", mssqlMaxBatchSize)
+			}
+
+			if format != formatXml && !runningParseFiles {
+				for _, xmlFlag := range []string{"xml-root", "xml-item", "xml-attr-prefix"} {
+					if cmd.Flags().Changed(xmlFlag) {
+						return fmt.Errorf("--%s option is only available when using --format xml (or --parse-files)", xmlFlag)
+					}
 				}
+			}
 
-				var wg sync.WaitGroup
-				var mu sync.Mutex
-				createdDirs := make(map[string]bool)
-				for _, inPath := range foundTemplateFiles {
-					wg.Add(1)
-					go func(inPath string) error {
-						defer wg.Done()
-						outPath := ""
-						bar := giveMeABar(inPath, &outPath, 5, mpbHandler)
-
-						// Read the template file (STEP)
-						templateFileContent, err := os.ReadFile(inPath)
-						if err != nil {
-							bar.Abort(false)
-							return fmt.Errorf("failed to read --parse-file '%w'", err)
-						}
-						generate, err := extractDigitInBrackets("file", inPath)
-						if err != nil {
-							bar.Abort(false)
-							return fmt.Errorf("failed to extract [digit] from '%w'", err)
-						}
-						bar.Increment()
+			if xmlName(xmlRoot) != xmlRoot || xmlName(xmlItem) != xmlItem {
+				return fmt.Errorf("--xml-root and --xml-item options must be valid XML element names")
+			}
 
-						// Parse the template file content (STEP)
-						var parseMap map[string]any
-						if err = json.Unmarshal(templateFileContent, &parseMap); err != nil {
-							bar.Abort(false)
-							return fmt.Errorf("failed to parse JSON from the provided --parse-file '%w'", err)
-						}
-						bar.Increment()
-
-						// Process the parsed map (STEP)
-						mocker := mocker.New()
-						parseMaps := make([]map[string]any, generate)
-						for i := range generate {
-							cpParseMap := deepcopy.Copy(parseMap).(map[string]any)
-							if err := processJsonMap(cpParseMap, mocker); err != nil {
-								bar.Abort(false)
-								return fmt.Errorf("%w", err)
-							}
-							parseMaps[i] = deepcopy.Copy(cpParseMap).(map[string]any)
-						}
-						bar.Increment()
+			if parallelism <= 0 {
+				return fmt.Errorf("--parallelism option must be greater than 0")
+			}
 
-						// Sanitize the parsed map (STEP)
-						for i := range parseMaps {
-							sanitizeJsonMap(parseMaps[i])
-						}
-						bar.Increment()
+			// Without --seed every run generates different data
+			if !cmd.Flags().Changed("seed") {
+				seed = rand.Int63()
+			}
 
-						// Write the processed map to a file (STEP)
-						if err := toFile(preserveFolderStructure, inPath, &outPath, parseFiles, &parseMaps, &mu, &createdDirs); err != nil {
-							bar.Abort(false)
-							return fmt.Errorf("%w", err)
-						}
-						bar.Increment()
+			if failFast && !runningParseFiles {
+				return fmt.Errorf("--fail-fast option is only available when using --parse-files")
+			}
 
-						return nil
-					}(inPath)
+			if len(countFlags) > 0 && !runningParseFiles {
+				return fmt.Errorf("--count option is only available when using --parse-files")
+			}
+			countOverrides := make([]*countOverride, 0, len(countFlags))
+			for _, countFlag := range countFlags {
+				override, err := parseCountOverride(countFlag)
+				if err != nil {
+					return err
 				}
-				wg.Wait()
+				countOverrides = append(countOverrides, override)
 			}
 
-			mpbHandler.Wait()
+			if cmd.Flags().Changed("scale") && !runningParseFiles && !runningParseJson {
+				return fmt.Errorf("--scale option is only available when using --parse-files or --parse-json")
+			}
+			scale, err := parseScale(scaleFlag)
+			if err != nil {
+				return err
+			}
 
-			return nil
-		},
-	}
+			if watch && !runningParseFiles {
+				return fmt.Errorf("--watch option is only available when using --parse-files")
+			}
+			if cmd.Flags().Changed("watch-interval") && !watch {
+				return fmt.Errorf("--watch-interval option is only available when using --watch")
+			}
+			if watchInterval <= 0 {
+				return fmt.Errorf("--watch-interval option must be greater than 0")
+			}
 
-	mockCmd.Flags().Bool("list", false, "list all available mock functions")
-	mockCmd.Flags().String("parse-str", "", "pass a string to be parsed. The mock data will be generated based on this provided string")
-	mockCmd.Flags().String("parse-json", "", "pass a JSON object as a string. The mock data will be generated based on this provided json object")
-	mockCmd.Flags().String("parse-files", "", "pass a path, directory, or glob pattern to find template files. The mock data will be generated based on the found template files")
-	mockCmd.Flags().Bool("preserve-folder-structure", false, "if set, the folder structure of the input files will be preserved in the output files (only available for --parse-file)")
-	mockCmd.Flags().Int("generate", 1, "pass the desired amount of root objects that will be generated (only available for --parse-json)")
+			var targetSize, recordSize int64
+			if targetSizeFlag != "" {
+				if runningParseStr || toStdout {
+					return fmt.Errorf("--target-size option is only available when writing files of --parse-files, --parse-json, --from-schema or --from-openapi")
+				}
+				if cmd.Flags().Changed("generate") {
+					return fmt.Errorf("--target-size and --generate options can't be used together")
+				}
+				targetSize, err = parseSize("target-size", targetSizeFlag)
+				if err != nil {
+					return err
+				}
+			}
+			if recordSizeFlag != "" {
+				if runningParseStr {
+					return fmt.Errorf("--record-size option is only available when using --parse-files, --parse-json, --from-schema or --from-openapi")
+				}
+				recordSize, err = parseSize("record-size", recordSizeFlag)
+				if err != nil {
+					return err
+				}
+			}
 
-	// Configure cobra ouput streams to use the custom 'Out'
-	mockCmd.SetOut(opts.Out)
+			for _, shardFlag := range []string{"shard-size", "shards"} {
+				if !cmd.Flags().Changed(shardFlag) {
+					continue
+				}
+				if runningParseStr || toStdout {
+					return fmt.Errorf("--%s option is only available when writing files of --parse-files, --parse-json, --from-schema or --from-openapi", shardFlag)
+				}
+				if targetSize > 0 {
+					return fmt.Errorf("--target-size and --%s options can't be used together", shardFlag)
+				}
+			}
+			if shardSize < 0 || (cmd.Flags().Changed("shard-size") && shardSize == 0) {
+				return fmt.Errorf("--shard-size option must be greater than 0")
+			}
+			if shards < 0 || (cmd.Flags().Changed("shards") && shards == 0) {
+				return fmt.Errorf("--shards option must be greater than 0")
+			}
+			if shardSize > 0 && shards > 0 {
+				return fmt.Errorf("--shard-size and --shards options can't be used together")
+			}
 
-	return mockCmd
-}
+			if clean && (runningParseStr || outFile != "" || toStdout) {
+				return fmt.Errorf("--clean option is only available when writing to --out-dir")
+			}
 
-// Splits a raw string of format "func:arg1:arg2:...".
-// It handles regex args wrapped with slashes (/.../) to avoid splitting inside them.
-// Returns: function name, and slice of parameter strings.
-func extractMockMethod(rawValue string) (string, []string) {
-	if rawValue == "" {
-		return "", nil
-	}
-	var parts []string
-	var buf strings.Builder
-	inRegex := false
+			if outDir == "" {
+				return fmt.Errorf("--out-dir option must not be empty")
+			}
 
-	trimmed := strings.TrimSpace(rawValue)
+			output := &outputOptions{
+				format: format,
+				csv: csvOptions{
+					delimiter:      delimiter,
+					arrays:         csvArrays,
+					arraySeparator: csvArraySeparator,
+					quote:          csvQuote,
+				},
+				sql: sqlOptions{
+					table:       table,
+					dialect:     dialect,
+					batchSize:   sqlBatchSize,
+					transaction: sqlTransaction,
+				},
+				xml: xmlOptions{
+					root:       xmlRoot,
+					item:       xmlItem,
+					attrPrefix: xmlAttrPrefix,
+				},
+				dir:                     outDir,
+				file:                    outFile,
+				force:                   force,
+				preserveFolderStructure: preserveFolderStructure,
+				parseFiles:              parseFiles,
+				recordSize:              recordSize,
+				targetSize:              targetSize,
+				shardSize:               shardSize,
+				shards:                  shards,
+			}
 
-	for _, char := range trimmed {
-		if char == '/' {
-			inRegex = !inRegex
-			// Always include slash
-			buf.WriteByte(byte(char))
-			continue
-		}
-		// If ':' outside regex — treat as delimiter
-		if char == ':' && !inRegex {
-			parts = append(parts, buf.String())
-			// Start building next segment
-			buf.Reset()
-			continue
-		}
-		// Default: build the current token
-		buf.WriteByte(byte(char))
-	}
+			// Clean previous output directory, only when asked to
+			if clean {
+				if err := cleanOutDir(outDir); err != nil {
+					return fmt.Errorf("failed to clean the output directory '%w'", err)
+				}
+			}
 
-	// Add the final piece (there's no trailing `:`)
-	if buf.Len() > 0 {
-		parts = append(parts, buf.String())
-	}
+			// Progress bars would be mixed with the generated data when printing it
+			var barsOutput io.Writer = os.Stdout
+			if toStdout {
+				barsOutput = io.Discard
+			}
+			mpbHandler := newProgressBars(barsOutput)
 
-	return parts[0], parts[1:]
-}
+			if runningParseStr {
+				// Process the string
+				mocker := mocker.NewWithSeed(seed)
+				mockedStr := engine.ProcessStr(parseStr, mocker)
 
-// Interprets a string value, checking if it contains a mock function between {{ }}.
-// If it does, it returns the function name and true.
-// If not, it returns the original string and false.
-func interpretString(rawValue string) (string, bool) {
-	if rawValue == "" {
-		return "", false
-	}
+				// Print the mocked string to STDOUT
+				fmt.Fprintf(opts.Out, "%s\n", mockedStr)
+			}
 
-	re := regexp.MustCompile(`^\s*{{\s*(.*?)\s*}}\s*$`)
-	matches := re.FindStringSubmatch(rawValue)
+			// Files written, to report the size they achieved (with --target-size) or their shards (with --shards)
+			var writtenOutputs []writtenOutput
 
-	if len(matches) > 0 {
-		return matches[1], true
-	}
+			// Reports the files written, and clears them for the next round of --watch
+			reportWrittenOutputs := func() {
+				if targetSize > 0 {
+					reportTargetSize(opts.Out, targetSize, writtenOutputs)
+				}
+				if output.shards > 0 {
+					reportFewerShards(opts.Out, output.shards, writtenOutputs)
+				}
+				writtenOutputs = nil
+			}
 
-	return rawValue, false
-}
+			// Parse string json object from `--parse-json`, or the JSON Schema of `--from-schema`
+			if runningSingleTemplate {
+				outPath := ""
+				bar := giveMeABar("CLI", &outPath, 4, mpbHandler)
 
-// Iterates through the parsed json map and processes each value.
-// It replaces string values with generated mock data based on the function name and parameters.
-// It handles nested maps and arrays of strings or maps.
-// Returns an error if any value is not a string or map.
-func processJsonMap(parseMap map[string]any, mocker *mocker.Mock) error {
-	objKeys := make([]string, 0, len(parseMap))
-	for key := range parseMap {
-		objKeys = append(objKeys, key)
-	}
-
-	for keyIndex := 0; keyIndex < len(objKeys); {
-		objKey := objKeys[keyIndex]
-		switch typedValue := parseMap[objKey].(type) {
-		case string:
-			// try to find [digit] in the "key"
-			generateAmount, err := extractDigitInBrackets("object", objKey)
-			if err != nil {
-				return err
-			}
-			// try to find the mock function in the "value"
-			interpretedValue, isMockFunction := interpretString(typedValue)
-			// if it's not a mock function, just replace the value
-			if !isMockFunction {
-				parseMap[objKey] = interpretedValue
-				keyIndex++
-				continue
-			}
-			// if it's a mock function, extract the function name and parameters
-			functionName, params := extractMockMethod(interpretedValue)
-			// either generate array of values, otherwise only one value
-			if generateAmount > 1 {
-				parseMap[objKey] = make([]string, generateAmount)
-				for i := range generateAmount {
-					mockValue, err := mocker.Generate(functionName, params)
+				// Parse the string object content, or load the schema (STEP)
+				var template any
+				var err error
+				switch {
+				case runningFromSchema:
+					template, err = engine.NewSchemaValue(fromSchema)
+					if err != nil {
+						return fmt.Errorf("failed to load the provided --from-schema '%w'", err)
+					}
+				case runningFromOpenapi:
+					template, err = engine.NewOpenapiOperation(fromOpenapi, operation, openapiPart, responseStatus)
+					if err != nil {
+						return fmt.Errorf("failed to load the provided --from-openapi '%w'", err)
+					}
+				default:
+					template, err = engine.UnmarshalTemplate([]byte(parseJson))
+					if err != nil {
+						return fmt.Errorf("failed to parse JSON from the provided --parse-json '%w'", err)
+					}
+					// The settings of "$meta" are used unless the flags are passed
+					meta, err := extractTemplateMeta(template)
+					if err != nil {
+						return fmt.Errorf("failed to read the settings of --parse-json '%w'", err)
+					}
+					if meta.count > 0 && !cmd.Flags().Changed("generate") {
+						generate = scaleCount(meta.count, scale, 1)
+					}
+					if meta.format != "" && !cmd.Flags().Changed("format") {
+						output.format = meta.format
+					}
+					if meta.seed != nil && !cmd.Flags().Changed("seed") {
+						seed = *meta.seed
+					}
+					output.name = meta.output
+					template, err = engine.ResolveTemplateRefs(template, ".")
 					if err != nil {
-						return err
+						return fmt.Errorf("failed to resolve the provided --parse-json '%w'", err)
 					}
-					parseMap[objKey].([]string)[i] = mockValue
+					if scale != 1 {
+						scaleTemplateKeys(template, scale)
+					}
+				}
+				// With --target-size, the records are generated until the file reaches the size, up to a limit estimated from a sample
+				if targetSize > 0 {
+					estimate, err := estimateRecordCount(template, seed, withoutRecordMocker, output, "mocked_data", recordSize, targetSize)
+					if err != nil {
+						return fmt.Errorf("failed to estimate the records of --target-size '%w'", err)
+					}
+					generate = targetRecordLimit(estimate)
+				}
+				bar.Increment()
+
+				// Process, sanitize and write (or print) the records, a chunk at a time (STEP)
+				var writer recordWriter
+				var file *os.File
+				if toStdout {
+					writer, err = newRecordWriter(output, "mocked_data", opts.Out, generate)
+				} else {
+					var mu sync.Mutex
+					createdDirs := make(map[string]bool, 1)
+					file, writer, err = openRecordOutput(output, "mocked-data", "mocked_data", generate, &outPath, &mu, &createdDirs)
 				}
-			} else {
-				mockValue, err := mocker.Generate(functionName, params)
 				if err != nil {
-					return err
+					return fmt.Errorf("%w", err)
 				}
-				parseMap[objKey] = mockValue
-			}
-			keyIndex++
-		case map[string]any:
-			// try to find [digit] in the "key"
-			generateAmount, err := extractDigitInBrackets("object", objKey)
-			if err != nil {
-				return err
-			}
-			// if generating multiple values, convert the map to a slice of maps (but force the type to generic any) and reprocess again
-			if generateAmount > 1 {
-				convertedValue := make([]any, generateAmount)
-				for i := range generateAmount {
-					convertedValue[i] = deepcopy.Copy(typedValue)
+				padder, err := newRecordPadder(output, "mocked_data", output.recordSize)
+				if err != nil {
+					return fmt.Errorf("%w", closeRecordOutput(file, writer, err))
 				}
-				parseMap[objKey] = convertedValue
-			} else {
-				if err := processJsonMap(typedValue, mocker); err != nil {
-					return err
+				sizer := newTargetSizer(file, writer, targetSize)
+				pool := newWorkerPool(parallelism)
+				pool.streamRecords(template, generate, seed, withoutRecordMocker, nil, func(records []any) error {
+					return writeRecords(writer, records, padder, sizer, nil)
+				}, func(streamErr error) {
+					err = streamErr
+				})
+				pool.close()
+				if sizer != nil {
+					generate = sizer.written
+					if errors.Is(err, errTargetSizeReached) {
+						err = nil
+					}
+				}
+				bar.Increment()
+				if err == nil {
+					err = writer.close()
+				}
+				bar.Increment()
+				if err = closeRecordOutput(file, writer, err); err != nil {
+					return fmt.Errorf("%w", err)
+				}
+				bar.Increment()
+				if !toStdout {
+					writtenOutputs = append(writtenOutputs, writtenOutput{path: outPath, records: generate})
 				}
-				keyIndex++
 			}
-		case []any:
-			for itemKey, item := range typedValue {
-				if itemStr, ok := item.(string); ok {
-					interpretedValue, isMockFunction := interpretString(itemStr)
-					if !isMockFunction {
-						typedValue[itemKey] = interpretedValue
-						continue
+
+			// Generates the templates found by `--parse-files`, except the ones `selectJobs` (when not nil) marks as unchanged.
+			// Records of referenced templates are kept in `registry`, so unchanged templates can still be referenced.
+			generateTemplateFiles := func(foundTemplateFiles []string, output *outputOptions, registry *refRegistry, mpbHandler *mpb.Progress, selectJobs func(jobs []*templateJob, dependencies [][]string)) ([]*templateJob, error) {
+				// Load every template first, so the references between them can be resolved
+				// When selecting the templates, only the selected ones get a visible progress bar
+				loadBars := mpbHandler
+				if selectJobs != nil {
+					loadBars = newProgressBars(io.Discard)
+					defer loadBars.Shutdown()
+				}
+				jobs := make([]*templateJob, len(foundTemplateFiles))
+				names := make([]string, len(foundTemplateFiles))
+				dependencies := make([][]string, len(foundTemplateFiles))
+				// With --fail-fast, the first failure stops any template not yet generated
+				var stopped atomic.Bool
+				for idx, inPath := range foundTemplateFiles {
+					job := &templateJob{inPath: inPath, name: templateName(inPath)}
+					job.bar = giveMeABar(inPath, &job.outPath, 5, loadBars)
+					if err := job.load(); err != nil {
+						job.fail(err)
+						if failFast {
+							stopped.Store(true)
+						}
 					}
-					functionName, params := extractMockMethod(interpretedValue)
-					mockValue, err := mocker.Generate(functionName, params)
-					if err != nil {
-						return err
+					jobs[idx] = job
+					names[idx] = job.name
+					dependencies[idx] = findRefDependencies(job.template)
+				}
+				// Counts may be scaled or overridden from the command line
+				if err := applyCountOverrides(jobs, countOverrides, scale); err != nil {
+					for _, job := range jobs {
+						job.fail(err)
 					}
-					typedValue[itemKey] = mockValue
-				} else if itemMap, ok := item.(map[string]any); ok {
-					err := processJsonMap(itemMap, mocker)
-					if err != nil {
-						return err
+					return jobs, err
+				}
+				// Only the records of referenced templates are kept in memory, every other one is just written
+				referencedNames := make(map[string]bool)
+				for _, jobDependencies := range dependencies {
+					for _, dependency := range jobDependencies {
+						referencedNames[dependency] = true
+					}
+				}
+				for _, job := range jobs {
+					job.referenced = referencedNames[job.name]
+				}
+				// Only the templates that can't be ordered fail (and the ones referencing them, when generated)
+				levels, sortErrs := sortByDependencies(names, dependencies)
+				for idx, err := range sortErrs {
+					if err != nil && jobs[idx].err == nil {
+						jobs[idx].fail(err)
+						if failFast {
+							stopped.Store(true)
+						}
+					}
+				}
+				if selectJobs != nil {
+					selectJobs(jobs, dependencies)
+					for _, job := range jobs {
+						if job.unchanged && job.err == nil {
+							continue
+						}
+						loadedSteps := job.bar.Current()
+						job.bar = giveMeABar(job.inPath, &job.outPath, 5, mpbHandler)
+						job.bar.SetCurrent(loadedSteps)
+						if job.err != nil {
+							job.bar.Abort(false)
+						}
 					}
-				} else {
-					return fmt.Errorf("value '%v' is not a string or map", item)
 				}
+
+				// Generate the templates level by level, templates in the same level are independent of each other
+				// The records of every template in a level are split across the same workers
+				var mu sync.Mutex
+				createdDirs := make(map[string]bool)
+				failedNames := make(map[string]bool)
+				pool := newWorkerPool(parallelism)
+				for _, level := range levels {
+					var wg sync.WaitGroup
+					for _, idx := range level {
+						job := jobs[idx]
+						if job.err != nil || job.unchanged {
+							continue
+						}
+						if stopped.Load() {
+							job.fail(errSkippedByFailFast)
+							continue
+						}
+						for _, dependency := range dependencies[idx] {
+							if failedNames[dependency] {
+								job.fail(fmt.Errorf("referenced template '%s' failed", dependency))
+								break
+							}
+						}
+						if job.err != nil {
+							continue
+						}
+						wg.Add(1)
+						job.run(pool, deriveSeed(seed, seedScope(parseFiles, job.inPath)), registry, output, &mu, &createdDirs, &stopped, func(err error) {
+							defer wg.Done()
+							if err != nil {
+								job.fail(err)
+								if failFast {
+									stopped.Store(true)
+								}
+							}
+						})
+					}
+					wg.Wait()
+					for _, idx := range level {
+						if jobs[idx].err != nil {
+							failedNames[jobs[idx].name] = true
+						}
+					}
+				}
+				pool.close()
+				return jobs, nil
 			}
-			keyIndex++
-		default:
-			return fmt.Errorf("value '%v' is not a string, map or array", typedValue)
-		}
-	}
-	return nil
-}
 
-// Iterates through the parsed json map and sanitizes the keys by removing segments between bracketes (e.g. [digits]).
-// It handles nested maps.
-func sanitizeJsonMap(parseMap map[string]any) {
-	// Clone keys to avoid modifying map during iteration
-	objKeys := make([]string, 0, len(parseMap))
-	for objKey := range parseMap {
-		objKeys = append(objKeys, objKey)
-	}
+			// Reports the files of the templates generated (see reportWrittenOutputs), and every template that failed
+			reportTemplateFiles := func(jobs []*templateJob) error {
+				for _, job := range jobs {
+					if job.err == nil && !job.unchanged {
+						writtenOutputs = append(writtenOutputs, writtenOutput{path: job.outPath, records: job.generate})
+					}
+				}
+				reportWrittenOutputs()
+				return reportFailedJobs(opts.Out, jobs)
+			}
 
-	for _, objKey := range objKeys {
-		objValue := parseMap[objKey]
-		sanitizedKey := sanitizeKeyWithBrackets(objKey)
+			// Parse object from `--parse-files` files
+			if runningParseFiles {
+				foundTemplateFiles, err := findTemplateFiles(parseFiles)
+				if err != nil {
+					return fmt.Errorf("failed to find template files from the provided --parse-files '%w'", err)
+				}
+				if len(foundTemplateFiles) == 0 {
+					return fmt.Errorf("no template files found in the provided --parse-files '%s'", parseFiles)
+				}
+				registry := newRefRegistry()
+				jobs, err := generateTemplateFiles(foundTemplateFiles, output, registry, mpbHandler, nil)
+				mpbHandler.Wait()
+				if err == nil {
+					err = reportTemplateFiles(jobs)
+				}
+				if !watch {
+					return err
+				}
 
-		// Recurse on nested maps
-		if mapValue, ok := objValue.(map[string]any); ok {
-			sanitizeJsonMap(mapValue)
-		}
+				// Keep generating the templates changed, until interrupted, reporting their errors instead of exiting
+				if err != nil {
+					fmt.Fprintf(opts.Out, "Error: %v\n", err)
+				}
+				ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+				defer stop()
+				fmt.Fprintf(opts.Out, "\nWatching '%s' for changes (press Ctrl+C to stop)\n", parseFiles)
+				watchTemplateFiles(ctx, parseFiles, watchInterval, jobs, opts.Out, func(foundTemplateFiles []string, changed map[string]bool) []*templateJob {
+					// The files of the changed templates are written again
+					roundOutput := *output
+					roundOutput.force = true
+					roundBars := newProgressBars(barsOutput)
+					jobs, err := generateTemplateFiles(foundTemplateFiles, &roundOutput, registry, roundBars, func(jobs []*templateJob, dependencies [][]string) {
+						selectChangedJobs(jobs, dependencies, changed, registry)
+					})
+					roundBars.Wait()
+					if err == nil {
+						err = reportTemplateFiles(jobs)
+					}
+					if err != nil {
+						fmt.Fprintf(opts.Out, "Error: %v\n", err)
+					}
+					return jobs
+				})
+				return nil
+			}
 
-		if sanitizedKey != objKey {
-			parseMap[sanitizedKey] = objValue
-			delete(parseMap, objKey)
-		}
-	}
-}
+			mpbHandler.Wait()
+			reportWrittenOutputs()
 
-// Process a simple string value, checking if it contains a mock function.
-// If it does, it generates the mock value using the mocker.
-// If not, it returns the original string.
-func processStr(parseStr string, mocker *mocker.Mock) string {
-	dBracketsPatterns := regexp.MustCompile(`{{\s*([^}]+?)\s*}}`)
+			return nil
+		},
+	}
 
-	all := dBracketsPatterns.ReplaceAllStringFunc(parseStr, func(match string) string {
-		interpretedValue := dBracketsPatterns.FindStringSubmatch(match)[1]
-		interpretedValue = strings.TrimSpace(interpretedValue)
+	mockCmd.Flags().Bool("list", false, "list all available mock functions")
+	mockCmd.Flags().String("parse-str", "", "pass a string to be parsed. The mock data will be generated based on this provided string")
+	mockCmd.Flags().String("parse-json", "", "pass a JSON object as a string. The mock data will be generated based on this provided json object")
+	mockCmd.Flags().String("parse-files", "", "pass a path, directory, or glob pattern to find template files. The mock data will be generated based on the found template files")
+	mockCmd.Flags().String("from-schema", "", "pass a JSON Schema file. The mock data will be valid instances of the schema")
+	mockCmd.Flags().String("from-openapi", "", "pass an OpenAPI 3 document. The mock data will be valid requests and responses of its --operation")
+	mockCmd.Flags().String("operation", "", "pass the operationId (or the method and path, e.g. \"POST /users\") of the operation to generate (only available for --from-openapi)")
+	mockCmd.Flags().String("openapi-part", engine.OpenapiPartAll, "pass what is generated for the operation: 'all' (the path, query parameters, request body and response), 'request' (the request body), 'query' (the query parameters) or 'response' (the response body) (only available for --from-openapi)")
+	mockCmd.Flags().String("response-status", "", "pass the status of the generated response (e.g. '404' or 'default'), defaults to the first successful one (only available for --from-openapi)")
+	mockCmd.Flags().Bool("preserve-folder-structure", false, "if set, the folder structure of the input files will be preserved in the output files (only available for --parse-file)")
+	mockCmd.Flags().Int("generate", 1, "pass the desired amount of root objects that will be generated (only available for --parse-json, --from-schema or --from-openapi)")
+	mockCmd.Flags().String("target-size", "", "pass the size of each generated file (e.g. '50MB'), generating records until the file reaches it instead of the counts of the templates (not available for --stdout)")
+	mockCmd.Flags().String("record-size", "", "pass the approximate size of each generated record in the output format (e.g. '8KB'), padding the records with a '_padding' key (only available for templates whose root is an object)")
+	mockCmd.Flags().Int("shard-size", 0, "pass the maximum amount of records of each file, splitting the records in numbered files (e.g. 'employees-0001.json') listed in a manifest (e.g. 'employees.manifest.json') with their counts and SHA-256 checksums (not available for --stdout)")
+	mockCmd.Flags().Int("shards", 0, "pass the amount of files the records are split in, as evenly as possible, as --shard-size does (fewer files when there are fewer records, not available for --stdout)")
+	mockCmd.Flags().Bool("watch", false, "if set, the template files are watched after being generated, and the changed ones (and the ones referencing them) are generated again, until interrupted (only available for --parse-files)")
+	mockCmd.Flags().Duration("watch-interval", time.Second, "pass how often the template files are checked for changes (only available for --watch)")
+	mockCmd.Flags().String("out-dir", "out", "pass the directory where the generated files will be written")
+	mockCmd.Flags().String("out-file", "", "pass the file where the generated data will be written (only available for --parse-json, --from-schema or --from-openapi)")
+	mockCmd.Flags().Bool("stdout", false, "if set, the generated data is printed instead of written to a file (only available for --parse-json, --from-schema or --from-openapi)")
+	mockCmd.Flags().Bool("clean", false, "if set, the output directory is removed before writing the generated files")
+	mockCmd.Flags().Bool("force", false, "if set, existing files are overwritten by the generated files")
+	mockCmd.Flags().Int("parallelism", runtime.NumCPU(), "pass the maximum amount of records generated at the same time, across all template files")
+	mockCmd.Flags().Int64("seed", 0, "pass a seed to always generate the same data (the amount of --parallelism doesn't change the generated data)")
+	mockCmd.Flags().String("format", formatJson, "pass the format of the generated data: 'json' (an array of records), 'ndjson' (a record per line), 'csv' or 'tsv' (a row per record), 'sql' (INSERT statements), 'yaml', 'xml' or 'toml'. Templates can also ask for a format in their name (e.g. \"company[10].yaml.template.json\")")
+	mockCmd.Flags().String("csv-delimiter", "", "pass the column delimiter, defaults to ',' for csv and a tab for tsv (only available for --format csv or tsv)")
+	mockCmd.Flags().String("csv-arrays", csvArraysJoin, "pass how arrays are written: 'join' (in a single column), 'index' (a column per item) or 'explode' (a row per item) (only available for --format csv or tsv)")
+	mockCmd.Flags().String("csv-array-separator", "|", "pass the separator of the items of joined arrays (only available for --format csv or tsv)")
+	mockCmd.Flags().String("csv-quote", csvQuoteMinimal, "pass which fields are quoted: 'minimal' (only the ones that need it) or 'all' (only available for --format csv or tsv)")
+	mockCmd.Flags().String("table", "", "pass the table the records are inserted into, defaults to the name of the template (only available for --format sql)")
+	mockCmd.Flags().String("dialect", sqlDialectPostgres, "pass the SQL dialect: 'postgres', 'mysql', 'sqlite' or 'mssql' (only available for --format sql)")
+	mockCmd.Flags().Int("sql-batch-size", 100, "pass the amount of records inserted by each INSERT statement (only available for --format sql)")
+	mockCmd.Flags().Bool("sql-transaction", false, "if set, the INSERT statements are wrapped in a transaction (only available for --format sql)")
+	mockCmd.Flags().String("xml-root", "records", "pass the name of the root element (only available for --format xml)")
+	mockCmd.Flags().String("xml-item", "record", "pass the name of the element of each record (only available for --format xml)")
+	mockCmd.Flags().String("xml-attr-prefix", "@", "pass the prefix of the keys written as attributes of their element, instead of child elements (only available for --format xml)")
+	mockCmd.Flags().StringArray("count", nil, "pass the amount of root objects of a template (e.g. 'company=100'), or of the items of one of its keys (e.g. 'company.employees=50'), taking the place of the one in its name or \"$meta\" (only available for --parse-files), can be repeated")
+	mockCmd.Flags().String("scale", "1x", "pass a multiplier of the counts of the templates, in their names, \"$meta\" and keys (e.g. '10x' or '0.5x'), not applied to --count and --generate (only available for --parse-files or --parse-json)")
+	mockCmd.Flags().Bool("fail-fast", false, "if set, stops generating the remaining templates after the first one fails (only available for --parse-files)")
 
-		functionName, params := extractMockMethod(interpretedValue)
+	// Configure cobra ouput streams to use the custom 'Out'
+	mockCmd.SetOut(opts.Out)
 
-		mockValue, err := mocker.Generate(functionName, params)
-		if err != nil {
-			return fmt.Sprintf("[%v]", err)
-		}
-		return mockValue
-	})
+	mockCmd.AddCommand(newMockInferCmd(opts))
 
-	return all
+	return mockCmd
 }
 
 // Extracts a digit from a string in the format "content[<digit>]" or "content[<digit>].template.json".
@@ -523,7 +897,7 @@ func extractDigitInBrackets(place string, str string) (int, error) {
 	if place == "file" {
 		matches = filenameNumberRegex.FindStringSubmatch(str)
 	} else if place == "object" {
-		matches = objKeyNumberRegex.FindStringSubmatch(str)
+		return engine.ExtractKeyCount(str)
 	} else {
 		return 0, fmt.Errorf("invalid value '%s' (must be either 'file' or 'object')", place)
 	}
@@ -532,11 +906,7 @@ func extractDigitInBrackets(place string, str string) (int, error) {
 		if !regexp.MustCompile(`[\[\]]`).MatchString(str) {
 			return 1, nil
 		}
-		if place == "file" {
-			return 0, fmt.Errorf("invalid format '%s' (must be 'text[digit].template.json')", str)
-		} else if place == "object" {
-			return 0, fmt.Errorf("invalid format '%s' (must be 'text[digit]')", str)
-		}
+		return 0, fmt.Errorf("invalid format '%s' (must be 'text[digit].template.json')", str)
 	}
 
 	digit, err := strconv.Atoi(matches[1])
@@ -551,22 +921,7 @@ func extractDigitInBrackets(place string, str string) (int, error) {
 	return digit, nil
 }
 
-// Removes the segment of a string between brackets, including the brackets themselves.
-// It returns the cleaned string.
-func sanitizeKeyWithBrackets(str string) string {
-	startBracket := strings.Index(str, "[")
-	endBracket := strings.Index(str, "]")
-
-	if startBracket != -1 && endBracket != -1 && endBracket > startBracket {
-		segment := str[startBracket : endBracket+1]
-		// Remove the segment from the original string
-		strCleaned := strings.Replace(str, segment, "", 1)
-		return strCleaned
-	}
-	return str
-}
-
-// Returns all *.template.json files from a path, directory, or glob.
+// Returns all template files (*.template.json, *.template.yaml, ...) from a path, directory, or glob.
 // It's recursive for directories, and respects any wildcard pattern.
 func findTemplateFiles(input string) ([]string, error) {
 	var matchedFiles []string
@@ -578,7 +933,7 @@ func findTemplateFiles(input string) ([]string, error) {
 			if err != nil {
 				return err
 			}
-			if !fi.IsDir() && strings.HasSuffix(path, ".template.json") {
+			if !fi.IsDir() && engine.IsTemplateFile(path) {
 				matchedFiles = append(matchedFiles, path)
 			}
 			return nil
@@ -600,7 +955,7 @@ func findTemplateFiles(input string) ([]string, error) {
 		if err != nil {
 			continue
 		}
-		if !info.IsDir() && strings.HasSuffix(file, ".template.json") {
+		if !info.IsDir() && engine.IsTemplateFile(file) {
 			matchedFiles = append(matchedFiles, file)
 		}
 	}
@@ -608,52 +963,377 @@ func findTemplateFiles(input string) ([]string, error) {
 	return matchedFiles, nil
 }
 
-// Writes the generated mock data to a file.
+// Reason of the templates not generated because another one failed, when using --fail-fast.
+var errSkippedByFailFast = errors.New("skipped after another template failed (--fail-fast)")
+
+// A template file found by --parse-files, and its progress while being generated.
+// `files` are the (absolute) paths of the template and of the files it includes, and `unchanged` marks the templates
+// not generated again by --watch.
+type templateJob struct {
+	inPath     string
+	name       string
+	generate   int
+	template   any
+	meta       *templateMeta
+	bar        *mpb.Bar
+	outPath    string
+	referenced bool
+	files      []string
+	unchanged  bool
+	err        error
+}
+
+// Reads, parses and resolves the template file.
+func (job *templateJob) load() error {
+	if absPath, err := filepath.Abs(job.inPath); err == nil {
+		job.files = []string{absPath}
+	}
+
+	// Read the template file (STEP)
+	templateFileContent, err := os.ReadFile(job.inPath)
+	if err != nil {
+		return fmt.Errorf("failed to read --parse-file '%w'", err)
+	}
+	job.bar.Increment()
+
+	// Parse the template file content, and its settings (STEP)
+	job.template, err = engine.UnmarshalTemplateFile(job.inPath, templateFileContent)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s from the provided --parse-file '%w'", engine.TemplateLanguage(job.inPath), err)
+	}
+	job.meta, err = extractTemplateMeta(job.template)
+	if err != nil {
+		return fmt.Errorf("failed to read the settings of --parse-file '%s' '%w'", job.inPath, err)
+	}
+	// The count of "$meta" takes the place of the one in the name
+	job.generate = job.meta.count
+	if job.generate == 0 {
+		job.generate, err = extractDigitInBrackets("file", job.inPath)
+		if err != nil {
+			return fmt.Errorf("failed to extract [digit] from '%w'", err)
+		}
+	}
+	var includedFiles []string
+	job.template, includedFiles, err = engine.ResolveTemplateRefsAndFiles(job.template, filepath.Dir(job.inPath))
+	job.files = append(job.files, includedFiles...)
+	if err != nil {
+		return fmt.Errorf("failed to resolve --parse-file '%s' '%w'", job.inPath, err)
+	}
+	job.bar.Increment()
+
+	return nil
+}
+
+// Generates the records of the loaded template in the workers, writing them as they are generated, and registers them
+// (so other templates can reference them). It stops early when `stopped` is set by another template failing.
+// `done` is called once the template is written, or with the reason it failed.
+func (job *templateJob) run(pool *workerPool, seed int64, registry *refRegistry, output *outputOptions, mu *sync.Mutex, createdDirs *map[string]bool, stopped *atomic.Bool, done func(err error)) {
+	// The template may ask for another format (and output file) in its "$meta" or its name, and for its own seed
+	jobOutput := *output
+	jobOutput.format = templateFormat(job.inPath, output.format)
+	if job.meta.format != "" {
+		jobOutput.format = job.meta.format
+	}
+	jobOutput.name = job.meta.output
+	output = &jobOutput
+	if job.meta.seed != nil {
+		seed = *job.meta.seed
+	}
+
+	// With --target-size, the records are generated until the file reaches the size, up to a limit estimated from a sample
+	if output.targetSize > 0 {
+		samplePicker := newRefPicker(registry, sizeSampleRecords, deriveSeed(seed, refPickFunction))
+		estimate, err := estimateRecordCount(job.template, seed, func(base mocker.Mocker, record int) mocker.Mocker {
+			return newRefMocker(base, samplePicker, record)
+		}, output, job.name, output.recordSize, output.targetSize)
+		if err != nil {
+			done(fmt.Errorf("failed to estimate the records of --target-size '%w'", err))
+			return
+		}
+		job.generate = targetRecordLimit(estimate)
+	}
+
+	file, writer, err := openRecordOutput(output, job.inPath, job.name, job.generate, &job.outPath, mu, createdDirs)
+	if err != nil {
+		done(err)
+		return
+	}
+	padder, err := newRecordPadder(output, job.name, output.recordSize)
+	if err != nil {
+		done(closeRecordOutput(file, writer, err))
+		return
+	}
+
+	// Process, sanitize and write the records, a chunk at a time (STEP)
+	picker := newRefPicker(registry, job.generate, deriveSeed(seed, refPickFunction))
+	recordMocker := func(base mocker.Mocker, record int) mocker.Mocker {
+		return newRefMocker(base, picker, record)
+	}
+	var referencedRecords *[]any
+	if job.referenced {
+		referencedRecords = &[]any{}
+	}
+	sizer := newTargetSizer(file, writer, output.targetSize)
+	pool.streamRecords(job.template, job.generate, seed, recordMocker, stopped, func(records []any) error {
+		return writeRecords(writer, records, padder, sizer, referencedRecords)
+	}, func(err error) {
+		if sizer != nil {
+			job.generate = sizer.written
+			if errors.Is(err, errTargetSizeReached) {
+				err = nil
+			}
+		}
+		if err == nil {
+			err = picker.verify()
+		}
+		job.bar.Increment()
+		if err == nil {
+			err = writer.close()
+		}
+		job.bar.Increment()
+		if err = closeRecordOutput(file, writer, err); err != nil {
+			done(err)
+			return
+		}
+		if referencedRecords != nil {
+			registry.add(job.name, *referencedRecords)
+		}
+		job.bar.Increment()
+		done(nil)
+	})
+}
+
+// Sanitizes, pads (when `padder` is not nil) and writes the records of a chunk, also keeping them in `kept` (when not nil).
+// With a `sizer`, it returns errTargetSizeReached (writing no more records) once the file reaches --target-size.
+func writeRecords(writer recordWriter, records []any, padder *recordPadder, sizer *targetSizer, kept *[]any) error {
+	for _, record := range records {
+		if sizer != nil {
+			if err := sizer.check(); err != nil {
+				return err
+			}
+		}
+		engine.SanitizeValue(record)
+		if padder != nil {
+			if err := padder.pad(record); err != nil {
+				return err
+			}
+		}
+		if err := writer.write(record); err != nil {
+			return fmt.Errorf("failed to write record '%w'", err)
+		}
+		if sizer != nil {
+			if err := sizer.add(record); err != nil {
+				return err
+			}
+		}
+		if kept != nil {
+			*kept = append(*kept, record)
+		}
+	}
+	return nil
+}
+
+// Uses the worker's mocker as is for every record.
+func withoutRecordMocker(base mocker.Mocker, record int) mocker.Mocker {
+	return base
+}
+
+// Marks the template as failed with the reason, aborting its progress bar.
+func (job *templateJob) fail(err error) {
+	if job.err == nil {
+		job.err = err
+		job.bar.Abort(false)
+	}
+}
+
+// Lists every template that failed (instead of stopping at the first one), returning the error summarizing them.
+func reportFailedJobs(out io.Writer, jobs []*templateJob) error {
+	failedJobs, skippedJobs := summarizeJobs(jobs)
+	if len(failedJobs) == 0 {
+		return nil
+	}
+	fmt.Fprintf(out, "\nFailed templates:\n")
+	for _, job := range append(failedJobs, skippedJobs...) {
+		fmt.Fprintf(out, "  - %s: %v\n", job.inPath, job.err)
+	}
+	if len(skippedJobs) > 0 {
+		return fmt.Errorf("%d of %d templates failed (%d skipped by --fail-fast)", len(failedJobs), len(jobs), len(skippedJobs))
+	}
+	return fmt.Errorf("%d of %d templates failed", len(failedJobs), len(jobs))
+}
+
+// Splits the templates that failed from the ones skipped because of --fail-fast.
+func summarizeJobs(jobs []*templateJob) ([]*templateJob, []*templateJob) {
+	var failedJobs, skippedJobs []*templateJob
+	for _, job := range jobs {
+		if errors.Is(job.err, errSkippedByFailFast) {
+			skippedJobs = append(skippedJobs, job)
+		} else if job.err != nil {
+			failedJobs = append(failedJobs, job)
+		}
+	}
+	return failedJobs, skippedJobs
+}
+
+// Where the generated mock data is written, and how large it is.
+// `file` is the exact output file, and `name` the one asked by the "$meta" of a template (inside `dir`).
+// `recordSize` pads every record to about that many bytes, and `targetSize` generates as many records as fill that many
+// bytes (both are unset when 0). `shardSize` splits the records in files of that many records, or `shards` in that many files.
+type outputOptions struct {
+	format                  string
+	csv                     csvOptions
+	sql                     sqlOptions
+	xml                     xmlOptions
+	dir                     string
+	file                    string
+	name                    string
+	force                   bool
+	preserveFolderStructure bool
+	parseFiles              string
+	recordSize              int64
+	targetSize              int64
+	shardSize               int
+	shards                  int
+}
+
+// Creates the file where the records generated from `inPath` are written, named after the template (or its "$meta") and the output format.
 // It creates the directory structure if it doesn't exist.
 // If `preserve-folder-structure` is true, it keeps the original folder structure.
-func toFile(preserveFolderStructure bool, inPath string, outPath *string, parseFiles string, result *[]map[string]any, mu *sync.Mutex, createdDirs *map[string]bool) error {
-	var prettyJSON []byte
+// Existing files are only overwritten when `force` is true.
+func openOutputFile(output *outputOptions, inPath string, outPath *string, mu *sync.Mutex, createdDirs *map[string]bool) (*os.File, error) {
 	var err error
-	if len(*result) == 1 {
-		prettyJSON, err = json.MarshalIndent((*result)[0], "", "  ")
-	} else {
-		prettyJSON, err = json.MarshalIndent(result, "", "  ")
-	}
+	*outPath, err = outputPath(output, inPath)
 	if err != nil {
-		return fmt.Errorf("error marshalling JSON '%w'", err)
+		return nil, err
 	}
+	return createOutputFile(output, *outPath, mu, createdDirs)
+}
 
-	if preserveFolderStructure {
-		normalizedParseFrom, err := normalizeParseFrom(parseFiles)
+// Returns the path of the output file of a template.
+func outputPath(output *outputOptions, inPath string) (string, error) {
+	if output.file != "" {
+		return output.file, nil
+	}
+	if output.preserveFolderStructure {
+		normalizedParseFrom, err := normalizeParseFrom(output.parseFiles)
 		if err != nil {
-			return fmt.Errorf("failed to normalize '--parse-file' path '%w'", err)
+			return "", fmt.Errorf("failed to normalize '--parse-file' path '%w'", err)
 		}
 		relPath, err := filepath.Rel(normalizedParseFrom, inPath)
 		if err != nil {
-			return fmt.Errorf("failed to get relative path '%w'", err)
+			return "", fmt.Errorf("failed to get relative path '%w'", err)
 		}
-		relPath = strings.Replace(relPath, ".template.json", ".json", 1)
-		*outPath = filepath.Join("out", relPath)
-	} else {
-		outName := strings.Replace(filepath.Base(inPath), ".template.json", ".json", 1)
-		*outPath = filepath.Join("out", outName)
+		if output.name != "" {
+			return filepath.Join(output.dir, filepath.Dir(relPath), metaOutputName(output.name, output.format)), nil
+		}
+		return filepath.Join(output.dir, outputName(relPath, output.format)), nil
+	}
+	if output.name != "" {
+		return filepath.Join(output.dir, metaOutputName(output.name, output.format)), nil
 	}
+	return filepath.Join(output.dir, outputName(filepath.Base(inPath), output.format)), nil
+}
 
+// Creates an output file (and its folders), refusing to overwrite an existing one unless --force is set.
+func createOutputFile(output *outputOptions, outPath string, mu *sync.Mutex, createdDirs *map[string]bool) (*os.File, error) {
 	// Any created folders must be Thread-safe
-	dir := filepath.Dir(*outPath)
+	dir := filepath.Dir(outPath)
 	mu.Lock()
 	if !(*createdDirs)[dir] {
 		if err := os.MkdirAll(dir, 0755); err != nil {
 			mu.Unlock()
-			return fmt.Errorf("failed to create directory '%v', '%w'", dir, err)
+			return nil, fmt.Errorf("failed to create directory '%v', '%w'", dir, err)
 		}
 		(*createdDirs)[dir] = true
 	}
 	mu.Unlock()
 
-	err = os.WriteFile(*outPath, prettyJSON, 0644)
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !output.force {
+		flags |= os.O_EXCL
+	}
+	file, err := os.OpenFile(outPath, flags, 0644)
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("'%s' already exists (use --force to overwrite it)", outPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write result to '%v', '%w'", outPath, err)
+	}
+	return file, nil
+}
+
+// Opens the output of the `total` records of a template: its output file, or its shards (with --shard-size or --shards),
+// returning the writer of the records. The file is nil when the records are sharded, as the writer creates the shards.
+func openRecordOutput(output *outputOptions, inPath string, name string, total int, outPath *string, mu *sync.Mutex, createdDirs *map[string]bool) (*os.File, recordWriter, error) {
+	if output.shardSize > 0 || output.shards > 0 {
+		writer, err := newShardedWriter(output, inPath, name, total, mu, createdDirs)
+		if err != nil {
+			return nil, nil, err
+		}
+		*outPath = writer.manifestPath
+		return nil, writer, nil
+	}
+	file, err := openOutputFile(output, inPath, outPath, mu, createdDirs)
+	if err != nil {
+		return nil, nil, err
+	}
+	writer, err := newRecordWriter(output, name, file, total)
+	if err != nil {
+		return nil, nil, closeOutputFile(file, err)
+	}
+	return file, writer, nil
+}
+
+// Closes the output opened by openRecordOutput, after its writer is closed, removing what was written when the
+// generation failed (`err`).
+func closeRecordOutput(file *os.File, writer recordWriter, err error) error {
+	if discardable, ok := writer.(discardableWriter); ok && err != nil {
+		discardable.discard()
+	}
+	if file != nil {
+		return closeOutputFile(file, err)
+	}
+	return err
+}
+
+// Closes an output file, removing it when the generation failed (`err`), so no partial file is left behind.
+func closeOutputFile(file *os.File, err error) error {
+	closeErr := file.Close()
+	if err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write result to '%v', '%w'", file.Name(), closeErr)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to write result to '%v', '%w'", outPath, err)
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+// Returns the name of the output file of a template (e.g. "company[10].template.json" -> "company[10].ndjson").
+// The format in the name of the template is replaced (e.g. "company[10].yaml.template.json" -> "company[10].yaml").
+func outputName(templatePath string, format string) string {
+	name := engine.TrimTemplateExtension(templatePath)
+	if templateFormat(name, "") != "" {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return name + formatExtension(format)
+}
+
+// Removes the output directory, refusing to remove the current directory or any of its parents.
+func cleanOutDir(outDir string) error {
+	absOutDir, err := filepath.Abs(outDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path '%s' '%w'", outDir, err)
+	}
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get the current directory '%w'", err)
+	}
+	relPath, err := filepath.Rel(absOutDir, workingDir)
+	if err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("refusing to remove '%s', it contains the current directory", outDir)
+	}
+	if err := os.RemoveAll(absOutDir); err != nil {
+		return fmt.Errorf("failed to remove '%s' '%w'", outDir, err)
 	}
 	return nil
 }
@@ -672,6 +1352,27 @@ func normalizeParseFrom(input string) (string, error) {
 	return base, nil
 }
 
+// Returns the path of a template relative to --parse-files, so its seed doesn't depend on how the path was written.
+func seedScope(parseFiles string, inPath string) string {
+	normalizedParseFrom, err := normalizeParseFrom(parseFiles)
+	if err != nil {
+		return inPath
+	}
+	relPath, err := filepath.Rel(normalizedParseFrom, inPath)
+	if err != nil {
+		return inPath
+	}
+	return filepath.ToSlash(relPath)
+}
+
+func newProgressBars(out io.Writer) *mpb.Progress {
+	return mpb.New(
+		mpb.WithWidth(60),
+		mpb.WithOutput(out),
+		mpb.WithAutoRefresh(),
+	)
+}
+
 func giveMeABar(taskName string, outPath *string, steps int64, mpbHandler *mpb.Progress) *mpb.Bar {
 	startElapsedTime := time.Now()
 	var elapsedTime time.Duration
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+)
+
+// A count asked with --count, either of the root objects of a template ("company=100") or of the items of one of
+// its keys, by their path without brackets ("company.departments.employees=50").
+type countOverride struct {
+	raw    string
+	target string
+	count  int
+}
+
+// Parses a --count value of format "<template name or key path>=<count>".
+func parseCountOverride(raw string) (*countOverride, error) {
+	target, rawCount, found := strings.Cut(raw, "=")
+	target = strings.TrimSpace(target)
+	if !found || target == "" || strings.HasPrefix(target, ".") || strings.HasSuffix(target, ".") || strings.Contains(target, "..") {
+		return nil, fmt.Errorf("invalid --count '%s' (must be '<template>=<count>' or '<template>.<key>=<count>')", raw)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(rawCount))
+	if err != nil || count <= 0 {
+		return nil, fmt.Errorf("invalid --count '%s' (the count must be a whole number greater than 0)", raw)
+	}
+	return &countOverride{raw: raw, target: target, count: count}, nil
+}
+
+// Parses a --scale value, a multiplier with an optional "x" (e.g. "10x" or "0.5x").
+func parseScale(raw string) (float64, error) {
+	scale, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(raw), "x"), 64)
+	if err != nil || scale <= 0 || math.IsInf(scale, 0) {
+		return 0, fmt.Errorf("invalid --scale '%s' (must be a number greater than 0, e.g. '10x')", raw)
+	}
+	return scale, nil
+}
+
+// Multiplies a count by the scale, rounding it and keeping it at least `minimum`.
+func scaleCount(count int, scale float64, minimum int) int {
+	return max(int(math.Round(float64(count)*scale)), minimum)
+}
+
+// Multiplies the count between brackets of every key of a template (e.g. "employees[5]" -> "employees[50]"), at any
+// depth. Only the outer count of keys with many dimensions is scaled, and arrays keep at least 2 items (a key with
+// "[1]" is a single object, not an array).
+func scaleTemplateKeys(template any, scale float64) {
+	switch typedValue := template.(type) {
+	case *engine.OrderedMap:
+		for _, objKey := range typedValue.Keys() {
+			objValue, _ := typedValue.Get(objKey)
+			scaleTemplateKeys(objValue, scale)
+			if scaledKey, ok := scaleKey(objKey, scale); ok && scaledKey != objKey {
+				typedValue.Rename(objKey, scaledKey)
+			}
+		}
+	case []any:
+		for _, item := range typedValue {
+			scaleTemplateKeys(item, scale)
+		}
+	}
+}
+
+// Returns the key with its count scaled, or false when it has no count.
+func scaleKey(objKey string, scale float64) (string, bool) {
+	if mockFunction, brackets, isDynamicKey := engine.SplitDynamicKey(objKey); isDynamicKey {
+		if brackets == "" {
+			return "", false
+		}
+		generateAmount, err := engine.ExtractKeyCount("key" + brackets)
+		if err != nil {
+			return "", false
+		}
+		return fmt.Sprintf("{{ %s }}[%d]", strings.TrimSpace(mockFunction), scaleCount(generateAmount, scale, 1)), true
+	}
+	dimensions, err := engine.ExtractDimensionsInBrackets(objKey)
+	if err != nil || len(dimensions) == 0 {
+		return "", false
+	}
+	dimensions[0] = scaleCount(dimensions[0], scale, 2)
+	return dimensionsKey(objKey, dimensions), true
+}
+
+// Sets the outer count of the keys at a path (e.g. ["departments", "employees"]), returning how many keys were set.
+// Keys are matched without their brackets, and arrays are searched item by item.
+func overrideKeyCount(template any, keyPath []string, count int) (int, error) {
+	switch typedValue := template.(type) {
+	case *engine.OrderedMap:
+		matched := 0
+		for _, objKey := range typedValue.Keys() {
+			if engine.SanitizeKeyWithBrackets(objKey) != keyPath[0] {
+				continue
+			}
+			objValue, _ := typedValue.Get(objKey)
+			if len(keyPath) > 1 {
+				nestedMatched, err := overrideKeyCount(objValue, keyPath[1:], count)
+				if err != nil {
+					return 0, err
+				}
+				matched += nestedMatched
+				continue
+			}
+			dimensions, err := engine.ExtractDimensionsInBrackets(objKey)
+			if err != nil {
+				return 0, err
+			}
+			if len(dimensions) == 0 {
+				return 0, fmt.Errorf("'%s' has no count (its key must be '%s[N]')", objKey, keyPath[0])
+			}
+			if count == 1 && len(dimensions) == 1 {
+				return 0, fmt.Errorf("the count of '%s' must be greater than 1 (a key with '[1]' is a single object)", objKey)
+			}
+			dimensions[0] = count
+			typedValue.Rename(objKey, dimensionsKey(objKey, dimensions))
+			matched++
+		}
+		return matched, nil
+	case []any:
+		matched := 0
+		for _, item := range typedValue {
+			itemMatched, err := overrideKeyCount(item, keyPath, count)
+			if err != nil {
+				return 0, err
+			}
+			matched += itemMatched
+		}
+		return matched, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Rebuilds a key with other dimensions (e.g. "matrix[3][2]" with [6, 2] -> "matrix[6][2]").
+func dimensionsKey(objKey string, dimensions []int) string {
+	var key strings.Builder
+	key.WriteString(engine.SanitizeKeyWithBrackets(objKey))
+	for _, dimension := range dimensions {
+		fmt.Fprintf(&key, "[%d]", dimension)
+	}
+	return key.String()
+}
+
+// Scales the counts of the loaded templates (their root objects and keys), then applies the --count overrides.
+// Overrides are matched by the template name, or by the name followed by a key path, and are not scaled.
+func applyCountOverrides(jobs []*templateJob, overrides []*countOverride, scale float64) error {
+	for _, job := range jobs {
+		if job.err != nil || scale == 1 {
+			continue
+		}
+		job.generate = scaleCount(job.generate, scale, 1)
+		scaleTemplateKeys(job.template, scale)
+	}
+
+	for _, override := range overrides {
+		matched := false
+		for _, job := range jobs {
+			if override.target == job.name {
+				matched = true
+				job.generate = override.count
+				continue
+			}
+			keyPath, found := strings.CutPrefix(override.target, job.name+".")
+			if !found {
+				continue
+			}
+			matched = true
+			if job.err != nil {
+				continue
+			}
+			keyMatched, err := overrideKeyCount(job.template, strings.Split(keyPath, "."), override.count)
+			if err != nil {
+				return fmt.Errorf("invalid --count '%s' '%w'", override.raw, err)
+			}
+			if keyMatched == 0 {
+				return fmt.Errorf("invalid --count '%s' (key '%s' not found in template '%s')", override.raw, keyPath, job.name)
+			}
+		}
+		if !matched {
+			return fmt.Errorf("invalid --count '%s' (no template named '%s')", override.raw, strings.Split(override.target, ".")[0])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockCountTestSuite struct {
+	suite.Suite
+}
+
+func TestMockCountTestSuite(t *testing.T) {
+	suite.Run(t, new(MockCountTestSuite))
+}
+
+func (suite *MockCountTestSuite) TestParseCountOverride() {
+	override, err := parseCountOverride("company.employees=50")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), &countOverride{raw: "company.employees=50", target: "company.employees", count: 50}, override)
+
+	tests := []struct {
+		testName      string
+		input         string
+		expectedError string
+	}{
+		{testName: "without count", input: "company", expectedError: "invalid --count 'company' (must be '<template>=<count>' or '<template>.<key>=<count>')"},
+		{testName: "without target", input: "=5", expectedError: "invalid --count '=5' (must be '<template>=<count>' or '<template>.<key>=<count>')"},
+		{testName: "empty key", input: "company..employees=5", expectedError: "invalid --count 'company..employees=5' (must be '<template>=<count>' or '<template>.<key>=<count>')"},
+		{testName: "zero", input: "company=0", expectedError: "invalid --count 'company=0' (the count must be a whole number greater than 0)"},
+		{testName: "not a number", input: "company=many", expectedError: "invalid --count 'company=many' (the count must be a whole number greater than 0)"},
+	}
+	for _, test := range tests {
+		_, err := parseCountOverride(test.input)
+		assert.EqualError(suite.T(), err, test.expectedError, "Test case '%s' failed", test.testName)
+	}
+}
+
+func (suite *MockCountTestSuite) TestParseScale() {
+	tests := []struct {
+		testName string
+		input    string
+		expected float64
+	}{
+		{testName: "with x", input: "10x", expected: 10},
+		{testName: "without x", input: "3", expected: 3},
+		{testName: "fraction", input: "0.5x", expected: 0.5},
+	}
+	for _, test := range tests {
+		scale, err := parseScale(test.input)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", test.testName)
+		assert.Equal(suite.T(), test.expected, scale, "Test case '%s' failed", test.testName)
+	}
+
+	for _, input := range []string{"0x", "-2x", "x", "ten"} {
+		_, err := parseScale(input)
+		assert.EqualError(suite.T(), err, "invalid --scale '"+input+"' (must be a number greater than 0, e.g. '10x')", input)
+	}
+}
+
+func (suite *MockCountTestSuite) TestScaleTemplateKeys() {
+	tests := []struct {
+		testName string
+		input    string
+		scale    float64
+		expected string
+	}{
+		{
+			testName: "keys at any depth",
+			input:    `{"employees[5]":{"phones[2]":"x"},"tags[3]":"y","single[1]":"z","name":"w"}`,
+			scale:    10,
+			expected: `{"employees[50]":{"phones[20]":"x"},"tags[30]":"y","single[1]":"z","name":"w"}`,
+		},
+		{
+			testName: "outer count of many dimensions",
+			input:    `{"matrix[3][2]":"x"}`,
+			scale:    2,
+			expected: `{"matrix[6][2]":"x"}`,
+		},
+		{
+			testName: "generated keys",
+			input:    `{"{{ UUID.uuidv4 }}[3]":"x","{{ Lorem.word }}":"y"}`,
+			scale:    2,
+			expected: `{"{{ UUID.uuidv4 }}[6]":"x","{{ Lorem.word }}":"y"}`,
+		},
+		{
+			testName: "arrays keep being arrays",
+			input:    `[{"tags[4]":"x"},{"tags[40]":"y"}]`,
+			scale:    0.1,
+			expected: `[{"tags[2]":"x"},{"tags[4]":"y"}]`,
+		},
+	}
+	for _, test := range tests {
+		template, err := engine.UnmarshalTemplate([]byte(test.input))
+		suite.Require().NoError(err, "Test case '%s' failed", test.testName)
+		scaleTemplateKeys(template, test.scale)
+		templateJSON, err := json.Marshal(template)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", test.testName)
+		assert.Equal(suite.T(), test.expected, string(templateJSON), "Test case '%s' failed", test.testName)
+	}
+}
+
+func (suite *MockCountTestSuite) TestApplyCountOverrides() {
+	newJob := func(name string, generate int, template string) *templateJob {
+		parsed, err := engine.UnmarshalTemplate([]byte(template))
+		suite.Require().NoError(err)
+		return &templateJob{name: name, generate: generate, template: parsed}
+	}
+	company := newJob("company", 10, `{"departments[2]":{"employees[5]":"x"},"boards[2]":[{"members[3]":"y"}]}`)
+	employee := newJob("employee", 20, `{"name":"x"}`)
+	overrides := []*countOverride{
+		{raw: "company.departments.employees=7", target: "company.departments.employees", count: 7},
+		{raw: "company.boards.members=2", target: "company.boards.members", count: 2},
+		{raw: "employee=3", target: "employee", count: 3},
+	}
+	assert.NoError(suite.T(), applyCountOverrides([]*templateJob{company, employee}, overrides, 2))
+	assert.Equal(suite.T(), 20, company.generate, "template counts are scaled")
+	assert.Equal(suite.T(), 3, employee.generate, "overrides are not scaled")
+	companyJSON, err := json.Marshal(company.template)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), `{"departments[4]":{"employees[7]":"x"},"boards[4]":[{"members[2]":"y"}]}`, string(companyJSON))
+
+	tests := []struct {
+		testName      string
+		override      string
+		expectedError string
+	}{
+		{testName: "unknown template", override: "building=2", expectedError: "invalid --count 'building=2' (no template named 'building')"},
+		{testName: "unknown key", override: "company.floors=2", expectedError: "invalid --count 'company.floors=2' (key 'floors' not found in template 'company')"},
+		{testName: "key without count", override: "employee.name=2", expectedError: "invalid --count 'employee.name=2' ''name' has no count (its key must be 'name[N]')'"},
+		{testName: "single object", override: "company.departments=1", expectedError: "invalid --count 'company.departments=1' 'the count of 'departments[4]' must be greater than 1 (a key with '[1]' is a single object)'"},
+	}
+	for _, test := range tests {
+		override, err := parseCountOverride(test.override)
+		suite.Require().NoError(err, "Test case '%s' failed", test.testName)
+		err = applyCountOverrides([]*templateJob{company, employee}, []*countOverride{override}, 1)
+		assert.EqualError(suite.T(), err, test.expectedError, "Test case '%s' failed", test.testName)
+	}
+}
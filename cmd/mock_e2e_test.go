@@ -2,7 +2,14 @@ package cmd_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/lfsc09/k-test-n-stress/cmd"
 	"github.com/stretchr/testify/assert"
@@ -61,11 +68,15 @@ func (suite *MockCmdE2ETestSuite) TestCLIShouldRaiseError_MultipleParseFlags() {
 			testName: "all three --parse-str, --parse-json and --parseFile",
 			input:    []string{"mock", "--parse-str", "Hello {{ Person.name }}", "--parse-json", "' {\"name\": \"{{ Person.name }}\"} '", "--parse-files", "test.json"},
 		},
+		{
+			testName: "both --parse-json and --from-schema",
+			input:    []string{"mock", "--parse-json", "' {\"name\": \"{{ Person.name }}\"} '", "--from-schema", "schema.json"},
+		},
 	}
 	for _, test := range tests {
 		_, err := suite.executeCommand(test.input...)
 		assert.Error(suite.T(), err, test.testName)
-		assert.EqualError(suite.T(), err, "provide only one of the three options: --parse-json, --parse-files or --parse-str", test.testName)
+		assert.EqualError(suite.T(), err, "provide only one of the five options: --parse-json, --parse-files, --parse-str, --from-schema or --from-openapi", test.testName)
 	}
 }
 
@@ -114,7 +125,7 @@ func (suite *MockCmdE2ETestSuite) TestCLIShouldRaiseError_GenerateFlagInvalidUse
 	for _, test := range tests {
 		_, err := suite.executeCommand(test.input...)
 		assert.Error(suite.T(), err, test.testName)
-		assert.EqualError(suite.T(), err, "--generate option is only available when using --parse-json", test.testName)
+		assert.EqualError(suite.T(), err, "--generate option is only available when using --parse-json, --from-schema or --from-openapi", test.testName)
 	}
 }
 
@@ -183,3 +194,952 @@ func (suite *MockCmdE2ETestSuite) TestCLIShouldMockFromParseStr() {
 		assert.Contains(suite.T(), stdOut, test.expectedValue, test.testName)
 	}
 }
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldRaiseError_OutputFlagsInvalidUse() {
+	tests := []struct {
+		testName      string
+		input         []string
+		expectedError string
+	}{
+		{
+			testName:      "--out-file with --parse-str",
+			input:         []string{"mock", "--parse-str", "Hello {{ Person.name }}", "--out-file", "data.json"},
+			expectedError: "--out-file option is only available when using --parse-json, --from-schema or --from-openapi",
+		},
+		{
+			testName:      "--stdout with --parse-files",
+			input:         []string{"mock", "--parse-files", "test.json", "--stdout"},
+			expectedError: "--stdout option is only available when using --parse-json, --from-schema or --from-openapi",
+		},
+		{
+			testName:      "both --out-file and --stdout",
+			input:         []string{"mock", "--parse-json", "{\"name\": \"{{ Person.name }}\"}", "--out-file", "data.json", "--stdout"},
+			expectedError: "provide only one of the two options: --out-file or --stdout",
+		},
+		{
+			testName:      "--clean with --stdout",
+			input:         []string{"mock", "--parse-json", "{\"name\": \"{{ Person.name }}\"}", "--stdout", "--clean"},
+			expectedError: "--clean option is only available when writing to --out-dir",
+		},
+		{
+			testName:      "--clean with --parse-str",
+			input:         []string{"mock", "--parse-str", "Hello {{ Person.name }}", "--clean"},
+			expectedError: "--clean option is only available when writing to --out-dir",
+		},
+		{
+			testName:      "empty --out-dir",
+			input:         []string{"mock", "--parse-json", "{\"name\": \"{{ Person.name }}\"}", "--out-dir", ""},
+			expectedError: "--out-dir option must not be empty",
+		},
+		{
+			testName:      "--clean of the current directory",
+			input:         []string{"mock", "--parse-json", "{\"name\": \"{{ Person.name }}\"}", "--out-dir", ".", "--clean"},
+			expectedError: "failed to clean the output directory 'refusing to remove '.', it contains the current directory'",
+		},
+		{
+			testName:      "--clean of a parent directory",
+			input:         []string{"mock", "--parse-json", "{\"name\": \"{{ Person.name }}\"}", "--out-dir", "..", "--clean"},
+			expectedError: "failed to clean the output directory 'refusing to remove '..', it contains the current directory'",
+		},
+	}
+	for _, test := range tests {
+		_, err := suite.executeCommand(test.input...)
+		assert.Error(suite.T(), err, test.testName)
+		assert.EqualError(suite.T(), err, test.expectedError, test.testName)
+	}
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldMockFromParseJsonToStdout() {
+	testName := "Should print the mocked data from --parse-json"
+	stdOut, err := suite.executeCommand("mock", "--parse-json", "{\"name\": \"{{ Person.name }}\", \"raw\": \"value\"}", "--generate", "2", "--stdout")
+	assert.NoError(suite.T(), err, testName)
+
+	var records []map[string]any
+	assert.NoError(suite.T(), json.Unmarshal([]byte(stdOut), &records), testName)
+	assert.Len(suite.T(), records, 2, testName)
+	assert.Equal(suite.T(), "value", records[0]["raw"], testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldWriteToOutDirWithoutOverwriting() {
+	testName := "Should write to --out-dir, only overwriting with --force"
+	outDir := suite.T().TempDir()
+	input := []string{"mock", "--parse-json", "{\"name\": \"{{ Person.name }}\"}", "--out-dir", outDir}
+
+	_, err := suite.executeCommand(input...)
+	assert.NoError(suite.T(), err, testName)
+	assert.FileExists(suite.T(), filepath.Join(outDir, "mocked-data.json"), testName)
+
+	_, err = suite.executeCommand(input...)
+	assert.ErrorContains(suite.T(), err, "already exists (use --force to overwrite it)", testName)
+
+	_, err = suite.executeCommand(append(input, "--force")...)
+	assert.NoError(suite.T(), err, testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldWriteToOutFile() {
+	testName := "Should write the mocked data from --parse-json to --out-file"
+	outFile := filepath.Join(suite.T().TempDir(), "data.json")
+
+	_, err := suite.executeCommand("mock", "--parse-json", "{\"raw\": \"value\"}", "--out-file", outFile)
+	assert.NoError(suite.T(), err, testName)
+
+	content, err := os.ReadFile(outFile)
+	assert.NoError(suite.T(), err, testName)
+	assert.JSONEq(suite.T(), "{\"raw\": \"value\"}", string(content), testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldCleanOutDir() {
+	testName := "Should remove the --out-dir before writing with --clean"
+	templatesDir := suite.T().TempDir()
+	outDir := filepath.Join(suite.T().TempDir(), "fixtures")
+	assert.NoError(suite.T(), os.WriteFile(filepath.Join(templatesDir, "company[2].template.json"), []byte("{\"name\": \"{{ Company.name }}\"}"), 0644), testName)
+	assert.NoError(suite.T(), os.MkdirAll(outDir, 0755), testName)
+	assert.NoError(suite.T(), os.WriteFile(filepath.Join(outDir, "stale.json"), []byte("{}"), 0644), testName)
+
+	_, err := suite.executeCommand("mock", "--parse-files", templatesDir, "--out-dir", outDir, "--clean")
+	assert.NoError(suite.T(), err, testName)
+	assert.NoFileExists(suite.T(), filepath.Join(outDir, "stale.json"), testName)
+	assert.FileExists(suite.T(), filepath.Join(outDir, "company[2].json"), testName)
+}
+
+// Writes template files (name -> content) into a temporary directory, returning the directory.
+func (suite *MockCmdE2ETestSuite) writeTemplates(files map[string]string) string {
+	dir := suite.T().TempDir()
+	for name, content := range files {
+		suite.Require().NoError(os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldReportFailedTemplates() {
+	tests := []struct {
+		testName        string
+		files           map[string]string
+		extraArgs       []string
+		expectedError   string
+		expectedOutput  []string
+		expectedWritten []string
+	}{
+		{
+			testName: "Should continue after a template fails",
+			files: map[string]string{
+				"broken.template.json":     "{ \"name\": ",
+				"company[2].template.json": "{ \"name\": \"{{ Company.name }}\" }",
+			},
+			expectedError:   "1 of 2 templates failed",
+			expectedOutput:  []string{"broken.template.json: failed to parse JSON"},
+			expectedWritten: []string{"company[2].json"},
+		},
+		{
+			testName: "Should fail templates referencing a failed template",
+			files: map[string]string{
+				"company[2].template.json":  "{ \"id\": \"{{ Unknown.function }}\" }",
+				"employee[2].template.json": "{ \"companyId\": \"{{ Ref.pick:company.id }}\" }",
+			},
+			expectedError: "2 of 2 templates failed",
+			expectedOutput: []string{
+				"company[2].template.json: failed to process record 0 'unknown mock function 'Unknown.function''",
+				"employee[2].template.json: referenced template 'company' failed",
+			},
+		},
+		{
+			testName: "Should only fail the templates with an unknown reference and their dependents",
+			files: map[string]string{
+				"company[2].template.json":  "{ \"name\": \"{{ Company.name }}\" }",
+				"employee[2].template.json": "{ \"id\": \"{{ UUID.uuidv4 }}\", \"buildingId\": \"{{ Ref.pick:building.id }}\" }",
+				"task[2].template.json":     "{ \"employeeId\": \"{{ Ref.pick:employee.id }}\" }",
+			},
+			expectedError: "2 of 3 templates failed",
+			expectedOutput: []string{
+				"employee[2].template.json: template 'employee' references unknown template 'building'",
+				"task[2].template.json: referenced template 'employee' failed",
+			},
+			expectedWritten: []string{"company[2].json"},
+		},
+		{
+			testName: "Should skip the remaining templates with --fail-fast",
+			files: map[string]string{
+				"broken.template.json":     "{ \"name\": ",
+				"company[2].template.json": "{ \"name\": \"{{ Company.name }}\" }",
+			},
+			extraArgs:      []string{"--fail-fast"},
+			expectedError:  "1 of 2 templates failed (1 skipped by --fail-fast)",
+			expectedOutput: []string{"company[2].template.json: skipped after another template failed (--fail-fast)"},
+		},
+	}
+	for _, test := range tests {
+		templatesDir := suite.writeTemplates(test.files)
+		outDir := suite.T().TempDir()
+		stdOut, err := suite.executeCommand(append([]string{"mock", "--parse-files", templatesDir, "--out-dir", outDir}, test.extraArgs...)...)
+		assert.EqualError(suite.T(), err, test.expectedError, test.testName)
+		for _, expectedOutput := range test.expectedOutput {
+			assert.Contains(suite.T(), stdOut, expectedOutput, test.testName)
+		}
+		for _, written := range test.expectedWritten {
+			assert.FileExists(suite.T(), filepath.Join(outDir, written), test.testName)
+		}
+	}
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldRaiseError_FailFastFlagInvalidUse() {
+	testName := "Should raise error when --fail-fast is used without --parse-files"
+	_, err := suite.executeCommand("mock", "--parse-json", "{\"name\": \"{{ Person.name }}\"}", "--fail-fast")
+	assert.EqualError(suite.T(), err, "--fail-fast option is only available when using --parse-files", testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldRaiseError_ParallelismFlagInvalidValues() {
+	testName := "Should raise error when --parallelism is not greater than 0"
+	_, err := suite.executeCommand("mock", "--parse-json", "{\"name\": \"{{ Person.name }}\"}", "--parallelism", "0")
+	assert.EqualError(suite.T(), err, "--parallelism option must be greater than 0", testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldGenerateTheSameDataWithTheSameSeed() {
+	testName := "Should generate the same data with the same --seed, whatever the --parallelism"
+	templatesDir := suite.writeTemplates(map[string]string{
+		"company[150].template.json":  "{ \"id\": \"{{ UUID.uuidv4 }}\", \"name\": \"{{ Company.name }}\" }",
+		"employee[300].template.json": "{ \"companyId\": \"{{ Ref.pick:company.id:each }}\", \"name\": \"{{ Person.name }}\" }",
+	})
+
+	generated := make([]map[string]string, 0, 2)
+	for _, parallelism := range []string{"1", "8"} {
+		outDir := suite.T().TempDir()
+		_, err := suite.executeCommand("mock", "--parse-files", templatesDir, "--out-dir", outDir, "--seed", "42", "--parallelism", parallelism)
+		assert.NoError(suite.T(), err, testName)
+		files := make(map[string]string)
+		for _, name := range []string{"company[150].json", "employee[300].json"} {
+			content, err := os.ReadFile(filepath.Join(outDir, name))
+			assert.NoError(suite.T(), err, testName)
+			files[name] = string(content)
+		}
+		generated = append(generated, files)
+	}
+	assert.Equal(suite.T(), generated[0], generated[1], testName)
+
+	first, err := suite.executeCommand("mock", "--parse-json", "{\"id\": \"{{ UUID.uuidv4 }}\"}", "--generate", "5", "--seed", "42", "--stdout")
+	assert.NoError(suite.T(), err, testName)
+	second, err := suite.executeCommand("mock", "--parse-json", "{\"id\": \"{{ UUID.uuidv4 }}\"}", "--generate", "5", "--seed", "42", "--stdout", "--parallelism", "3")
+	assert.NoError(suite.T(), err, testName)
+	assert.Equal(suite.T(), first, second, testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldRaiseError_FormatFlagInvalidValues() {
+	testName := "Should raise error when --format is not a known format"
+	_, err := suite.executeCommand("mock", "--parse-json", "{\"name\": \"{{ Person.name }}\"}", "--format", "parquet")
+	assert.EqualError(suite.T(), err, "invalid --format 'parquet' (must be one of 'json', 'ndjson', 'csv', 'tsv', 'sql', 'yaml', 'xml', 'toml')", testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldWriteNdjson() {
+	testName := "Should write a record per line with --format ndjson"
+	stdOut, err := suite.executeCommand("mock", "--parse-json", "{\"raw\": \"value\"}", "--generate", "3", "--format", "ndjson", "--stdout")
+	assert.NoError(suite.T(), err, testName)
+	assert.Equal(suite.T(), "{\"raw\":\"value\"}\n{\"raw\":\"value\"}\n{\"raw\":\"value\"}\n", stdOut, testName)
+
+	templatesDir := suite.writeTemplates(map[string]string{
+		"company[250].template.json": "{ \"id\": \"{{ UUID.uuidv4 }}\" }",
+		"employee[2].template.json":  "{ \"companyId\": \"{{ Ref.pick:company.id }}\" }",
+	})
+	outDir := suite.T().TempDir()
+	_, err = suite.executeCommand("mock", "--parse-files", templatesDir, "--out-dir", outDir, "--format", "ndjson")
+	assert.NoError(suite.T(), err, testName)
+	content, err := os.ReadFile(filepath.Join(outDir, "company[250].ndjson"))
+	assert.NoError(suite.T(), err, testName)
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	assert.Len(suite.T(), lines, 250, testName)
+	assert.FileExists(suite.T(), filepath.Join(outDir, "employee[2].ndjson"), testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldNotLeavePartialFiles() {
+	testName := "Should remove the output file of a failed template"
+	templatesDir := suite.writeTemplates(map[string]string{
+		"company[300].template.json": "{ \"value\": { \"$if\": \"{{ Boolean.booleanWithChance:100 }}\", \"$then\": \"{{ Unknown.function }}\" } }",
+	})
+	outDir := suite.T().TempDir()
+	_, err := suite.executeCommand("mock", "--parse-files", templatesDir, "--out-dir", outDir, "--seed", "1")
+	assert.EqualError(suite.T(), err, "1 of 1 templates failed", testName)
+	assert.NoFileExists(suite.T(), filepath.Join(outDir, "company[300].json"), testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldRaiseError_CsvFlagsInvalidUse() {
+	tests := []struct {
+		testName      string
+		input         []string
+		expectedError string
+	}{
+		{
+			testName:      "Should raise error when --csv-arrays is used without --format csv or tsv",
+			input:         []string{"--csv-arrays", "index"},
+			expectedError: "--csv-arrays option is only available when using --format csv or tsv (or --parse-files)",
+		},
+		{
+			testName:      "Should raise error when --csv-delimiter is not a single character",
+			input:         []string{"--format", "csv", "--csv-delimiter", ";;"},
+			expectedError: "--csv-delimiter option must be a single character (other than a quote or a line break)",
+		},
+		{
+			testName:      "Should raise error when --csv-arrays is not a known mode",
+			input:         []string{"--format", "tsv", "--csv-arrays", "flatten"},
+			expectedError: "invalid --csv-arrays 'flatten' (must be one of 'join', 'index', 'explode')",
+		},
+		{
+			testName:      "Should raise error when --csv-quote is not a known mode",
+			input:         []string{"--format", "csv", "--csv-quote", "none"},
+			expectedError: "invalid --csv-quote 'none' (must be one of 'minimal', 'all')",
+		},
+	}
+	for _, test := range tests {
+		_, err := suite.executeCommand(append([]string{"mock", "--parse-json", "{\"name\": \"{{ Person.name }}\"}"}, test.input...)...)
+		assert.EqualError(suite.T(), err, test.expectedError, test.testName)
+	}
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldWriteCsv() {
+	testName := "Should write a row per record with --format csv"
+	templatesDir := suite.writeTemplates(map[string]string{
+		"company[3].template.json": "{ \"name\": \"raw\", \"address\": { \"city\": \"{{ Address.city }}\" } }",
+	})
+	outDir := suite.T().TempDir()
+	_, err := suite.executeCommand("mock", "--parse-files", templatesDir, "--out-dir", outDir, "--format", "csv")
+	assert.NoError(suite.T(), err, testName)
+	content, err := os.ReadFile(filepath.Join(outDir, "company[3].csv"))
+	assert.NoError(suite.T(), err, testName)
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	assert.Len(suite.T(), lines, 4, testName)
+	assert.Equal(suite.T(), "name,address.city", lines[0], testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldRaiseError_SqlFlagsInvalidUse() {
+	tests := []struct {
+		testName      string
+		input         []string
+		expectedError string
+	}{
+		{
+			testName:      "Should raise error when --table is used without --format sql",
+			input:         []string{"--table", "company"},
+			expectedError: "--table option is only available when using --format sql (or --parse-files)",
+		},
+		{
+			testName:      "Should raise error when --dialect is not a known dialect",
+			input:         []string{"--format", "sql", "--dialect", "oracle"},
+			expectedError: "invalid --dialect 'oracle' (must be one of 'postgres', 'mysql', 'sqlite', 'mssql')",
+		},
+		{
+			testName:      "Should raise error when --sql-batch-size is not greater than 0",
+			input:         []string{"--format", "sql", "--sql-batch-size", "0"},
+			expectedError: "--sql-batch-size option must be greater than 0",
+		},
+		{
+			testName:      "Should raise error when --sql-batch-size is too big for mssql",
+			input:         []string{"--format", "sql", "--dialect", "mssql", "--sql-batch-size", "1001"},
+			expectedError: "--sql-batch-size option must be at most 1000 for the mssql dialect",
+		},
+	}
+	for _, test := range tests {
+		_, err := suite.executeCommand(append([]string{"mock", "--parse-json", "{\"name\": \"{{ Person.name }}\"}"}, test.input...)...)
+		assert.EqualError(suite.T(), err, test.expectedError, test.testName)
+	}
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldWriteSql() {
+	testName := "Should write INSERT statements into the table of each template with --format sql"
+	templatesDir := suite.writeTemplates(map[string]string{
+		"company[3].template.json": "{ \"name\": \"raw\" }",
+	})
+	outDir := suite.T().TempDir()
+	_, err := suite.executeCommand("mock", "--parse-files", templatesDir, "--out-dir", outDir, "--format", "sql", "--sql-transaction")
+	assert.NoError(suite.T(), err, testName)
+	content, err := os.ReadFile(filepath.Join(outDir, "company[3].sql"))
+	assert.NoError(suite.T(), err, testName)
+	assert.Equal(suite.T(), "BEGIN;\nINSERT INTO \"company\" (\"name\") VALUES\n  ('raw'),\n  ('raw'),\n  ('raw');\nCOMMIT;\n", string(content), testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldWriteTheFormatOfTheTemplateName() {
+	testName := "Should write each template in the format of its name, or in --format"
+	templatesDir := suite.writeTemplates(map[string]string{
+		"company[2].yaml.template.json": "{ \"id\": \"{{ UUID.uuidv4 }}\" }",
+		"employee[2].template.json":     "{ \"companyId\": \"{{ Ref.pick:company.id }}\" }",
+		"settings.toml.template.json":   "{ \"name\": \"raw\" }",
+	})
+	outDir := suite.T().TempDir()
+	_, err := suite.executeCommand("mock", "--parse-files", templatesDir, "--out-dir", outDir, "--format", "xml", "--xml-root", "employees")
+	assert.NoError(suite.T(), err, testName)
+	assert.FileExists(suite.T(), filepath.Join(outDir, "company[2].yaml"), testName)
+	content, err := os.ReadFile(filepath.Join(outDir, "employee[2].xml"))
+	assert.NoError(suite.T(), err, testName)
+	assert.Contains(suite.T(), string(content), "<employees>", testName)
+	content, err = os.ReadFile(filepath.Join(outDir, "settings.toml"))
+	assert.NoError(suite.T(), err, testName)
+	assert.Equal(suite.T(), "name = \"raw\"\n", string(content), testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldUseTheSettingsOfTemplateMeta() {
+	testName := "Should generate each template with the count, output, format and seed of its \"$meta\""
+	templatesDir := suite.writeTemplates(map[string]string{
+		"employees[5].template.json": `{ "$meta": { "count": 3, "output": "staff", "format": "ndjson", "seed": 7 }, "name": "{{ Person.name }}" }`,
+		"company.yaml.template.yaml": "$meta:\n  count: 2\n  locale: en_US\nid: \"{{ UUID.uuidv4 }}\"\n",
+	})
+	outDir := suite.T().TempDir()
+	_, err := suite.executeCommand("mock", "--parse-files", templatesDir, "--out-dir", outDir, "--seed", "1")
+	assert.NoError(suite.T(), err, testName)
+	staff, err := os.ReadFile(filepath.Join(outDir, "staff.ndjson"))
+	assert.NoError(suite.T(), err, testName)
+	assert.Len(suite.T(), strings.Split(strings.TrimSpace(string(staff)), "\n"), 3, testName)
+	assert.NotContains(suite.T(), string(staff), "$meta", testName)
+	company, err := os.ReadFile(filepath.Join(outDir, "company.yaml"))
+	assert.NoError(suite.T(), err, testName)
+	assert.Equal(suite.T(), 2, strings.Count(string(company), "id: "), testName)
+
+	// the seed of "$meta" doesn't depend on --seed
+	otherDir := suite.T().TempDir()
+	_, err = suite.executeCommand("mock", "--parse-files", templatesDir, "--out-dir", otherDir, "--seed", "2")
+	assert.NoError(suite.T(), err, testName)
+	otherStaff, err := os.ReadFile(filepath.Join(otherDir, "staff.ndjson"))
+	assert.NoError(suite.T(), err, testName)
+	assert.Equal(suite.T(), string(staff), string(otherStaff), testName)
+
+	// with --parse-json, the flags take the place of "$meta"
+	stdOut, err := suite.executeCommand("mock", "--parse-json", `{ "$meta": { "count": 4, "format": "ndjson" }, "name": "{{ Person.name }}" }`, "--generate", "2", "--stdout")
+	assert.NoError(suite.T(), err, testName)
+	assert.Len(suite.T(), strings.Split(strings.TrimSpace(stdOut), "\n"), 2, testName)
+
+	_, err = suite.executeCommand("mock", "--parse-json", `{ "$meta": { "count": 0 } }`, "--stdout")
+	assert.EqualError(suite.T(), err, "failed to read the settings of --parse-json 'invalid '$meta' count '0' (must be a whole number greater than 0)'", testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldRaiseError_CountFlagsInvalidUse() {
+	tests := []struct {
+		testName      string
+		input         []string
+		expectedError string
+	}{
+		{
+			testName:      "--count without --parse-files",
+			input:         []string{"mock", "--parse-json", "{}", "--count", "company=5"},
+			expectedError: "--count option is only available when using --parse-files",
+		},
+		{
+			testName:      "--scale with --parse-str",
+			input:         []string{"mock", "--parse-str", "{{ Person.name }}", "--scale", "2x"},
+			expectedError: "--scale option is only available when using --parse-files or --parse-json",
+		},
+		{
+			testName:      "invalid --scale",
+			input:         []string{"mock", "--parse-json", "{}", "--scale", "0x"},
+			expectedError: "invalid --scale '0x' (must be a number greater than 0, e.g. '10x')",
+		},
+		{
+			testName:      "invalid --count",
+			input:         []string{"mock", "--parse-files", "templates", "--count", "company"},
+			expectedError: "invalid --count 'company' (must be '<template>=<count>' or '<template>.<key>=<count>')",
+		},
+	}
+	for _, test := range tests {
+		_, err := suite.executeCommand(test.input...)
+		assert.EqualError(suite.T(), err, test.expectedError, test.testName)
+	}
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldOverrideCountsFromTheCommandLine() {
+	testName := "Should scale the counts of the templates, and override them with --count"
+	templatesDir := suite.writeTemplates(map[string]string{
+		"company[2].template.json":  `{ "name": "{{ Company.name }}", "employees[3]": { "name": "{{ Person.name }}" } }`,
+		"building[1].template.json": `{ "floors[2]": "{{ Number.number::1:10 }}" }`,
+	})
+	outDir := suite.T().TempDir()
+	_, err := suite.executeCommand("mock", "--parse-files", templatesDir, "--out-dir", outDir, "--format", "ndjson", "--scale", "10x", "--count", "company=4", "--count", "company.employees=5")
+	assert.NoError(suite.T(), err, testName)
+
+	content, err := os.ReadFile(filepath.Join(outDir, "company[2].ndjson"))
+	assert.NoError(suite.T(), err, testName)
+	companies := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(suite.T(), companies, 4, testName)
+	var company map[string]any
+	assert.NoError(suite.T(), json.Unmarshal([]byte(companies[0]), &company), testName)
+	assert.Len(suite.T(), company["employees"], 5, testName)
+
+	content, err = os.ReadFile(filepath.Join(outDir, "building[1].ndjson"))
+	assert.NoError(suite.T(), err, testName)
+	buildings := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(suite.T(), buildings, 10, testName)
+	var building map[string]any
+	assert.NoError(suite.T(), json.Unmarshal([]byte(buildings[0]), &building), testName)
+	assert.Len(suite.T(), building["floors"], 20, testName)
+
+	_, err = suite.executeCommand("mock", "--parse-files", templatesDir, "--out-dir", suite.T().TempDir(), "--count", "company.floors=2")
+	assert.EqualError(suite.T(), err, "invalid --count 'company.floors=2' (key 'floors' not found in template 'company')", testName)
+
+	// with --parse-json, the count of "$meta" is scaled as well (but not --generate)
+	stdOut, err := suite.executeCommand("mock", "--parse-json", `{ "$meta": { "count": 3, "format": "ndjson" }, "tags[2]": "{{ Lorem.word }}" }`, "--scale", "2x", "--stdout")
+	assert.NoError(suite.T(), err, testName)
+	records := strings.Split(strings.TrimSpace(stdOut), "\n")
+	assert.Len(suite.T(), records, 6, testName)
+	var record map[string]any
+	assert.NoError(suite.T(), json.Unmarshal([]byte(records[0]), &record), testName)
+	assert.Len(suite.T(), record["tags"], 4, testName)
+
+	stdOut, err = suite.executeCommand("mock", "--parse-json", `{ "$meta": { "count": 3, "format": "ndjson" }, "name": "{{ Person.name }}" }`, "--scale", "2x", "--generate", "5", "--stdout")
+	assert.NoError(suite.T(), err, testName)
+	assert.Len(suite.T(), strings.Split(strings.TrimSpace(stdOut), "\n"), 5, testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldRaiseError_SizeFlagsInvalidUse() {
+	tests := []struct {
+		testName      string
+		input         []string
+		expectedError string
+	}{
+		{
+			testName:      "--target-size with --stdout",
+			input:         []string{"mock", "--parse-json", "{}", "--stdout", "--target-size", "1MB"},
+			expectedError: "--target-size option is only available when writing files of --parse-files, --parse-json, --from-schema or --from-openapi",
+		},
+		{
+			testName:      "--target-size with --generate",
+			input:         []string{"mock", "--parse-json", "{}", "--generate", "5", "--target-size", "1MB"},
+			expectedError: "--target-size and --generate options can't be used together",
+		},
+		{
+			testName:      "--record-size with --parse-str",
+			input:         []string{"mock", "--parse-str", "{{ Person.name }}", "--record-size", "1KB"},
+			expectedError: "--record-size option is only available when using --parse-files, --parse-json, --from-schema or --from-openapi",
+		},
+		{
+			testName:      "invalid --record-size",
+			input:         []string{"mock", "--parse-json", "{}", "--record-size", "8 kilobytes"},
+			expectedError: "invalid --record-size '8 kilobytes' (must be a size such as '8KB', '50MB' or '1GB')",
+		},
+	}
+	for _, test := range tests {
+		_, err := suite.executeCommand(test.input...)
+		assert.EqualError(suite.T(), err, test.expectedError, test.testName)
+	}
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldGenerateUntilTheTargetSize() {
+	testName := "Should generate as many records as fill --target-size, each padded to --record-size"
+	outFile := filepath.Join(suite.T().TempDir(), "payloads.ndjson")
+	out, err := suite.executeCommand("mock", "--parse-json", `{ "name": "{{ Person.name }}" }`, "--out-file", outFile, "--format", "ndjson", "--target-size", "64KB", "--record-size", "1KB", "--seed", "1")
+	assert.NoError(suite.T(), err, testName)
+
+	content, err := os.ReadFile(outFile)
+	assert.NoError(suite.T(), err, testName)
+	records := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(suite.T(), records, 64, testName)
+	// Each record is padded to 1KB with its new line
+	for _, record := range records {
+		assert.Len(suite.T(), record, 1023, testName)
+	}
+	assert.Contains(suite.T(), out, "Target size: [64.00 KB] ", testName)
+	assert.Contains(suite.T(), out, outFile+": [64.00 KB] (64 records)", testName)
+
+	templatesDir := suite.writeTemplates(map[string]string{
+		"company[2].template.json": `{ "name": "{{ Company.name }}", "employees[3]": { "name": "{{ Person.name }}" } }`,
+	})
+	outDir := suite.T().TempDir()
+	_, err = suite.executeCommand("mock", "--parse-files", templatesDir, "--out-dir", outDir, "--target-size", "200KB", "--seed", "1")
+	assert.NoError(suite.T(), err, testName)
+	info, err := os.Stat(filepath.Join(outDir, "company[2].json"))
+	assert.NoError(suite.T(), err, testName)
+	// The records are written until the file reaches the size, so it is only larger by (part of) the last record
+	assert.GreaterOrEqual(suite.T(), info.Size(), int64(200*1024), testName)
+	assert.Less(suite.T(), info.Size(), int64(200*1024+512), testName)
+
+	for _, format := range []string{"csv", "sql", "yaml"} {
+		outFile := filepath.Join(suite.T().TempDir(), "people."+format)
+		_, err = suite.executeCommand("mock", "--parse-json", `{ "name": "{{ Person.name }}", "bio": "{{ Lorem.sentence:8 }}" }`, "--out-file", outFile, "--format", format, "--target-size", "100KB", "--seed", "1")
+		assert.NoError(suite.T(), err, testName)
+		info, err := os.Stat(outFile)
+		assert.NoError(suite.T(), err, testName)
+		assert.GreaterOrEqual(suite.T(), info.Size(), int64(100*1024), "%s: %s", testName, format)
+		// sql also has the INSERT of each batch, which its rows only measure in part
+		assert.Less(suite.T(), info.Size(), int64(101*1024), "%s: %s", testName, format)
+	}
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldRaiseError_ShardFlagsInvalidUse() {
+	tests := []struct {
+		testName      string
+		input         []string
+		expectedError string
+	}{
+		{
+			testName:      "--shard-size with --stdout",
+			input:         []string{"mock", "--parse-json", "{}", "--stdout", "--shard-size", "10"},
+			expectedError: "--shard-size option is only available when writing files of --parse-files, --parse-json, --from-schema or --from-openapi",
+		},
+		{
+			testName:      "--shards with --target-size",
+			input:         []string{"mock", "--parse-json", "{}", "--target-size", "1MB", "--shards", "2"},
+			expectedError: "--target-size and --shards options can't be used together",
+		},
+		{
+			testName:      "--shard-size and --shards",
+			input:         []string{"mock", "--parse-json", "{}", "--shard-size", "10", "--shards", "2"},
+			expectedError: "--shard-size and --shards options can't be used together",
+		},
+		{
+			testName:      "--shards of zero",
+			input:         []string{"mock", "--parse-json", "{}", "--shards", "0"},
+			expectedError: "--shards option must be greater than 0",
+		},
+	}
+	for _, test := range tests {
+		_, err := suite.executeCommand(test.input...)
+		assert.EqualError(suite.T(), err, test.expectedError, test.testName)
+	}
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldSplitRecordsInShards() {
+	testName := "Should split the records of each template in numbered files, listed in a manifest"
+	templatesDir := suite.writeTemplates(map[string]string{
+		"employees[25].template.json": `{ "name": "{{ Person.name }}" }`,
+		"company[1].template.json":    `{ "name": "{{ Company.name }}" }`,
+	})
+	outDir := suite.T().TempDir()
+	_, err := suite.executeCommand("mock", "--parse-files", templatesDir, "--out-dir", outDir, "--format", "ndjson", "--shard-size", "10")
+	assert.NoError(suite.T(), err, testName)
+
+	content, err := os.ReadFile(filepath.Join(outDir, "employees.manifest.json"))
+	assert.NoError(suite.T(), err, testName)
+	var manifest struct {
+		Records int `json:"records"`
+		Shards  []struct {
+			File    string `json:"file"`
+			Records int    `json:"records"`
+			Sha256  string `json:"sha256"`
+		} `json:"shards"`
+	}
+	assert.NoError(suite.T(), json.Unmarshal(content, &manifest), testName)
+	assert.Equal(suite.T(), 25, manifest.Records, testName)
+	suite.Require().Len(manifest.Shards, 3, testName)
+	for idx, expected := range []struct {
+		file    string
+		records int
+	}{{"employees-0001.ndjson", 10}, {"employees-0002.ndjson", 10}, {"employees-0003.ndjson", 5}} {
+		assert.Equal(suite.T(), expected.file, manifest.Shards[idx].File, testName)
+		assert.Equal(suite.T(), expected.records, manifest.Shards[idx].Records, testName)
+		shardContent, err := os.ReadFile(filepath.Join(outDir, expected.file))
+		assert.NoError(suite.T(), err, testName)
+		assert.Len(suite.T(), strings.Split(strings.TrimSpace(string(shardContent)), "\n"), expected.records, testName)
+		assert.Len(suite.T(), manifest.Shards[idx].Sha256, 64, testName)
+	}
+	_, err = os.Stat(filepath.Join(outDir, "company-0001.ndjson"))
+	assert.NoError(suite.T(), err, testName)
+
+	outFile := filepath.Join(suite.T().TempDir(), "payloads.json")
+	_, err = suite.executeCommand("mock", "--parse-json", `{ "name": "{{ Person.name }}" }`, "--generate", "3", "--out-file", outFile, "--shards", "2")
+	assert.NoError(suite.T(), err, testName)
+	var records []map[string]any
+	content, err = os.ReadFile(strings.TrimSuffix(outFile, ".json") + "-0002.json")
+	assert.NoError(suite.T(), err, testName)
+	assert.NoError(suite.T(), json.Unmarshal(content, &records), testName)
+	assert.Len(suite.T(), records, 1, "the last shard is still an array")
+
+	outFile = filepath.Join(suite.T().TempDir(), "few.ndjson")
+	out, err := suite.executeCommand("mock", "--parse-json", `{ "name": "{{ Person.name }}" }`, "--generate", "2", "--out-file", outFile, "--format", "ndjson", "--shards", "4")
+	assert.NoError(suite.T(), err, testName)
+	assert.Contains(suite.T(), out, "Fewer shards than --shards 4 (not enough records):\n  - "+strings.TrimSuffix(outFile, ".ndjson")+".manifest.json: 2 shards (2 records)", testName)
+	_, err = os.Stat(strings.TrimSuffix(outFile, ".ndjson") + "-0003.ndjson")
+	assert.True(suite.T(), os.IsNotExist(err), "a shard is written for each record")
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldRaiseError_WatchFlagsInvalidUse() {
+	tests := []struct {
+		testName      string
+		input         []string
+		expectedError string
+	}{
+		{
+			testName:      "--watch without --parse-files",
+			input:         []string{"mock", "--parse-json", "{}", "--watch"},
+			expectedError: "--watch option is only available when using --parse-files",
+		},
+		{
+			testName:      "--watch-interval without --watch",
+			input:         []string{"mock", "--parse-files", "templates", "--watch-interval", "2s"},
+			expectedError: "--watch-interval option is only available when using --watch",
+		},
+		{
+			testName:      "--watch-interval of zero",
+			input:         []string{"mock", "--parse-files", "templates", "--watch", "--watch-interval", "0s"},
+			expectedError: "--watch-interval option must be greater than 0",
+		},
+	}
+	for _, test := range tests {
+		_, err := suite.executeCommand(test.input...)
+		assert.EqualError(suite.T(), err, test.expectedError, test.testName)
+	}
+}
+
+// Output of a command that is read while the command still runs.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldRegenerateChangedTemplatesWhenWatching() {
+	testName := "Should generate again only the changed templates and the ones referencing them, reporting errors without exiting"
+	templatesDir := suite.writeTemplates(map[string]string{
+		"company[2].template.json":  `{ "id": "{{ UUID.uuidv4 }}" }`,
+		"employee[3].template.json": `{ "companyId": "{{ Ref.pick:company.id }}" }`,
+		"building[1].template.json": `{ "name": "{{ Company.name }}" }`,
+	})
+	outDir := suite.T().TempDir()
+	out := &syncBuffer{}
+	rootCmd := cmd.NewRootCmd(&cmd.CommandOptions{Out: out})
+	rootCmd.SetArgs([]string{"mock", "--parse-files", templatesDir, "--out-dir", outDir, "--watch", "--watch-interval", "20ms", "--seed", "1"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	finished := make(chan error, 1)
+	go func() {
+		finished <- rootCmd.ExecuteContext(ctx)
+	}()
+
+	outputExists := func(name string) func() bool {
+		return func() bool {
+			_, err := os.Stat(filepath.Join(outDir, name))
+			return err == nil
+		}
+	}
+	suite.Require().Eventually(func() bool { return strings.Contains(out.String(), "Watching") }, 5*time.Second, 10*time.Millisecond, testName)
+	for _, name := range []string{"company[2].json", "employee[3].json", "building[1].json"} {
+		suite.Require().NoError(os.Remove(filepath.Join(outDir, name)), testName)
+	}
+
+	// The company and its employees are generated again, the building is not
+	suite.Require().NoError(os.WriteFile(filepath.Join(templatesDir, "company[2].template.json"), []byte(`{ "id": "{{ UUID.uuidv4 }}", "kind": "raw" }`), 0644))
+	assert.Eventually(suite.T(), outputExists("employee[3].json"), 5*time.Second, 10*time.Millisecond, testName)
+	content, err := os.ReadFile(filepath.Join(outDir, "company[2].json"))
+	assert.NoError(suite.T(), err, testName)
+	assert.Contains(suite.T(), string(content), `"kind": "raw"`, testName)
+	assert.False(suite.T(), outputExists("building[1].json")(), testName)
+
+	// Errors are reported, and fixing the template generates it again
+	suite.Require().NoError(os.WriteFile(filepath.Join(templatesDir, "building[1].template.json"), []byte(`{ "name": `), 0644))
+	suite.Require().Eventually(func() bool { return strings.Contains(out.String(), "Failed templates") }, 5*time.Second, 10*time.Millisecond, testName)
+	assert.Contains(suite.T(), out.String(), "1 of 3 templates failed", testName)
+	suite.Require().NoError(os.WriteFile(filepath.Join(templatesDir, "building[1].template.json"), []byte(`{ "name": "{{ Company.name }}" }`), 0644))
+	assert.Eventually(suite.T(), outputExists("building[1].json"), 5*time.Second, 10*time.Millisecond, testName)
+
+	cancel()
+	select {
+	case err := <-finished:
+		assert.NoError(suite.T(), err, testName)
+	case <-time.After(5 * time.Second):
+		suite.Fail("the watch didn't stop", testName)
+	}
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldRaiseError_XmlFlagsInvalidUse() {
+	tests := []struct {
+		testName      string
+		input         []string
+		expectedError string
+	}{
+		{
+			testName:      "Should raise error when --xml-root is used without --format xml",
+			input:         []string{"--xml-root", "companies"},
+			expectedError: "--xml-root option is only available when using --format xml (or --parse-files)",
+		},
+		{
+			testName:      "Should raise error when --xml-item is not a valid element name",
+			input:         []string{"--format", "xml", "--xml-item", "1 company"},
+			expectedError: "--xml-root and --xml-item options must be valid XML element names",
+		},
+	}
+	for _, test := range tests {
+		_, err := suite.executeCommand(append([]string{"mock", "--parse-json", "{\"name\": \"{{ Person.name }}\"}"}, test.input...)...)
+		assert.EqualError(suite.T(), err, test.expectedError, test.testName)
+	}
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldMockFromYamlAndJson5Templates() {
+	testName := "Should generate from .template.yaml, .template.json5 and .template.jsonc files"
+	templatesDir := suite.writeTemplates(map[string]string{
+		"company[2].template.yaml":   "# The companies\nid: \"{{ UUID.uuidv4 }}\"\nname: raw\nperson:\n  $include: person.template.jsonc\n",
+		"person.template.jsonc":      "{\n  // Reused by other templates\n  \"kind\": \"person\",\n}",
+		"employee[3].template.json5": "{\n  // Each employee works for a company\n  companyId: '{{ Ref.pick:company.id }}',\n  role: 'dev',\n}",
+	})
+	outDir := suite.T().TempDir()
+	_, err := suite.executeCommand("mock", "--parse-files", templatesDir, "--out-dir", outDir)
+	assert.NoError(suite.T(), err, testName)
+
+	var companies []map[string]any
+	content, err := os.ReadFile(filepath.Join(outDir, "company[2].json"))
+	assert.NoError(suite.T(), err, testName)
+	assert.NoError(suite.T(), json.Unmarshal(content, &companies), testName)
+	assert.Len(suite.T(), companies, 2, testName)
+	assert.Equal(suite.T(), map[string]any{"kind": "person"}, companies[0]["person"], testName)
+
+	var employees []map[string]any
+	content, err = os.ReadFile(filepath.Join(outDir, "employee[3].json"))
+	assert.NoError(suite.T(), err, testName)
+	assert.NoError(suite.T(), json.Unmarshal(content, &employees), testName)
+	assert.Len(suite.T(), employees, 3, testName)
+	assert.Equal(suite.T(), "dev", employees[0]["role"], testName)
+	assert.FileExists(suite.T(), filepath.Join(outDir, "person.json"), testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldMockFromSchema() {
+	testName := "Should generate valid instances of the --from-schema JSON Schema"
+	schemasDir := suite.writeTemplates(map[string]string{
+		"user.schema.json": `{
+			"type": "object",
+			"required": ["id", "email", "age", "role", "address"],
+			"properties": {
+				"id": { "type": "string", "format": "uuid" },
+				"email": { "type": "string", "format": "email" },
+				"age": { "type": "integer", "minimum": 18, "maximum": 99 },
+				"role": { "enum": ["admin", "user"] },
+				"name": { "type": "string", "x-ktns": "{{ Person.name }}" },
+				"address": { "$ref": "address.schema.yaml" }
+			}
+		}`,
+		"address.schema.yaml": "type: object\nrequired: [zip]\nproperties:\n  zip:\n    type: string\n    pattern: '^[0-9]{5}$'\n",
+	})
+	stdOut, err := suite.executeCommand("mock", "--from-schema", filepath.Join(schemasDir, "user.schema.json"), "--generate", "5", "--stdout")
+	assert.NoError(suite.T(), err, testName)
+
+	var users []map[string]any
+	assert.NoError(suite.T(), json.Unmarshal([]byte(stdOut), &users), testName)
+	assert.Len(suite.T(), users, 5, testName)
+	for _, user := range users {
+		assert.Regexp(suite.T(), `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, user["id"], testName)
+		assert.Contains(suite.T(), user["email"], "@", testName)
+		assert.GreaterOrEqual(suite.T(), user["age"], 18.0, testName)
+		assert.LessOrEqual(suite.T(), user["age"], 99.0, testName)
+		assert.Contains(suite.T(), []any{"admin", "user"}, user["role"], testName)
+		assert.Regexp(suite.T(), `^[0-9]{5}$`, user["address"].(map[string]any)["zip"], testName)
+	}
+
+	_, err = suite.executeCommand("mock", "--from-schema", filepath.Join(schemasDir, "missing.schema.json"), "--stdout")
+	assert.ErrorContains(suite.T(), err, "failed to load the provided --from-schema 'failed to read schema", testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldRaiseError_OpenapiFlagsInvalidUse() {
+	tests := []struct {
+		testName      string
+		input         []string
+		expectedError string
+	}{
+		{
+			testName:      "--from-openapi without --operation",
+			input:         []string{"mock", "--from-openapi", "api.yaml"},
+			expectedError: "--operation option is required when using --from-openapi",
+		},
+		{
+			testName:      "--operation with --parse-json",
+			input:         []string{"mock", "--parse-json", "{\"name\": \"{{ Person.name }}\"}", "--operation", "createUser"},
+			expectedError: "--operation option is only available when using --from-openapi",
+		},
+		{
+			testName:      "--response-status with --from-schema",
+			input:         []string{"mock", "--from-schema", "user.schema.json", "--response-status", "200"},
+			expectedError: "--response-status option is only available when using --from-openapi",
+		},
+		{
+			testName:      "invalid --openapi-part",
+			input:         []string{"mock", "--from-openapi", "api.yaml", "--operation", "createUser", "--openapi-part", "headers"},
+			expectedError: "invalid --openapi-part 'headers' (must be one of 'all', 'request', 'query', 'response')",
+		},
+	}
+	for _, test := range tests {
+		_, err := suite.executeCommand(test.input...)
+		assert.EqualError(suite.T(), err, test.expectedError, test.testName)
+	}
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldMockFromOpenapi() {
+	testName := "Should generate request bodies of the --operation of the --from-openapi document"
+	documentDir := suite.writeTemplates(map[string]string{
+		"api.json": `{
+			"openapi": "3.1.0",
+			"paths": {
+				"/companies": {
+					"post": {
+						"operationId": "createCompany",
+						"requestBody": { "content": { "application/json": { "schema": { "$ref": "#/components/schemas/Company" } } } },
+						"responses": { "201": { "description": "Created" } }
+					}
+				}
+			},
+			"components": {
+				"schemas": {
+					"Company": {
+						"type": "object",
+						"required": ["name", "employees"],
+						"properties": {
+							"name": { "type": "string", "x-ktns": "{{ Company.name }}" },
+							"employees": { "type": "integer", "minimum": 1, "maximum": 500 }
+						}
+					}
+				}
+			}
+		}`,
+	})
+	stdOut, err := suite.executeCommand("mock", "--from-openapi", filepath.Join(documentDir, "api.json"), "--operation", "createCompany", "--openapi-part", "request", "--generate", "3", "--format", "ndjson", "--stdout")
+	assert.NoError(suite.T(), err, testName)
+
+	lines := strings.Split(strings.TrimSpace(stdOut), "\n")
+	assert.Len(suite.T(), lines, 3, testName)
+	for _, line := range lines {
+		var company map[string]any
+		assert.NoError(suite.T(), json.Unmarshal([]byte(line), &company), testName)
+		assert.NotEmpty(suite.T(), company["name"], testName)
+		assert.GreaterOrEqual(suite.T(), company["employees"], 1.0, testName)
+	}
+
+	stdOut, err = suite.executeCommand("mock", "--from-openapi", filepath.Join(documentDir, "api.json"), "--operation", "POST /companies", "--stdout")
+	assert.NoError(suite.T(), err, testName)
+	var operation map[string]any
+	assert.NoError(suite.T(), json.Unmarshal([]byte(stdOut), &operation), testName)
+	assert.Equal(suite.T(), "/companies", operation["path"], testName)
+	assert.Equal(suite.T(), map[string]any{"status": 201.0}, operation["response"], testName)
+}
+
+func (suite *MockCmdE2ETestSuite) TestCLIShouldInferTemplateFromSample() {
+	testName := "Should infer a template from a sample, which generates alike data"
+	samplesDir := suite.writeTemplates(map[string]string{
+		"users.json": `[
+			{ "id": "0b6a5c7e-8f3d-4e21-9a3b-2c1d0e9f8a7b", "email": "ada@example.com", "tags": ["admin", "dev"] },
+			{ "id": "5f0e4a1b-2c3d-4e5f-8a9b-0c1d2e3f4a5b", "email": "alan@example.com", "tags": ["ops"] }
+		]`,
+	})
+	stdOut, err := suite.executeCommand("mock", "infer", filepath.Join(samplesDir, "users.json"))
+	assert.NoError(suite.T(), err, testName)
+	templatePath := filepath.Join(samplesDir, "users[2].template.json")
+	assert.Contains(suite.T(), stdOut, templatePath, testName)
+
+	content, err := os.ReadFile(templatePath)
+	assert.NoError(suite.T(), err, testName)
+	assert.JSONEq(suite.T(), `{"id":"{{ UUID.uuidv4 }}","email":"{{ Internet.email }}","tags[2]":"{{ Lorem.word }}"}`, string(content), testName)
+
+	_, err = suite.executeCommand("mock", "infer", filepath.Join(samplesDir, "users.json"))
+	assert.EqualError(suite.T(), err, "'"+templatePath+"' already exists (use --force to overwrite it)", testName)
+
+	outDir := suite.T().TempDir()
+	_, err = suite.executeCommand("mock", "--parse-files", samplesDir, "--out-dir", outDir)
+	assert.NoError(suite.T(), err, testName)
+	var users []map[string]any
+	content, err = os.ReadFile(filepath.Join(outDir, "users[2].json"))
+	assert.NoError(suite.T(), err, testName)
+	assert.NoError(suite.T(), json.Unmarshal(content, &users), testName)
+	assert.Len(suite.T(), users, 2, testName)
+	assert.Len(suite.T(), users[0]["tags"], 2, testName)
+
+	stdOut, err = suite.executeCommand("mock", "infer", filepath.Join(samplesDir, "users.json"), "--stdout")
+	assert.NoError(suite.T(), err, testName)
+	assert.JSONEq(suite.T(), `{"id":"{{ UUID.uuidv4 }}","email":"{{ Internet.email }}","tags[2]":"{{ Lorem.word }}"}`, stdOut, testName)
+}
@@ -0,0 +1,406 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+	"github.com/spf13/cobra"
+)
+
+var (
+	inferEmailRegex  = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	inferUuidRegex   = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	inferCpfRegex    = regexp.MustCompile(`^\d{3}\.\d{3}\.\d{3}-\d{2}$`)
+	inferCnpjRegex   = regexp.MustCompile(`^\d{2}\.\d{3}\.\d{3}/\d{4}-\d{2}$`)
+	inferDigitsRegex = regexp.MustCompile(`^\d+$`)
+)
+
+// Kinds of the sample values, each one inferred as a mock function.
+const (
+	inferKindObject   = "object"
+	inferKindArray    = "array"
+	inferKindBoolean  = "boolean"
+	inferKindNumber   = "number"
+	inferKindEmail    = "email"
+	inferKindUuid     = "uuid"
+	inferKindUrl      = "url"
+	inferKindDateTime = "datetime"
+	inferKindDate     = "date"
+	inferKindTime     = "time"
+	inferKindCpf      = "cpf"
+	inferKindCnpj     = "cnpj"
+	inferKindIpv4     = "ipv4"
+	inferKindIpv6     = "ipv6"
+	inferKindDigits   = "digits"
+	inferKindText     = "text"
+)
+
+// Mock functions of the kinds of strings that don't depend on the sample values.
+var inferKindFunctions = map[string]string{
+	inferKindBoolean:  "{{ Boolean.boolean }}",
+	inferKindEmail:    "{{ Internet.email }}",
+	inferKindUuid:     "{{ UUID.uuidv4 }}",
+	inferKindUrl:      "{{ Internet.url }}",
+	inferKindDateTime: "{{ Time.date:datetime }}",
+	inferKindDate:     "{{ Time.date:date }}",
+	inferKindTime:     "{{ Time.date:time }}",
+	inferKindCpf:      "{{ Person.cpf }}",
+	inferKindCnpj:     "{{ Company.cnpj }}",
+	inferKindIpv4:     "{{ Internet.ipv4 }}",
+	inferKindIpv6:     "{{ Regex.regex:/[0-9a-f]{1,4}(:[0-9a-f]{1,4}){7}/ }}",
+}
+
+// Mock functions of texts with well-known keys (compared in lower case, without "_" and "-").
+var inferKeyFunctions = map[string]string{
+	"name":        "{{ Person.name }}",
+	"fullname":    "{{ Person.name }}",
+	"firstname":   "{{ Person.firstName }}",
+	"lastname":    "{{ Person.lastName }}",
+	"phone":       "{{ Person.phoneNumber }}",
+	"phonenumber": "{{ Person.phoneNumber }}",
+	"company":     "{{ Company.name }}",
+	"companyname": "{{ Company.name }}",
+	"jobtitle":    "{{ Company.jobTitle }}",
+	"street":      "{{ Address.streetName }}",
+	"city":        "{{ Address.city }}",
+	"state":       "{{ Address.state }}",
+	"country":     "{{ Address.country }}",
+}
+
+func newMockInferCmd(opts *CommandOptions) *cobra.Command {
+	inferCmd := &cobra.Command{
+		Use:   "infer <sample>",
+		Short: "Infer a template file from a sample payload",
+		Long: `Infer a template file from a sample payload (a JSON, YAML or JSON5 file), to be used with --parse-files.
+
+* Values are replaced by the mock functions that generate alike values: emails, UUIDs, URLs, ISO dates and times, CPFs, CNPJs, IPs, digits, numbers (within the range of the sample) and booleans.
+* Other texts are replaced by words or sentences, or by names, phones and addresses when their key says so (e.g. "firstName" or "city").
+* Arrays whose items are alike become "key[N]" entries, N being the amount of items in the sample. (Objects of an array are merged, so every key found is kept)
+* When the sample itself is an array of alike objects, the template is a single object and N goes to its name (e.g. "users[25].template.json").
+
+* The template is written next to the sample (e.g. "users.json" -> "users[25].template.json"), unless --out-file or --stdout is added.
+
+Examples:
+  ktns mock infer users.json
+  ktns mock infer payload.json --out-file "templates/payload.template.json" --force
+  ktns mock infer payload.json --stdout
+	`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			outFile, _ := cmd.Flags().GetString("out-file")
+			toStdout, _ := cmd.Flags().GetBool("stdout")
+			force, _ := cmd.Flags().GetBool("force")
+
+			if outFile != "" && toStdout {
+				return fmt.Errorf("provide only one of the two options: --out-file or --stdout")
+			}
+
+			samplePath := args[0]
+			content, err := os.ReadFile(samplePath)
+			if err != nil {
+				return fmt.Errorf("failed to read the sample '%w'", err)
+			}
+			sample, err := engine.UnmarshalTemplateFile(samplePath, content)
+			if err != nil {
+				return fmt.Errorf("failed to parse %s from the sample '%w'", engine.TemplateLanguage(samplePath), err)
+			}
+
+			template, count := inferTemplate(sample)
+			prettyJSON, err := json.MarshalIndent(template, "", "  ")
+			if err != nil {
+				return fmt.Errorf("error marshalling JSON '%w'", err)
+			}
+			prettyJSON = append(prettyJSON, '\n')
+
+			if toStdout {
+				_, err := opts.Out.Write(prettyJSON)
+				return err
+			}
+			if outFile == "" {
+				outFile = inferTemplateName(samplePath, count)
+			}
+			var outPath string
+			var mu sync.Mutex
+			createdDirs := make(map[string]bool, 1)
+			file, err := openOutputFile(&outputOptions{file: outFile, force: force}, samplePath, &outPath, &mu, &createdDirs)
+			if err != nil {
+				return fmt.Errorf("%w", err)
+			}
+			_, err = file.Write(prettyJSON)
+			if err = closeOutputFile(file, err); err != nil {
+				return fmt.Errorf("%w", err)
+			}
+			fmt.Fprintf(opts.Out, "Template written to '%s'\n", outPath)
+			return nil
+		},
+	}
+
+	inferCmd.Flags().String("out-file", "", "pass the file where the template will be written, defaults to a template next to the sample")
+	inferCmd.Flags().Bool("stdout", false, "if set, the template is printed instead of written to a file")
+	inferCmd.Flags().Bool("force", false, "if set, an existing template is overwritten")
+
+	return inferCmd
+}
+
+// Returns the name of the template of a sample, next to it (e.g. "data/users.json" -> "data/users[25].template.json").
+func inferTemplateName(samplePath string, count int) string {
+	name := engine.TrimTemplateExtension(samplePath)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if count > 0 {
+		name += "[" + strconv.Itoa(count) + "]"
+	}
+	return name + ".template.json"
+}
+
+// Infers the template of a sample. When the sample is an array of alike objects, the template is their object, and the
+// amount of items is returned (to be added to the template's name). A single object stays an array, as a count of 1
+// would generate the object alone.
+func inferTemplate(sample any) (any, int) {
+	array, ok := sample.([]any)
+	if !ok {
+		template, _ := inferValue([]any{sample}, "")
+		return template, 0
+	}
+	if _, isObject := inferFirst(array).(*engine.OrderedMap); isObject && len(array) > 1 && inferAlike(array) {
+		template, _ := inferValue(array, "")
+		return template, len(array)
+	}
+	return inferLiteralArray(array), 0
+}
+
+// Returns the first value of an array that is not null.
+func inferFirst(array []any) any {
+	for _, item := range array {
+		if item != nil {
+			return item
+		}
+	}
+	return nil
+}
+
+// Infers the template value of the values found at the same place of a sample (e.g. the "email" of every user).
+// Returns the dimensions of the array it repeats, when the values are arrays of alike items (e.g. "tags[3]").
+func inferValue(samples []any, key string) (any, []int) {
+	var values []any
+	for _, value := range samples {
+		if value != nil {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		// Nothing can be inferred from nulls, so they are kept
+		return nil, nil
+	}
+	kind := inferKind(values[0])
+	sameKind := values[:0:0]
+	for _, value := range values {
+		if inferKind(value) == kind {
+			sameKind = append(sameKind, value)
+		}
+	}
+	values = sameKind
+
+	switch kind {
+	case inferKindObject:
+		return inferObject(values), nil
+	case inferKindArray:
+		var items []any
+		size := 0
+		for _, value := range values {
+			items = append(items, value.([]any)...)
+			size = max(size, len(value.([]any)))
+		}
+		if size == 0 {
+			return []any{}, nil
+		}
+		// A count of 1 generates a single value instead of an array, so arrays of a single item are kept as arrays
+		if size == 1 || !inferAlike(items) {
+			return inferLiteralArray(values[0].([]any)), nil
+		}
+		item, dimensions := inferValue(items, key)
+		return item, append([]int{size}, dimensions...)
+	case inferKindNumber:
+		return inferNumber(values), nil
+	case inferKindDigits:
+		minLength, maxLength := math.MaxInt, 0
+		for _, value := range values {
+			minLength = min(minLength, len(value.(string)))
+			maxLength = max(maxLength, len(value.(string)))
+		}
+		if minLength == maxLength {
+			return fmt.Sprintf("{{ Regex.regex:/[0-9]{%d}/ }}", minLength), nil
+		}
+		return fmt.Sprintf("{{ Regex.regex:/[0-9]{%d,%d}/ }}", minLength, maxLength), nil
+	case inferKindText:
+		normalizedKey := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+		if function, ok := inferKeyFunctions[normalizedKey]; ok {
+			return function, nil
+		}
+		words := 0
+		for _, value := range values {
+			words = max(words, len(strings.Fields(value.(string))))
+		}
+		switch {
+		case words <= 1:
+			return "{{ Lorem.word }}", nil
+		case words <= 20:
+			return fmt.Sprintf("{{ Lorem.sentence:%d }}", words), nil
+		default:
+			return fmt.Sprintf("{{ Lorem.paragraph:%d }}", max(1, words/15)), nil
+		}
+	default:
+		return inferKindFunctions[kind], nil
+	}
+}
+
+// Infers an object from alike objects, keeping every key found (in the order they were found).
+func inferObject(values []any) *engine.OrderedMap {
+	template := engine.NewOrderedMap()
+	var keys []string
+	seen := make(map[string]bool)
+	for _, value := range values {
+		for _, objKey := range value.(*engine.OrderedMap).Keys() {
+			if !seen[objKey] {
+				seen[objKey] = true
+				keys = append(keys, objKey)
+			}
+		}
+	}
+	for _, objKey := range keys {
+		var samples []any
+		for _, value := range values {
+			if objValue, ok := value.(*engine.OrderedMap).Get(objKey); ok {
+				samples = append(samples, objValue)
+			}
+		}
+		objValue, dimensions := inferValue(samples, objKey)
+		for _, size := range dimensions {
+			objKey += "[" + strconv.Itoa(size) + "]"
+		}
+		template.Set(objKey, objValue)
+	}
+	return template
+}
+
+// Infers an array item by item, when its items are not alike.
+func inferLiteralArray(array []any) []any {
+	template := make([]any, len(array))
+	for idx, item := range array {
+		if itemArray, ok := item.([]any); ok {
+			template[idx] = inferLiteralArray(itemArray)
+			continue
+		}
+		template[idx], _ = inferValue([]any{item}, "")
+	}
+	return template
+}
+
+// Infers a number within the range of the sample values, with as many decimals as they have.
+func inferNumber(values []any) string {
+	minimum, maximum := math.Inf(1), math.Inf(-1)
+	decimals := 0
+	for _, value := range values {
+		number := value.(float64)
+		minimum = math.Min(minimum, number)
+		maximum = math.Max(maximum, number)
+		text := strconv.FormatFloat(number, 'f', -1, 64)
+		if _, fraction, ok := strings.Cut(text, "."); ok {
+			decimals = max(decimals, len(fraction))
+		}
+	}
+	// A single value gives no range, so numbers between 0 and its double are generated
+	if minimum == maximum {
+		minimum, maximum = math.Min(0, 2*minimum), math.Max(0, 2*maximum)
+		if minimum == maximum {
+			maximum = 10
+		}
+	}
+	minimum, maximum = math.Floor(minimum), math.Ceil(maximum)
+	decimalsParam := ""
+	if decimals > 0 {
+		decimalsParam = strconv.Itoa(decimals)
+	}
+	return fmt.Sprintf("{{ Number.number:%s:%s:%s }}", decimalsParam, strconv.FormatFloat(minimum, 'f', -1, 64), strconv.FormatFloat(maximum, 'f', -1, 64))
+}
+
+// Returns whether the items of an array are alike: all objects, all arrays, or all values of the same kind.
+func inferAlike(items []any) bool {
+	kind := ""
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		itemKind := inferKind(item)
+		if kind != "" && itemKind != kind {
+			return false
+		}
+		kind = itemKind
+	}
+	return true
+}
+
+// Returns the kind of a sample value.
+func inferKind(value any) string {
+	switch typedValue := value.(type) {
+	case *engine.OrderedMap:
+		return inferKindObject
+	case []any:
+		return inferKindArray
+	case bool:
+		return inferKindBoolean
+	case float64:
+		return inferKindNumber
+	case string:
+		return inferStringKind(typedValue)
+	default:
+		return inferKindText
+	}
+}
+
+// Detects what a string holds (e.g. an email or a date), or returns inferKindText.
+func inferStringKind(text string) string {
+	switch {
+	case inferUuidRegex.MatchString(text):
+		return inferKindUuid
+	case inferEmailRegex.MatchString(text):
+		return inferKindEmail
+	case inferCpfRegex.MatchString(text):
+		return inferKindCpf
+	case inferCnpjRegex.MatchString(text):
+		return inferKindCnpj
+	case inferDigitsRegex.MatchString(text):
+		return inferKindDigits
+	}
+	if ip := net.ParseIP(text); ip != nil {
+		if ip.To4() != nil {
+			return inferKindIpv4
+		}
+		return inferKindIpv6
+	}
+	if parsed, err := url.Parse(text); err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "" {
+		return inferKindUrl
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		if _, err := time.Parse(layout, text); err == nil {
+			return inferKindDateTime
+		}
+	}
+	if _, err := time.Parse(time.DateOnly, text); err == nil {
+		return inferKindDate
+	}
+	if _, err := time.Parse(time.TimeOnly, text); err == nil {
+		return inferKindTime
+	}
+	return inferKindText
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+	"github.com/lfsc09/k-test-n-stress/mocker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockInferTestSuite struct {
+	suite.Suite
+}
+
+func TestMockInferTestSuite(t *testing.T) {
+	suite.Run(t, new(MockInferTestSuite))
+}
+
+func (suite *MockInferTestSuite) TestInferStringKind() {
+	tests := []struct {
+		testName string
+		input    string
+		expected string
+	}{
+		{testName: "email", input: "ada@example.com", expected: inferKindEmail},
+		{testName: "uuid", input: "0b6a5c7e-8f3d-4e21-9a3b-2c1d0e9f8a7b", expected: inferKindUuid},
+		{testName: "url", input: "https://example.com/users?page=2", expected: inferKindUrl},
+		{testName: "date-time", input: "2024-03-01T10:20:30.123Z", expected: inferKindDateTime},
+		{testName: "date-time without zone", input: "2024-03-01 10:20:30", expected: inferKindDateTime},
+		{testName: "date", input: "2024-03-01", expected: inferKindDate},
+		{testName: "time", input: "10:20:30", expected: inferKindTime},
+		{testName: "cpf", input: "123.456.789-09", expected: inferKindCpf},
+		{testName: "cnpj", input: "12.345.678/0001-95", expected: inferKindCnpj},
+		{testName: "ipv4", input: "192.168.0.1", expected: inferKindIpv4},
+		{testName: "ipv6", input: "2001:db8::1", expected: inferKindIpv6},
+		{testName: "digits", input: "01310100", expected: inferKindDigits},
+		{testName: "text", input: "Hello world", expected: inferKindText},
+		{testName: "not an url", input: "example.com", expected: inferKindText},
+	}
+
+	for _, test := range tests {
+		assert.Equal(suite.T(), test.expected, inferStringKind(test.input), "Test case '%s' failed", test.testName)
+	}
+}
+
+func (suite *MockInferTestSuite) TestInferTemplate() {
+	tests := []struct {
+		testName      string
+		input         string
+		expectedJSON  string
+		expectedCount int
+	}{
+		{
+			testName:     "object with detected values",
+			input:        `{ "id": "0b6a5c7e-8f3d-4e21-9a3b-2c1d0e9f8a7b", "email": "ada@example.com", "active": true, "score": 7.25, "name": "Ada Lovelace", "bio": "Wrote the first program", "nothing": null }`,
+			expectedJSON: `{"id":"{{ UUID.uuidv4 }}","email":"{{ Internet.email }}","active":"{{ Boolean.boolean }}","score":"{{ Number.number:2:0:15 }}","name":"{{ Person.name }}","bio":"{{ Lorem.sentence:4 }}","nothing":null}`,
+		},
+		{
+			testName:     "alike arrays become key[N]",
+			input:        `{ "tags": ["a", "b", "c"], "matrix": [[1, 2], [3, 4], [5, 6]], "zips": ["01310100", "123"] }`,
+			expectedJSON: `{"tags[3]":"{{ Lorem.word }}","matrix[3][2]":"{{ Number.number::1:6 }}","zips[2]":"{{ Regex.regex:/[0-9]{3,8}/ }}"}`,
+		},
+		{
+			testName:     "arrays of objects are merged",
+			input:        `{ "users": [{ "email": "a@b.co" }, { "email": "c@d.co", "age": 30 }] }`,
+			expectedJSON: `{"users[2]":{"email":"{{ Internet.email }}","age":"{{ Number.number::0:60 }}"}}`,
+		},
+		{
+			testName:     "arrays with different items are kept",
+			input:        `{ "mixed": ["ada@example.com", 1, [true]], "empty": [] }`,
+			expectedJSON: `{"mixed":["{{ Internet.email }}","{{ Number.number::0:2 }}",["{{ Boolean.boolean }}"]],"empty":[]}`,
+		},
+		{
+			testName:     "arrays of a single item are kept",
+			input:        `{ "one": [{ "a": "x" }], "tags": ["a"], "matrix": [[1], [2]] }`,
+			expectedJSON: `{"one":[{"a":"{{ Lorem.word }}"}],"tags":["{{ Lorem.word }}"],"matrix[2]":["{{ Number.number::0:2 }}"]}`,
+		},
+		{
+			testName:     "root array of a single object",
+			input:        `[{ "ip": "10.0.0.1" }]`,
+			expectedJSON: `[{"ip":"{{ Internet.ipv4 }}"}]`,
+		},
+		{
+			testName:      "root array of objects",
+			input:         `[{ "ip": "10.0.0.1" }, { "ip": "10.0.0.2" }, null]`,
+			expectedJSON:  `{"ip":"{{ Internet.ipv4 }}"}`,
+			expectedCount: 3,
+		},
+		{
+			testName:     "root array of values",
+			input:        `["2024-03-01", "2024-03-02"]`,
+			expectedJSON: `["{{ Time.date:date }}","{{ Time.date:date }}"]`,
+		},
+	}
+
+	for _, test := range tests {
+		sample, err := engine.UnmarshalOrdered([]byte(test.input))
+		suite.Require().NoError(err, "Test case '%s' failed", test.testName)
+		template, count := inferTemplate(sample)
+		templateJSON, err := json.Marshal(template)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", test.testName)
+		assert.Equal(suite.T(), test.expectedJSON, string(templateJSON), "Test case '%s' failed", test.testName)
+		assert.Equal(suite.T(), test.expectedCount, count, "Test case '%s' failed", test.testName)
+
+		// The inferred templates are valid templates
+		_, err = engine.ProcessTemplate(template, mocker.NewWithSeed(1))
+		assert.NoError(suite.T(), err, "Test case '%s' failed", test.testName)
+	}
+}
+
+func (suite *MockInferTestSuite) TestInferTemplateName() {
+	assert.Equal(suite.T(), "data/users[25].template.json", inferTemplateName("data/users.json", 25))
+	assert.Equal(suite.T(), "payload.template.json", inferTemplateName("payload.yaml", 0))
+}
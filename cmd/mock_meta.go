@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+	"github.com/lfsc09/k-test-n-stress/mocker"
+)
+
+// Keys of the "$meta" section.
+const (
+	metaCount  = "count"
+	metaOutput = "output"
+	metaFormat = "format"
+	metaSeed   = "seed"
+	metaLocale = "locale"
+)
+
+func metaKeys() []string {
+	return []string{metaCount, metaOutput, metaFormat, metaSeed, metaLocale}
+}
+
+// Generation settings a template carries in its "$meta" section, e.g.:
+//
+//	{ "$meta": { "count": 50, "output": "staff", "format": "csv", "seed": 42, "locale": "en_US" }, "name": "{{ Person.name }}" }
+//
+// Settings left out are zero, so the ones of the template name and the flags are used instead.
+type templateMeta struct {
+	count  int
+	output string
+	format string
+	seed   *int64
+	locale string
+}
+
+// Extracts the "$meta" section of a parsed template (removing it from the template), checking its settings.
+// Templates without it (or whose root is an array) have no settings.
+func extractTemplateMeta(template any) (*templateMeta, error) {
+	meta := &templateMeta{}
+	rootMap, ok := template.(*engine.OrderedMap)
+	if !ok {
+		return meta, nil
+	}
+	rawMeta, ok := rootMap.Get(engine.MetaKey)
+	if !ok {
+		return meta, nil
+	}
+	rootMap.Remove(engine.MetaKey)
+	metaMap, ok := rawMeta.(*engine.OrderedMap)
+	if !ok {
+		return nil, fmt.Errorf("invalid '%s' (must be an object)", engine.MetaKey)
+	}
+
+	for _, key := range metaMap.Keys() {
+		value, _ := metaMap.Get(key)
+		switch key {
+		case metaCount:
+			count, ok := metaInteger(value)
+			if !ok || count <= 0 {
+				return nil, fmt.Errorf("invalid '%s' count '%v' (must be a whole number greater than 0)", engine.MetaKey, value)
+			}
+			meta.count = int(count)
+		case metaOutput:
+			output, ok := value.(string)
+			if !ok || !filepath.IsLocal(output) {
+				return nil, fmt.Errorf("invalid '%s' output '%v' (must be a file name, or a path inside --out-dir)", engine.MetaKey, value)
+			}
+			meta.output = filepath.Clean(output)
+		case metaFormat:
+			format, ok := value.(string)
+			if !ok || !slices.Contains(outputFormats(), format) {
+				return nil, fmt.Errorf("invalid '%s' format '%v' (must be one of '%s')", engine.MetaKey, value, strings.Join(outputFormats(), "', '"))
+			}
+			meta.format = format
+		case metaSeed:
+			seed, ok := metaInteger(value)
+			if !ok {
+				return nil, fmt.Errorf("invalid '%s' seed '%v' (must be a whole number)", engine.MetaKey, value)
+			}
+			meta.seed = &seed
+		case metaLocale:
+			// As template.WithLocale, only the default locale is available for now
+			if value != mocker.DefaultLocale {
+				return nil, fmt.Errorf("unsupported '%s' locale '%v' (must be '%s')", engine.MetaKey, value, mocker.DefaultLocale)
+			}
+			meta.locale = mocker.DefaultLocale
+		default:
+			return nil, fmt.Errorf("unknown '%s' key '%s' (must be one of '%s')", engine.MetaKey, key, strings.Join(metaKeys(), "', '"))
+		}
+	}
+	return meta, nil
+}
+
+// Returns a whole number of a parsed template, which are decoded as float64.
+func metaInteger(value any) (int64, bool) {
+	number, ok := value.(float64)
+	if !ok || number != math.Trunc(number) || math.Abs(number) > 1<<53 {
+		return 0, false
+	}
+	return int64(number), true
+}
+
+// Returns the name of the output file asked by "$meta", with the extension of the format. The extension of another
+// format is replaced (e.g. "staff" and "staff.json" -> "staff.csv").
+func metaOutputName(output string, format string) string {
+	if templateFormat(output, "") != "" {
+		output = strings.TrimSuffix(output, filepath.Ext(output))
+	}
+	return output + formatExtension(format)
+}
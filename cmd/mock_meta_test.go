@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockMetaTestSuite struct {
+	suite.Suite
+}
+
+func TestMockMetaTestSuite(t *testing.T) {
+	suite.Run(t, new(MockMetaTestSuite))
+}
+
+func (suite *MockMetaTestSuite) TestExtractTemplateMeta_ValidInputs() {
+	seed := int64(-42)
+	tests := []struct {
+		testName         string
+		input            string
+		expected         *templateMeta
+		expectedTemplate string
+	}{
+		{
+			testName:         "without meta",
+			input:            `{ "name": "{{ Person.name }}" }`,
+			expected:         &templateMeta{},
+			expectedTemplate: `{"name":"{{ Person.name }}"}`,
+		},
+		{
+			testName:         "root array",
+			input:            `[{ "$meta": { "count": 2 } }]`,
+			expected:         &templateMeta{},
+			expectedTemplate: `[{"$meta":{"count":2}}]`,
+		},
+		{
+			testName:         "every setting",
+			input:            `{ "$meta": { "count": 50, "output": "exports/./staff", "format": "csv", "seed": -42, "locale": "en_US" }, "name": "{{ Person.name }}" }`,
+			expected:         &templateMeta{count: 50, output: "exports/staff", format: "csv", seed: &seed, locale: "en_US"},
+			expectedTemplate: `{"name":"{{ Person.name }}"}`,
+		},
+	}
+
+	for _, test := range tests {
+		template, err := engine.UnmarshalTemplate([]byte(test.input))
+		suite.Require().NoError(err, "Test case '%s' failed", test.testName)
+		meta, err := extractTemplateMeta(template)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", test.testName)
+		assert.Equal(suite.T(), test.expected, meta, "Test case '%s' failed", test.testName)
+		templateJSON, err := json.Marshal(template)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", test.testName)
+		assert.Equal(suite.T(), test.expectedTemplate, string(templateJSON), "Test case '%s' failed", test.testName)
+	}
+}
+
+func (suite *MockMetaTestSuite) TestExtractTemplateMeta_InvalidInputs() {
+	tests := []struct {
+		testName      string
+		input         string
+		expectedError string
+	}{
+		{
+			testName:      "not an object",
+			input:         `{ "$meta": [] }`,
+			expectedError: "invalid '$meta' (must be an object)",
+		},
+		{
+			testName:      "unknown key",
+			input:         `{ "$meta": { "amount": 2 } }`,
+			expectedError: "unknown '$meta' key 'amount' (must be one of 'count', 'output', 'format', 'seed', 'locale')",
+		},
+		{
+			testName:      "count with decimals",
+			input:         `{ "$meta": { "count": 2.5 } }`,
+			expectedError: "invalid '$meta' count '2.5' (must be a whole number greater than 0)",
+		},
+		{
+			testName:      "count as text",
+			input:         `{ "$meta": { "count": "2" } }`,
+			expectedError: "invalid '$meta' count '2' (must be a whole number greater than 0)",
+		},
+		{
+			testName:      "output outside --out-dir",
+			input:         `{ "$meta": { "output": "../staff" } }`,
+			expectedError: "invalid '$meta' output '../staff' (must be a file name, or a path inside --out-dir)",
+		},
+		{
+			testName:      "absolute output",
+			input:         `{ "$meta": { "output": "/tmp/staff" } }`,
+			expectedError: "invalid '$meta' output '/tmp/staff' (must be a file name, or a path inside --out-dir)",
+		},
+		{
+			testName:      "unknown format",
+			input:         `{ "$meta": { "format": "xlsx" } }`,
+			expectedError: "invalid '$meta' format 'xlsx' (must be one of 'json', 'ndjson', 'csv', 'tsv', 'sql', 'yaml', 'xml', 'toml')",
+		},
+		{
+			testName:      "seed with decimals",
+			input:         `{ "$meta": { "seed": 1.5 } }`,
+			expectedError: "invalid '$meta' seed '1.5' (must be a whole number)",
+		},
+		{
+			testName:      "unsupported locale",
+			input:         `{ "$meta": { "locale": "pt_BR" } }`,
+			expectedError: "unsupported '$meta' locale 'pt_BR' (must be 'en_US')",
+		},
+	}
+
+	for _, test := range tests {
+		template, err := engine.UnmarshalTemplate([]byte(test.input))
+		suite.Require().NoError(err, "Test case '%s' failed", test.testName)
+		_, err = extractTemplateMeta(template)
+		assert.EqualError(suite.T(), err, test.expectedError, "Test case '%s' failed", test.testName)
+	}
+}
+
+func (suite *MockMetaTestSuite) TestMetaOutputName() {
+	assert.Equal(suite.T(), "staff.csv", metaOutputName("staff", formatCsv))
+	assert.Equal(suite.T(), "staff.csv", metaOutputName("staff.csv", formatCsv))
+	assert.Equal(suite.T(), "staff.v2.json", metaOutputName("staff.v2", formatJson))
+	assert.Equal(suite.T(), "staff.csv", metaOutputName("staff.json", formatCsv), "the extension of another format is replaced")
+	assert.Equal(suite.T(), "exports/staff.yaml", metaOutputName("exports/staff.ndjson", formatYaml))
+}
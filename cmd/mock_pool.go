@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+	"github.com/lfsc09/k-test-n-stress/mocker"
+)
+
+// Amount of records a worker generates at a time, so a single template with many records is split across the workers.
+const recordsPerTask = 100
+
+// A fixed amount of workers generating records, each with its own mocker (mockers are not safe for concurrent use).
+// The chunks of records generated but not yet written are limited by `inFlight`, so the memory doesn't grow with the records.
+type workerPool struct {
+	tasks    chan func(mocker *mocker.Mock)
+	inFlight chan struct{}
+	wg       sync.WaitGroup
+}
+
+func newWorkerPool(parallelism int) *workerPool {
+	pool := &workerPool{
+		tasks:    make(chan func(mocker *mocker.Mock)),
+		inFlight: make(chan struct{}, 2*parallelism),
+	}
+	for range parallelism {
+		pool.wg.Add(1)
+		go func() {
+			defer pool.wg.Done()
+			mocker := mocker.New()
+			for task := range pool.tasks {
+				task(mocker)
+			}
+		}()
+	}
+	return pool
+}
+
+// Stops the workers, after every submitted task is done.
+func (p *workerPool) close() {
+	close(p.tasks)
+	p.wg.Wait()
+}
+
+// Generates `total` records of a template across the workers, handing them to `write` in order, a chunk at a time.
+// Every record is generated by a mocker reseeded for it, so the records only depend on the seed (and not on the amount of workers).
+// `recordMocker` may wrap the worker's mocker for each record, and `stopped` (optional) interrupts the generation.
+// `write` is never called concurrently, and only a few chunks wait in memory for the previous ones to be written.
+// `done` is called by the last worker once every chunk is written, or with the first error (of a record or of `write`).
+func (p *workerPool) streamRecords(template any, total int, seed int64, recordMocker func(base mocker.Mocker, record int) mocker.Mocker, stopped *atomic.Bool, write func(records []any) error, done func(err error)) {
+	var mu sync.Mutex
+	var firstErr error
+	firstErrRecord := total
+	nextChunk := 0
+	completed := make(map[int][]any)
+	chunks := (total + recordsPerTask - 1) / recordsPerTask
+	var pending atomic.Int64
+	pending.Store(int64(chunks))
+
+	for chunk := range chunks {
+		start := chunk * recordsPerTask
+		end := min(start+recordsPerTask, total)
+		p.inFlight <- struct{}{}
+		p.tasks <- func(base *mocker.Mock) {
+			records := make([]any, 0, end-start)
+			for i := start; i < end; i++ {
+				mu.Lock()
+				failed := firstErr != nil
+				mu.Unlock()
+				if failed {
+					break
+				}
+
+				var record any
+				var err error
+				if stopped != nil && stopped.Load() {
+					err = errSkippedByFailFast
+				} else {
+					base.Reseed(deriveSeed(seed, strconv.Itoa(i)))
+					record, err = engine.ProcessTemplate(engine.CloneValue(template), recordMocker(base, i))
+					if err != nil {
+						err = fmt.Errorf("failed to process record %d '%w'", i, err)
+					}
+				}
+				if err != nil {
+					mu.Lock()
+					if i < firstErrRecord {
+						firstErr, firstErrRecord = err, i
+					}
+					mu.Unlock()
+					break
+				}
+				records = append(records, record)
+			}
+
+			// Write the finished chunks in order, releasing their place for the next ones
+			mu.Lock()
+			completed[chunk] = records
+			for {
+				chunkRecords, ok := completed[nextChunk]
+				if !ok {
+					break
+				}
+				delete(completed, nextChunk)
+				nextChunk++
+				if firstErr == nil {
+					if err := write(chunkRecords); err != nil {
+						firstErr, firstErrRecord = err, -1
+					}
+				}
+				<-p.inFlight
+			}
+			mu.Unlock()
+
+			if pending.Add(-1) > 0 {
+				return
+			}
+			mu.Lock()
+			err := firstErr
+			mu.Unlock()
+			done(err)
+		}
+	}
+}
+
+// Derives a seed from another one, so each template and each record has its own independent seed.
+func deriveSeed(seed int64, scope string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(strconv.FormatInt(seed, 10) + ":" + scope))
+	return int64(hash.Sum64())
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+	"github.com/lfsc09/k-test-n-stress/mocker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockPoolTestSuite struct {
+	suite.Suite
+}
+
+func TestMockPoolTestSuite(t *testing.T) {
+	suite.Run(t, new(MockPoolTestSuite))
+}
+
+// Generates the records of a template with the given amount of workers, returning them sanitized.
+func (suite *MockPoolTestSuite) generate(template map[string]any, total int, seed int64, parallelism int) ([]any, error) {
+	var records []any
+	var err error
+	pool := newWorkerPool(parallelism)
+	pool.streamRecords(toOrderedValue(template), total, seed, withoutRecordMocker, nil, func(chunk []any) error {
+		records = append(records, chunk...)
+		return nil
+	}, func(streamErr error) {
+		err = streamErr
+	})
+	pool.close()
+	for _, record := range records {
+		engine.SanitizeValue(record)
+	}
+	return records, err
+}
+
+func (suite *MockPoolTestSuite) TestStreamRecords_SameSeedWhateverTheParallelism() {
+	template := map[string]any{
+		"id":        "{{ UUID.uuidv4 }}",
+		"phones[2]": "{{ Person.phoneNumber }}",
+		"kind":      map[string]any{"$oneOf": []any{"a", "b", "c"}},
+	}
+	total := recordsPerTask*3 + 7
+
+	sequential, err := suite.generate(template, total, 42, 1)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), sequential, total)
+	for _, parallelism := range []int{2, 8} {
+		parallel, err := suite.generate(template, total, 42, parallelism)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), fromOrderedValue(sequential), fromOrderedValue(parallel), "parallelism %d", parallelism)
+	}
+
+	otherSeed, err := suite.generate(template, total, 43, 2)
+	assert.NoError(suite.T(), err)
+	assert.NotEqual(suite.T(), fromOrderedValue(sequential), fromOrderedValue(otherSeed))
+}
+
+func (suite *MockPoolTestSuite) TestStreamRecords_FirstFailedRecord() {
+	template := map[string]any{
+		"value": map[string]any{
+			"$if":   "{{ Boolean.booleanWithChance:100 }}",
+			"$then": "{{ Unknown.function }}",
+		},
+	}
+	_, err := suite.generate(template, recordsPerTask*2, 1, 4)
+	assert.EqualError(suite.T(), err, "failed to process record 0 'unknown mock function 'Unknown.function''")
+}
+
+func (suite *MockPoolTestSuite) TestStreamRecords_Stopped() {
+	var stopped atomic.Bool
+	stopped.Store(true)
+	var err error
+	pool := newWorkerPool(2)
+	pool.streamRecords(toOrderedValue(map[string]any{"key": "raw"}), 10, 1, func(base mocker.Mocker, record int) mocker.Mocker {
+		return base
+	}, &stopped, func(_ []any) error {
+		return nil
+	}, func(streamErr error) {
+		err = streamErr
+	})
+	pool.close()
+	assert.ErrorIs(suite.T(), err, errSkippedByFailFast)
+}
+
+func (suite *MockPoolTestSuite) TestStreamRecords_WritesChunksInOrder() {
+	var written []any
+	var chunks int
+	var err error
+	total := recordsPerTask*5 + 1
+	pool := newWorkerPool(4)
+	pool.streamRecords(toOrderedValue(map[string]any{"key": "raw"}), total, 1, withoutRecordMocker, nil, func(chunk []any) error {
+		chunks++
+		written = append(written, chunk...)
+		return nil
+	}, func(streamErr error) {
+		err = streamErr
+	})
+	pool.close()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 6, chunks)
+	assert.Len(suite.T(), written, total)
+}
+
+func (suite *MockPoolTestSuite) TestStreamRecords_FailedWrite() {
+	var err error
+	pool := newWorkerPool(2)
+	pool.streamRecords(toOrderedValue(map[string]any{"key": "raw"}), recordsPerTask*3, 1, withoutRecordMocker, nil, func(_ []any) error {
+		return errors.New("disk full")
+	}, func(streamErr error) {
+		err = streamErr
+	})
+	pool.close()
+	assert.EqualError(suite.T(), err, "disk full")
+}
+
+func (suite *MockPoolTestSuite) TestDeriveSeed() {
+	assert.Equal(suite.T(), deriveSeed(1, "company.template.json"), deriveSeed(1, "company.template.json"))
+	assert.NotEqual(suite.T(), deriveSeed(1, "company.template.json"), deriveSeed(2, "company.template.json"))
+	assert.NotEqual(suite.T(), deriveSeed(1, "company.template.json"), deriveSeed(1, "employee.template.json"))
+}
@@ -0,0 +1,356 @@
+package cmd
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+	"github.com/lfsc09/k-test-n-stress/mocker"
+)
+
+const refPickFunction = "Ref.pick"
+
+// Cardinality rules of `Ref.pick`.
+const (
+	refPickRandom = ""
+	refPickUnique = "unique"
+	refPickEach   = "each"
+)
+
+// Holds the records generated by each template file, so other templates can reference their values.
+type refRegistry struct {
+	mu      sync.Mutex
+	records map[string][]any
+	values  map[string][]string
+}
+
+func newRefRegistry() *refRegistry {
+	return &refRegistry{
+		records: make(map[string][]any),
+		values:  make(map[string][]string),
+	}
+}
+
+// Registers the (sanitized) records generated by a template, replacing the ones it had (and their values).
+func (r *refRegistry) add(templateName string, records []any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records[templateName] = records
+	for ref := range r.values {
+		if strings.HasPrefix(ref, templateName+".") {
+			delete(r.values, ref)
+		}
+	}
+}
+
+// Returns whether the records of a template are registered.
+func (r *refRegistry) has(templateName string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.records[templateName]
+	return ok
+}
+
+// Returns all the values of a reference in the format "template.path.to.key".
+func (r *refRegistry) lookup(ref string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if values, ok := r.values[ref]; ok {
+		return values, nil
+	}
+
+	templateName, keyPath, found := strings.Cut(ref, ".")
+	if !found || templateName == "" || keyPath == "" {
+		return nil, fmt.Errorf("invalid reference '%s' (must be 'template.key')", ref)
+	}
+	records, ok := r.records[templateName]
+	if !ok {
+		return nil, fmt.Errorf("template '%s' was not generated, unable to reference '%s'", templateName, ref)
+	}
+
+	values := make([]string, 0, len(records))
+	for _, record := range records {
+		recordMap, ok := record.(*engine.OrderedMap)
+		if !ok {
+			return nil, fmt.Errorf("records of template '%s' are not objects, unable to reference '%s'", templateName, ref)
+		}
+		value, ok := valueAtPath(recordMap, strings.Split(keyPath, "."))
+		if !ok {
+			return nil, fmt.Errorf("key '%s' not found in the records of template '%s'", keyPath, templateName)
+		}
+		values = append(values, value)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("template '%s' has no records to reference", templateName)
+	}
+	r.values[ref] = values
+	return values, nil
+}
+
+// Follows a path of keys through nested objects, returning the value found as a string.
+func valueAtPath(record *engine.OrderedMap, keyPath []string) (string, bool) {
+	value, ok := record.Get(keyPath[0])
+	if !ok {
+		return "", false
+	}
+	if len(keyPath) == 1 {
+		switch value.(type) {
+		case *engine.OrderedMap, []any:
+			return "", false
+		}
+		return fmt.Sprint(value), true
+	}
+	nested, ok := value.(*engine.OrderedMap)
+	if !ok {
+		return "", false
+	}
+	return valueAtPath(nested, keyPath[1:])
+}
+
+// Picks referenced values for the records of one template, applying the cardinality rules.
+//
+// Cardinality is resolved by the record index instead of by the order of the picks, so the picked values
+// don't depend on which record was generated first.
+type refPicker struct {
+	registry *refRegistry
+	total    int
+	seed     int64
+	mu       sync.Mutex
+	perms    map[string][]int
+	picked   map[string]int
+}
+
+func newRefPicker(registry *refRegistry, total int, seed int64) *refPicker {
+	return &refPicker{
+		registry: registry,
+		total:    total,
+		seed:     seed,
+		perms:    make(map[string][]int),
+		picked:   make(map[string]int),
+	}
+}
+
+// Picks a value of `ref` for the `pick`-th reference to it inside the record `record`.
+// Random picks use `random`, the random source of the record.
+func (p *refPicker) pick(ref string, cardinality string, record int, pick int, random *rand.Rand) (string, error) {
+	values, err := p.registry.lookup(ref)
+	if err != nil {
+		return "", err
+	}
+
+	switch cardinality {
+	case refPickRandom:
+		return values[random.Intn(len(values))], nil
+	case refPickUnique, refPickEach:
+		slot := pick*p.total + record
+		perm := p.permutation(ref, cardinality, len(values), slot)
+		if slot < len(perm) {
+			return values[perm[slot]], nil
+		}
+		if cardinality == refPickUnique {
+			return "", fmt.Errorf("not enough values in '%s' to pick unique ones (only %d available)", ref, len(values))
+		}
+		return values[random.Intn(len(values))], nil
+	default:
+		return "", fmt.Errorf("invalid cardinality '%s' (must be '%s' or '%s')", cardinality, refPickUnique, refPickEach)
+	}
+}
+
+// Returns the shuffled order in which the values of `ref` are picked, keeping track of how many slots were used.
+// The order only depends on the picker's seed, and not on which record asked for it first.
+func (p *refPicker) permutation(ref string, cardinality string, size int, slot int) []int {
+	key := cardinality + ":" + ref
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	perm, ok := p.perms[key]
+	if !ok {
+		perm = rand.New(rand.NewSource(deriveSeed(p.seed, key))).Perm(size)
+		p.perms[key] = perm
+	}
+	if slot+1 > p.picked[key] {
+		p.picked[key] = slot + 1
+	}
+	return perm
+}
+
+// Checks that every value referenced with the 'each' cardinality was picked at least once.
+func (p *refPicker) verify() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, perm := range p.perms {
+		cardinality, ref, _ := strings.Cut(key, ":")
+		if cardinality == refPickEach && p.picked[key] < len(perm) {
+			return fmt.Errorf("not enough records to pick each value of '%s' at least once (%d values, %d picked)", ref, len(perm), p.picked[key])
+		}
+	}
+	return nil
+}
+
+// Mocker used while generating one record of a template, handling `Ref.pick` and delegating everything else.
+type refMocker struct {
+	mocker.Mocker
+	picker *refPicker
+	record int
+	picks  map[string]int
+}
+
+func newRefMocker(base mocker.Mocker, picker *refPicker, record int) *refMocker {
+	return &refMocker{
+		Mocker: base,
+		picker: picker,
+		record: record,
+		picks:  make(map[string]int),
+	}
+}
+
+func (m *refMocker) Generate(mockFunction string, functionParams []string) (string, error) {
+	if mockFunction != refPickFunction {
+		return m.Mocker.Generate(mockFunction, functionParams)
+	}
+	if len(functionParams) == 0 || functionParams[0] == "" {
+		return "", fmt.Errorf("%s function requires a reference as parameter (e.g. %s:company.id)", refPickFunction, refPickFunction)
+	}
+	ref := functionParams[0]
+	cardinality := refPickRandom
+	if len(functionParams) > 1 {
+		cardinality = functionParams[1]
+	}
+	pick := m.picks[ref]
+	m.picks[ref]++
+	return m.picker.pick(ref, cardinality, m.record, pick, m.Rand())
+}
+
+func (m *refMocker) Rand() *rand.Rand {
+	return mocker.RandOf(m.Mocker)
+}
+
+// Returns the name by which a template file can be referenced (e.g. "assets/company[10].template.json" -> "company").
+func templateName(inPath string) string {
+	name := engine.TrimTemplateExtension(filepath.Base(inPath))
+	if templateFormat(name, "") != "" {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return engine.SanitizeKeyWithBrackets(name)
+}
+
+// Finds the names of the templates referenced with `Ref.pick` in a parsed template.
+func findRefDependencies(template any) []string {
+	found := make(map[string]bool)
+	var walk func(value any)
+	walk = func(value any) {
+		switch typedValue := value.(type) {
+		case string:
+			interpretedValue, isMockFunction := engine.InterpretString(typedValue)
+			if !isMockFunction {
+				return
+			}
+			functionName, params := engine.ExtractMockMethod(interpretedValue)
+			if functionName == refPickFunction && len(params) > 0 {
+				if templateName, _, ok := strings.Cut(params[0], "."); ok && templateName != "" {
+					found[templateName] = true
+				}
+			}
+		case *engine.OrderedMap:
+			for _, objKey := range typedValue.Keys() {
+				objValue, _ := typedValue.Get(objKey)
+				if functionName, params, _, isDynamicKey, _ := engine.ExtractDynamicKey(objKey); isDynamicKey && functionName == refPickFunction && len(params) > 0 {
+					if templateName, _, ok := strings.Cut(params[0], "."); ok && templateName != "" {
+						found[templateName] = true
+					}
+				}
+				walk(objValue)
+			}
+		case []any:
+			for _, item := range typedValue {
+				walk(item)
+			}
+		}
+	}
+	walk(template)
+
+	dependencies := make([]string, 0, len(found))
+	for templateName := range found {
+		dependencies = append(dependencies, templateName)
+	}
+	sort.Strings(dependencies)
+	return dependencies
+}
+
+// Groups templates in levels so every template comes after the templates it references.
+// Templates in the same level don't depend on each other, and can be generated concurrently.
+// `names` holds the name of each template and `dependencies` the template names each one references.
+// Returns the levels as indexes of `names`, and the error of each template that can't be ordered (nil for the others):
+// templates referencing an unknown (or duplicated) name are still in the levels, so their dependents come after them,
+// while templates in (or depending on) a cycle are left out of them.
+func sortByDependencies(names []string, dependencies [][]string) ([][]int, []error) {
+	byName := make(map[string]int, len(names))
+	duplicated := make(map[string]bool)
+	for idx, name := range names {
+		if _, ok := byName[name]; ok {
+			duplicated[name] = true
+		}
+		byName[name] = idx
+	}
+
+	errs := make([]error, len(names))
+	pending := make([]int, len(names))
+	dependents := make([][]int, len(names))
+	for idx := range names {
+		for _, dependency := range dependencies[idx] {
+			dependencyIdx, ok := byName[dependency]
+			if !ok {
+				errs[idx] = fmt.Errorf("template '%s' references unknown template '%s'", names[idx], dependency)
+				continue
+			}
+			if duplicated[dependency] {
+				errs[idx] = fmt.Errorf("multiple templates named '%s', unable to resolve references to it", dependency)
+				continue
+			}
+			pending[idx]++
+			dependents[dependencyIdx] = append(dependents[dependencyIdx], idx)
+		}
+	}
+
+	var levels [][]int
+	done := 0
+	var current []int
+	for idx := range names {
+		if pending[idx] == 0 {
+			current = append(current, idx)
+		}
+	}
+	for len(current) > 0 {
+		levels = append(levels, current)
+		done += len(current)
+		var next []int
+		for _, idx := range current {
+			for _, dependent := range dependents[idx] {
+				pending[dependent]--
+				if pending[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		sort.Ints(next)
+		current = next
+	}
+
+	if done != len(names) {
+		var cyclic []string
+		for idx, name := range names {
+			if pending[idx] > 0 {
+				cyclic = append(cyclic, name)
+			}
+		}
+		for idx := range names {
+			if pending[idx] > 0 && errs[idx] == nil {
+				errs[idx] = fmt.Errorf("cyclic references between templates '%s'", strings.Join(cyclic, "', '"))
+			}
+		}
+	}
+	return levels, errs
+}
@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lfsc09/k-test-n-stress/mocker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockRefTestSuite struct {
+	suite.Suite
+}
+
+func TestMockRefTestSuite(t *testing.T) {
+	suite.Run(t, new(MockRefTestSuite))
+}
+
+// Registry with a "company" template of `total` records, with ids "c0", "c1", ...
+func (suite *MockRefTestSuite) companyRegistry(total int) *refRegistry {
+	registry := newRefRegistry()
+	companies := make([]any, total)
+	for i := range total {
+		companies[i] = toOrderedValue(map[string]any{
+			"id":      fmt.Sprintf("c%d", i),
+			"address": map[string]any{"city": "city"},
+		})
+	}
+	registry.add("company", companies)
+	return registry
+}
+
+func (suite *MockRefTestSuite) TestTemplateName_ValidInputs() {
+	tests := []struct {
+		testName     string
+		input        string
+		expectedName string
+	}{
+		{
+			testName:     "simple file",
+			input:        "company.template.json",
+			expectedName: "company",
+		},
+		{
+			testName:     "file with count",
+			input:        "company[10].template.json",
+			expectedName: "company",
+		},
+		{
+			testName:     "file inside folders",
+			input:        "templates/assets/employee[200].template.json",
+			expectedName: "employee",
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(suite.T(), tt.expectedName, templateName(tt.input), "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *MockRefTestSuite) TestFindRefDependencies_ValidInputs() {
+	tests := []struct {
+		testName     string
+		input        map[string]any
+		expectedDeps []string
+	}{
+		{
+			testName:     "no references",
+			input:        map[string]any{"name": "{{ Person.name }}"},
+			expectedDeps: []string{},
+		},
+		{
+			testName: "references in nested objects and arrays, deduplicated",
+			input: map[string]any{
+				"companyId": "{{ Ref.pick:company.id }}",
+				"manager": map[string]any{
+					"id": "{{ Ref.pick:employee.id:unique }}",
+				},
+				"tags": []any{"{{ Ref.pick:tag.name }}", map[string]any{"companyId": "{{ Ref.pick:company.id }}"}},
+				"byBuilding": map[string]any{
+					"{{ Ref.pick:building.id:unique }}[2]": "{{ Person.name }}",
+				},
+			},
+			expectedDeps: []string{"building", "company", "employee", "tag"},
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(suite.T(), tt.expectedDeps, findRefDependencies(toOrderedValue(tt.input)), "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *MockRefTestSuite) TestSortByDependencies_ValidInputs() {
+	tests := []struct {
+		testName       string
+		names          []string
+		dependencies   [][]string
+		expectedLevels [][]int
+	}{
+		{
+			testName:       "no dependencies",
+			names:          []string{"company", "building"},
+			dependencies:   [][]string{nil, nil},
+			expectedLevels: [][]int{{0, 1}},
+		},
+		{
+			testName:       "chain of dependencies",
+			names:          []string{"task", "employee", "company"},
+			dependencies:   [][]string{{"employee"}, {"company"}, nil},
+			expectedLevels: [][]int{{2}, {1}, {0}},
+		},
+		{
+			testName:       "multiple dependencies",
+			names:          []string{"employee", "company", "building", "department"},
+			dependencies:   [][]string{{"company", "department"}, nil, {"company"}, {"company"}},
+			expectedLevels: [][]int{{1}, {2, 3}, {0}},
+		},
+		{
+			testName:       "duplicated names not referenced",
+			names:          []string{"company", "company"},
+			dependencies:   [][]string{nil, nil},
+			expectedLevels: [][]int{{0, 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		levels, errs := sortByDependencies(tt.names, tt.dependencies)
+		assert.Equal(suite.T(), make([]error, len(tt.names)), errs, "Test case '%s' failed", tt.testName)
+		assert.Equal(suite.T(), tt.expectedLevels, levels, "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *MockRefTestSuite) TestSortByDependencies_InvalidInputs() {
+	tests := []struct {
+		testName       string
+		names          []string
+		dependencies   [][]string
+		expectedLevels [][]int
+		expectedErrors []string
+	}{
+		{
+			testName:       "unknown template",
+			names:          []string{"employee", "company", "task"},
+			dependencies:   [][]string{{"building"}, nil, {"employee"}},
+			expectedLevels: [][]int{{0, 1}, {2}},
+			expectedErrors: []string{"template 'employee' references unknown template 'building'", "", ""},
+		},
+		{
+			testName:       "self reference",
+			names:          []string{"employee", "company"},
+			dependencies:   [][]string{{"employee"}, nil},
+			expectedLevels: [][]int{{1}},
+			expectedErrors: []string{"cyclic references between templates 'employee'", ""},
+		},
+		{
+			testName:       "cyclic references",
+			names:          []string{"employee", "company", "task", "building"},
+			dependencies:   [][]string{{"company"}, {"employee"}, {"employee"}, nil},
+			expectedLevels: [][]int{{3}},
+			expectedErrors: []string{
+				"cyclic references between templates 'employee', 'company', 'task'",
+				"cyclic references between templates 'employee', 'company', 'task'",
+				"cyclic references between templates 'employee', 'company', 'task'",
+				"",
+			},
+		},
+		{
+			testName:       "referencing duplicated names",
+			names:          []string{"company", "company", "employee"},
+			dependencies:   [][]string{nil, nil, {"company"}},
+			expectedLevels: [][]int{{0, 1, 2}},
+			expectedErrors: []string{"", "", "multiple templates named 'company', unable to resolve references to it"},
+		},
+	}
+
+	for _, tt := range tests {
+		levels, errs := sortByDependencies(tt.names, tt.dependencies)
+		assert.Equal(suite.T(), tt.expectedLevels, levels, "Test case '%s' failed", tt.testName)
+		suite.Require().Len(errs, len(tt.expectedErrors), "Test case '%s' failed", tt.testName)
+		for idx, expectedError := range tt.expectedErrors {
+			if expectedError == "" {
+				assert.NoError(suite.T(), errs[idx], "Test case '%s' failed", tt.testName)
+				continue
+			}
+			assert.EqualError(suite.T(), errs[idx], expectedError, "Test case '%s' failed", tt.testName)
+		}
+	}
+}
+
+func (suite *MockRefTestSuite) TestRefMocker_PickValues() {
+	registry := suite.companyRegistry(3)
+	validIds := []string{"c0", "c1", "c2"}
+
+	// Random picks
+	picker := newRefPicker(registry, 5, 1)
+	for i := range 5 {
+		value, err := newRefMocker(mocker.New(), picker, i).Generate("Ref.pick", []string{"company.id"})
+		assert.NoError(suite.T(), err)
+		assert.Contains(suite.T(), validIds, value)
+	}
+
+	// Nested keys
+	value, err := newRefMocker(mocker.New(), picker, 0).Generate("Ref.pick", []string{"company.address.city"})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "city", value)
+
+	// Other functions are delegated to the mocker
+	value, err = newRefMocker(mocker.New(), picker, 0).Generate("Person.name", nil)
+	assert.NoError(suite.T(), err)
+	assert.NotEmpty(suite.T(), value)
+}
+
+func (suite *MockRefTestSuite) TestRefMocker_PickUniqueValues() {
+	registry := suite.companyRegistry(3)
+
+	picker := newRefPicker(registry, 3, 1)
+	picked := make([]string, 0, 3)
+	for i := range 3 {
+		value, err := newRefMocker(mocker.New(), picker, i).Generate("Ref.pick", []string{"company.id", "unique"})
+		assert.NoError(suite.T(), err)
+		picked = append(picked, value)
+	}
+	assert.ElementsMatch(suite.T(), []string{"c0", "c1", "c2"}, picked)
+
+	// More records than values
+	picker = newRefPicker(registry, 4, 1)
+	_, err := newRefMocker(mocker.New(), picker, 3).Generate("Ref.pick", []string{"company.id", "unique"})
+	assert.Error(suite.T(), err)
+
+	// Two picks in the same record
+	picker = newRefPicker(registry, 1, 1)
+	refMocker := newRefMocker(mocker.New(), picker, 0)
+	first, err := refMocker.Generate("Ref.pick", []string{"company.id", "unique"})
+	assert.NoError(suite.T(), err)
+	second, err := refMocker.Generate("Ref.pick", []string{"company.id", "unique"})
+	assert.NoError(suite.T(), err)
+	assert.NotEqual(suite.T(), first, second)
+}
+
+func (suite *MockRefTestSuite) TestRefMocker_PickEachValue() {
+	registry := suite.companyRegistry(3)
+
+	picker := newRefPicker(registry, 5, 1)
+	picked := make(map[string]bool)
+	for i := range 5 {
+		value, err := newRefMocker(mocker.New(), picker, i).Generate("Ref.pick", []string{"company.id", "each"})
+		assert.NoError(suite.T(), err)
+		picked[value] = true
+	}
+	assert.Len(suite.T(), picked, 3)
+	assert.NoError(suite.T(), picker.verify())
+
+	// Less records than values
+	picker = newRefPicker(registry, 2, 1)
+	for i := range 2 {
+		_, err := newRefMocker(mocker.New(), picker, i).Generate("Ref.pick", []string{"company.id", "each"})
+		assert.NoError(suite.T(), err)
+	}
+	assert.Error(suite.T(), picker.verify())
+}
+
+func (suite *MockRefTestSuite) TestRefMocker_InvalidInputs() {
+	registry := suite.companyRegistry(3)
+	tests := []struct {
+		testName string
+		params   []string
+	}{
+		{
+			testName: "missing reference",
+			params:   []string{},
+		},
+		{
+			testName: "reference without key",
+			params:   []string{"company"},
+		},
+		{
+			testName: "unknown template",
+			params:   []string{"building.id"},
+		},
+		{
+			testName: "unknown key",
+			params:   []string{"company.name"},
+		},
+		{
+			testName: "referencing an object",
+			params:   []string{"company.address"},
+		},
+		{
+			testName: "invalid cardinality",
+			params:   []string{"company.id", "some"},
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := newRefMocker(mocker.New(), newRefPicker(registry, 1, 1), 0).Generate("Ref.pick", tt.params)
+		assert.Error(suite.T(), err, "Test case '%s' failed", tt.testName)
+	}
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+)
+
+// A shard written with --shard-size or --shards, as listed in the manifest.
+type shardManifest struct {
+	File    string `json:"file"`
+	Records int    `json:"records"`
+	Size    int64  `json:"size"`
+	Sha256  string `json:"sha256"`
+}
+
+// Lists the shards of a template, written next to them (e.g. "employees.manifest.json").
+type outputManifest struct {
+	Name    string          `json:"name"`
+	Format  string          `json:"format"`
+	Records int             `json:"records"`
+	Shards  []shardManifest `json:"shards"`
+}
+
+// Writes the records of a template in shards (e.g. "employees-0001.json", "employees-0002.json"), each one a complete
+// file of the output format, followed by the manifest listing them. Shards have `shardSize` records, or with --shards,
+// the records are split as evenly as possible (see shardRecords).
+type shardedWriter struct {
+	output       *outputOptions
+	name         string
+	total        int
+	shardSize    int
+	basePath     string
+	manifestPath string
+	manifest     *os.File
+	mu           *sync.Mutex
+	createdDirs  *map[string]bool
+	shards       []shardManifest
+	paths        []string
+	current      recordWriter
+	file         *os.File
+	hash         hash.Hash
+	counter      *countingWriter
+}
+
+// Creates the manifest of the `total` records of a template, so an existing one fails before any shard is written.
+// The shards are created as the records are written.
+func newShardedWriter(output *outputOptions, inPath string, name string, total int, mu *sync.Mutex, createdDirs *map[string]bool) (*shardedWriter, error) {
+	basePath, err := outputPath(output, inPath)
+	if err != nil {
+		return nil, err
+	}
+	if output.file == "" && output.name == "" {
+		basePath = shardBasePath(basePath)
+	}
+	writer := &shardedWriter{
+		output:       output,
+		name:         name,
+		total:        total,
+		shardSize:    output.shardSize,
+		basePath:     basePath,
+		manifestPath: manifestPath(basePath),
+		mu:           mu,
+		createdDirs:  createdDirs,
+	}
+	writer.manifest, err = createOutputFile(output, writer.manifestPath, mu, createdDirs)
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *shardedWriter) write(record any) error {
+	if w.current == nil || w.shards[len(w.shards)-1].Records == w.shardRecords(len(w.shards)-1) {
+		if err := w.nextShard(); err != nil {
+			return err
+		}
+	}
+	if err := w.current.write(record); err != nil {
+		return err
+	}
+	w.shards[len(w.shards)-1].Records++
+	return nil
+}
+
+// Returns the amount of records of a shard (numbered from 0). With --shards, every shard gets `total / shards` records,
+// and the first `total % shards` shards a record more, so fewer shards are only written when there are fewer records.
+func (w *shardedWriter) shardRecords(shard int) int {
+	if w.output.shards == 0 {
+		return w.shardSize
+	}
+	records := w.total / w.output.shards
+	if shard < w.total%w.output.shards {
+		records++
+	}
+	return max(records, 1)
+}
+
+// Finishes the last shard and writes the manifest.
+func (w *shardedWriter) close() error {
+	if err := w.finishShard(); err != nil {
+		return err
+	}
+	manifest := outputManifest{Name: w.name, Format: w.output.format, Shards: w.shards}
+	for _, shard := range w.shards {
+		manifest.Records += shard.Records
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling JSON '%w'", err)
+	}
+	_, err = w.manifest.Write(append(manifestJSON, '\n'))
+	closeErr := w.manifest.Close()
+	w.manifest = nil
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write result to '%v', '%w'", w.manifestPath, err)
+	}
+	return nil
+}
+
+// Removes the shards and the manifest, so no partial output is left behind when the generation fails.
+func (w *shardedWriter) discard() {
+	if discardable, ok := w.current.(discardableWriter); ok {
+		discardable.discard()
+	}
+	if w.file != nil {
+		w.file.Close()
+	}
+	if w.manifest != nil {
+		w.manifest.Close()
+	}
+	for _, path := range w.paths {
+		os.Remove(path)
+	}
+	os.Remove(w.manifestPath)
+}
+
+// Finishes the shard being written (if any), and starts the next one.
+func (w *shardedWriter) nextShard() error {
+	if err := w.finishShard(); err != nil {
+		return err
+	}
+	path := shardPath(w.basePath, len(w.shards)+1)
+	file, err := createOutputFile(w.output, path, w.mu, w.createdDirs)
+	if err != nil {
+		return err
+	}
+	w.paths = append(w.paths, path)
+	w.file, w.hash, w.counter = file, sha256.New(), &countingWriter{}
+	// Every shard is written as part of the whole output (e.g. a JSON array, even for a last shard of one record)
+	w.current, err = newRecordWriter(w.output, w.name, io.MultiWriter(file, w.hash, w.counter), w.total)
+	if err != nil {
+		return err
+	}
+	w.shards = append(w.shards, shardManifest{File: filepath.Base(path)})
+	return nil
+}
+
+// Closes the shard being written, adding its size and checksum to the manifest.
+func (w *shardedWriter) finishShard() error {
+	if w.current == nil {
+		return nil
+	}
+	err := w.current.close()
+	closeErr := w.file.Close()
+	w.current, w.file = nil, nil
+	if err != nil {
+		return fmt.Errorf("failed to write record '%w'", err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to write result to '%v', '%w'", w.paths[len(w.paths)-1], closeErr)
+	}
+	shard := &w.shards[len(w.shards)-1]
+	shard.Size = w.counter.size
+	shard.Sha256 = hex.EncodeToString(w.hash.Sum(nil))
+	return nil
+}
+
+// Prints the files split in fewer shards than --shards, as they have fewer records (a shard for each record).
+func reportFewerShards(out io.Writer, shards int, outputs []writtenOutput) {
+	var fewer []writtenOutput
+	for _, output := range outputs {
+		if output.records < shards {
+			fewer = append(fewer, output)
+		}
+	}
+	if len(fewer) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\nFewer shards than --shards %d (not enough records):\n", shards)
+	for _, output := range fewer {
+		fmt.Fprintf(out, "  - %s: %d shards (%d records)\n", output.path, output.records, output.records)
+	}
+}
+
+// Returns the output file named after a template without its count, as the shards are named after the template name
+// (e.g. "out/employees[100000].json" -> "out/employees.json").
+func shardBasePath(basePath string) string {
+	ext := filepath.Ext(basePath)
+	name := engine.SanitizeKeyWithBrackets(strings.TrimSuffix(filepath.Base(basePath), ext))
+	return filepath.Join(filepath.Dir(basePath), name+ext)
+}
+
+// Returns the path of a shard of an output file, numbered from 1 (e.g. "out/employees.json" -> "out/employees-0001.json").
+func shardPath(basePath string, shard int) string {
+	ext := filepath.Ext(basePath)
+	return fmt.Sprintf("%s-%04d%s", strings.TrimSuffix(basePath, ext), shard, ext)
+}
+
+// Returns the path of the manifest of a sharded output file (e.g. "out/employees.json" -> "out/employees.manifest.json").
+func manifestPath(basePath string) string {
+	return strings.TrimSuffix(basePath, filepath.Ext(basePath)) + ".manifest.json"
+}
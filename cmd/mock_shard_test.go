@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockShardTestSuite struct {
+	suite.Suite
+}
+
+func TestMockShardTestSuite(t *testing.T) {
+	suite.Run(t, new(MockShardTestSuite))
+}
+
+func (suite *MockShardTestSuite) TestShardPaths() {
+	assert.Equal(suite.T(), filepath.Join("out", "employees-0001.json"), shardPath(filepath.Join("out", "employees.json"), 1))
+	assert.Equal(suite.T(), "employees-0012.ndjson", shardPath("employees.ndjson", 12))
+	assert.Equal(suite.T(), "employees-12345.csv", shardPath("employees.csv", 12345))
+	assert.Equal(suite.T(), filepath.Join("out", "employees.manifest.json"), manifestPath(filepath.Join("out", "employees.json")))
+	assert.Equal(suite.T(), filepath.Join("out", "employees.json"), shardBasePath(filepath.Join("out", "employees[100000].json")))
+	assert.Equal(suite.T(), "employees.csv", shardBasePath("employees.csv"))
+}
+
+func (suite *MockShardTestSuite) writeShards(output *outputOptions, total int) *shardedWriter {
+	var mu sync.Mutex
+	createdDirs := make(map[string]bool)
+	writer, err := newShardedWriter(output, "employees.template.json", "employees", total, &mu, &createdDirs)
+	suite.Require().NoError(err)
+	for idx := range total {
+		record := engine.NewOrderedMap()
+		record.Set("id", float64(idx))
+		suite.Require().NoError(writer.write(record))
+	}
+	return writer
+}
+
+func (suite *MockShardTestSuite) TestShardedWriter() {
+	tests := []struct {
+		testName        string
+		output          outputOptions
+		total           int
+		expectedRecords []int
+		expectedShard   string
+	}{
+		{
+			testName:        "json by shard size",
+			output:          outputOptions{format: formatJson, shardSize: 2},
+			total:           5,
+			expectedRecords: []int{2, 2, 1},
+			expectedShard:   "[\n  {\n    \"id\": 4\n  }\n]\n",
+		},
+		{
+			testName:        "ndjson by amount of shards",
+			output:          outputOptions{format: formatNdjson, shards: 3},
+			total:           10,
+			expectedRecords: []int{4, 3, 3},
+			expectedShard:   "{\"id\":7}\n{\"id\":8}\n{\"id\":9}\n",
+		},
+		{
+			testName:        "records not divisible by the shards",
+			output:          outputOptions{format: formatNdjson, shards: 4},
+			total:           10,
+			expectedRecords: []int{3, 3, 2, 2},
+			expectedShard:   "{\"id\":8}\n{\"id\":9}\n",
+		},
+		{
+			testName:        "a record more in the first shard",
+			output:          outputOptions{format: formatJson, shards: 4},
+			total:           5,
+			expectedRecords: []int{2, 1, 1, 1},
+			expectedShard:   "[\n  {\n    \"id\": 4\n  }\n]\n",
+		},
+		{
+			testName:        "more shards than records",
+			output:          outputOptions{format: formatNdjson, shards: 4},
+			total:           2,
+			expectedRecords: []int{1, 1},
+			expectedShard:   "{\"id\":1}\n",
+		},
+	}
+
+	for _, test := range tests {
+		test.output.dir = suite.T().TempDir()
+		writer := suite.writeShards(&test.output, test.total)
+		assert.NoError(suite.T(), writer.close(), "Test case '%s' failed", test.testName)
+
+		content, err := os.ReadFile(filepath.Join(test.output.dir, "employees.manifest.json"))
+		suite.Require().NoError(err, "Test case '%s' failed", test.testName)
+		var manifest outputManifest
+		suite.Require().NoError(json.Unmarshal(content, &manifest), "Test case '%s' failed", test.testName)
+		assert.Equal(suite.T(), "employees", manifest.Name, "Test case '%s' failed", test.testName)
+		assert.Equal(suite.T(), test.output.format, manifest.Format, "Test case '%s' failed", test.testName)
+		assert.Equal(suite.T(), test.total, manifest.Records, "Test case '%s' failed", test.testName)
+		suite.Require().Len(manifest.Shards, len(test.expectedRecords), "Test case '%s' failed", test.testName)
+		for idx, shard := range manifest.Shards {
+			assert.Equal(suite.T(), shardPath("employees"+formatExtension(test.output.format), idx+1), shard.File, "Test case '%s' failed", test.testName)
+			assert.Equal(suite.T(), test.expectedRecords[idx], shard.Records, "Test case '%s' failed", test.testName)
+			shardContent, err := os.ReadFile(filepath.Join(test.output.dir, shard.File))
+			assert.NoError(suite.T(), err, "Test case '%s' failed", test.testName)
+			checksum := sha256.Sum256(shardContent)
+			assert.Equal(suite.T(), hex.EncodeToString(checksum[:]), shard.Sha256, "Test case '%s' failed", test.testName)
+			assert.Equal(suite.T(), int64(len(shardContent)), shard.Size, "Test case '%s' failed", test.testName)
+			if idx == len(manifest.Shards)-1 {
+				assert.Equal(suite.T(), test.expectedShard, string(shardContent), "Test case '%s' failed", test.testName)
+			}
+		}
+	}
+}
+
+func (suite *MockShardTestSuite) TestReportFewerShards() {
+	var out bytes.Buffer
+	reportFewerShards(&out, 4, []writtenOutput{
+		{path: "employees.manifest.json", records: 10},
+		{path: "company.manifest.json", records: 2},
+	})
+	assert.Equal(suite.T(), "\nFewer shards than --shards 4 (not enough records):\n  - company.manifest.json: 2 shards (2 records)\n", out.String())
+
+	out.Reset()
+	reportFewerShards(&out, 4, []writtenOutput{{path: "employees.manifest.json", records: 4}})
+	assert.Empty(suite.T(), out.String(), "nothing is reported when every file has its shards")
+}
+
+func (suite *MockShardTestSuite) TestShardedWriter_Remove() {
+	output := &outputOptions{format: formatNdjson, shardSize: 2, dir: suite.T().TempDir()}
+	writer := suite.writeShards(output, 3)
+	writer.discard()
+	entries, err := os.ReadDir(output.dir)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), entries, "no partial output is left behind")
+}
+
+func (suite *MockShardTestSuite) TestShardedWriter_ExistingManifest() {
+	output := &outputOptions{format: formatJson, shardSize: 2, dir: suite.T().TempDir()}
+	manifest := filepath.Join(output.dir, "employees.manifest.json")
+	suite.Require().NoError(os.WriteFile(manifest, []byte("{}"), 0644))
+	var mu sync.Mutex
+	createdDirs := make(map[string]bool)
+	_, err := newShardedWriter(output, "employees.template.json", "employees", 3, &mu, &createdDirs)
+	assert.EqualError(suite.T(), err, "'"+manifest+"' already exists (use --force to overwrite it)")
+	content, err := os.ReadFile(manifest)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "{}", string(content), "existing manifests are kept")
+}
@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+	"github.com/lfsc09/k-test-n-stress/mocker"
+)
+
+// Key added to every record by --record-size, holding the filler text that brings the record to the asked size.
+const paddingKey = "_padding"
+
+// Text repeated in the padding of the records.
+const paddingText = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// The records generated to estimate how many records reach a --target-size, stopping at whichever limit comes first.
+const (
+	sizeSampleRecords = 1000
+	sizeSampleBytes   = 256 * kb
+)
+
+var sizeRegex = regexp.MustCompile(`^(?i)(\d+(?:\.\d+)?)\s*(B|KB|MB|GB)?$`)
+
+// Parses a size (e.g. "8KB", "1.5MB" or "512"), in bytes. Units are powers of 1024, as in the reported sizes.
+func parseSize(flag string, raw string) (int64, error) {
+	matches := sizeRegex.FindStringSubmatch(strings.TrimSpace(raw))
+	if len(matches) == 0 {
+		return 0, fmt.Errorf("invalid --%s '%s' (must be a size such as '8KB', '50MB' or '1GB')", flag, raw)
+	}
+	amount, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --%s '%s' (must be a size such as '8KB', '50MB' or '1GB')", flag, raw)
+	}
+	switch strings.ToUpper(matches[2]) {
+	case "KB":
+		amount *= kb
+	case "MB":
+		amount *= mb
+	case "GB":
+		amount *= gb
+	}
+	size := int64(math.Round(amount))
+	if size <= 0 {
+		return 0, fmt.Errorf("invalid --%s '%s' (must be greater than 0 bytes)", flag, raw)
+	}
+	return size, nil
+}
+
+// Implemented by the writers that don't write each record as it comes (e.g. the rows of csv/tsv, or the batches of
+// sql), measuring the bytes a record adds to their output on their own.
+type recordMeasurer interface {
+	recordSize(record any) (int64, error)
+}
+
+// Pads the records to about `recordSize` bytes in the output format. A record is measured by the bytes it adds to an
+// output of the format that already has a record (e.g. with the comma and indentation of a JSON array), by writing it
+// to a writer that only counts them (or by the writer itself, see recordMeasurer).
+type recordPadder struct {
+	recordSize int64
+	writer     recordWriter
+	counter    *countingWriter
+	primed     bool
+}
+
+// Returns the padder of the records written in the output format, or nil when they aren't padded (no --record-size).
+func newRecordPadder(output *outputOptions, name string, recordSize int64) (*recordPadder, error) {
+	if recordSize <= 0 {
+		return nil, nil
+	}
+	counter := &countingWriter{}
+	writer, err := newRecordWriter(output, name, counter, 2)
+	if err != nil {
+		return nil, err
+	}
+	return &recordPadder{recordSize: recordSize, writer: writer, counter: counter}, nil
+}
+
+// Adds a padding key to a sanitized record, so it is about `recordSize` bytes long in the output format.
+// Every record gets the key (empty when the record is already as large), so they all keep the same keys.
+func (p *recordPadder) pad(record any) error {
+	rootMap, ok := record.(*engine.OrderedMap)
+	if !ok {
+		return fmt.Errorf("failed to pad record (--record-size is only available for templates whose root is an object)")
+	}
+	rootMap.Remove(paddingKey)
+	rootMap.Set(paddingKey, "")
+	size, err := p.measure(rootMap)
+	if err != nil {
+		return err
+	}
+	missing := p.recordSize - size
+	if missing <= 0 {
+		return nil
+	}
+	rootMap.Set(paddingKey, paddingOf(missing))
+	padded, err := p.measure(rootMap)
+	if err != nil {
+		return err
+	}
+	// A format may write the padding with more (or less) bytes than characters (e.g. quoted only when empty, or in
+	// every row of exploded csv arrays), so the characters are corrected by the bytes each one added
+	if padded != p.recordSize {
+		growth := float64(padded-size) / float64(missing)
+		missing = max(missing+int64(math.Round(float64(p.recordSize-padded)/growth)), 0)
+		rootMap.Set(paddingKey, paddingOf(missing))
+	}
+	return nil
+}
+
+// Returns the bytes a record adds to an output of the format.
+func (p *recordPadder) measure(record any) (int64, error) {
+	if measurer, ok := p.writer.(recordMeasurer); ok {
+		return measurer.recordSize(record)
+	}
+	if !p.primed {
+		if err := p.writer.write(record); err != nil {
+			return 0, fmt.Errorf("failed to pad record '%w'", err)
+		}
+		p.primed = true
+	}
+	before := p.counter.size
+	if err := p.writer.write(record); err != nil {
+		return 0, fmt.Errorf("failed to pad record '%w'", err)
+	}
+	return p.counter.size - before, nil
+}
+
+// Returns `size` filler characters.
+func paddingOf(size int64) string {
+	return strings.Repeat(paddingText, int(size)/len(paddingText)+1)[:size]
+}
+
+// Counts the bytes written to it.
+type countingWriter struct {
+	size int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.size += int64(len(p))
+	return len(p), nil
+}
+
+// Estimates how many records of a template reach `targetSize` bytes in the output format, by writing a sample of them.
+// The sample is made of the first records (with the same seeds), so the estimate only depends on the seed.
+func estimateRecordCount(template any, seed int64, recordMocker func(base mocker.Mocker, record int) mocker.Mocker, output *outputOptions, name string, recordSize int64, targetSize int64) (int, error) {
+	counter := &countingWriter{}
+	writer, err := newRecordWriter(output, name, counter, sizeSampleRecords)
+	if err != nil {
+		return 0, err
+	}
+	padder, err := newRecordPadder(output, name, recordSize)
+	if err != nil {
+		return 0, err
+	}
+	base := mocker.New()
+	sampled := 0
+	for sampled < sizeSampleRecords && counter.size < min(targetSize, sizeSampleBytes) {
+		base.Reseed(deriveSeed(seed, strconv.Itoa(sampled)))
+		record, err := engine.ProcessTemplate(engine.CloneValue(template), recordMocker(base, sampled))
+		// A larger sample may not be possible (e.g. not enough values to pick unique ones), the records still fail
+		// when generated, if they are needed
+		if err != nil && sampled > 0 {
+			break
+		}
+		if err != nil {
+			return 0, closeRecordOutput(nil, writer, fmt.Errorf("failed to process record %d '%w'", sampled, err))
+		}
+		if err := writeRecords(writer, []any{record}, padder, nil, nil); err != nil {
+			return 0, closeRecordOutput(nil, writer, err)
+		}
+		sampled++
+	}
+	// Some formats (as csv) only write the records when closed, after the header
+	if err := writer.close(); err != nil {
+		return 0, fmt.Errorf("failed to write record '%w'", err)
+	}
+	if counter.size == 0 {
+		return 1, nil
+	}
+	return max(int(math.Ceil(float64(targetSize)*float64(sampled)/float64(counter.size))), 1), nil
+}
+
+// Reason the records of a file stop being written once it reaches --target-size (which is not a failure).
+var errTargetSizeReached = errors.New("--target-size reached")
+
+// Returns the most records generated for a --target-size estimated to be filled by `estimate` records. The records are
+// written until the file reaches the size, so the rest are only generated when the sample had smaller records.
+func targetRecordLimit(estimate int) int {
+	return 2*estimate + sizeSampleRecords
+}
+
+// Stops the records written to a file once it reaches --target-size. Most formats write each record as it comes, so
+// the bytes already in the file are used, while the records of csv/tsv and sql (written later) are measured instead.
+type targetSizer struct {
+	file       *os.File
+	measurer   recordMeasurer
+	targetSize int64
+	measured   int64
+	written    int
+}
+
+// Returns the sizer of the records written to `file`, or nil when they aren't limited (no --target-size).
+func newTargetSizer(file *os.File, writer recordWriter, targetSize int64) *targetSizer {
+	if targetSize <= 0 {
+		return nil
+	}
+	measurer, _ := writer.(recordMeasurer)
+	return &targetSizer{file: file, measurer: measurer, targetSize: targetSize}
+}
+
+// Returns errTargetSizeReached when the file already has the size, before another record is written.
+func (s *targetSizer) check() error {
+	size := s.measured
+	if s.measurer == nil {
+		position, err := s.file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return fmt.Errorf("failed to measure the file '%w'", err)
+		}
+		size = position
+	}
+	if size >= s.targetSize {
+		return errTargetSizeReached
+	}
+	return nil
+}
+
+// Counts a record written to the file.
+func (s *targetSizer) add(record any) error {
+	s.written++
+	if s.measurer == nil {
+		return nil
+	}
+	size, err := s.measurer.recordSize(record)
+	if err != nil {
+		return fmt.Errorf("failed to write record '%w'", err)
+	}
+	s.measured += size
+	return nil
+}
+
+// A file written (the manifest of its shards, when sharded), and the amount of records written to it.
+type writtenOutput struct {
+	path    string
+	records int
+}
+
+// Prints the size each file achieved, next to the --target-size.
+func reportTargetSize(out io.Writer, targetSize int64, outputs []writtenOutput) {
+	fmt.Fprintf(out, "\nTarget size:%s\n", formatSizeMetrics(targetSize))
+	for _, output := range outputs {
+		info, err := os.Stat(output.path)
+		if err != nil {
+			fmt.Fprintf(out, "  - %s: [N/A] (%d records)\n", output.path, output.records)
+			continue
+		}
+		fmt.Fprintf(out, "  - %s:%s(%d records)\n", output.path, formatSizeMetrics(info.Size()), output.records)
+	}
+}
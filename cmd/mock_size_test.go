@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockSizeTestSuite struct {
+	suite.Suite
+}
+
+func TestMockSizeTestSuite(t *testing.T) {
+	suite.Run(t, new(MockSizeTestSuite))
+}
+
+func (suite *MockSizeTestSuite) TestParseSize_ValidInputs() {
+	tests := []struct {
+		testName string
+		input    string
+		expected int64
+	}{
+		{testName: "bytes", input: "512", expected: 512},
+		{testName: "bytes with unit", input: "512B", expected: 512},
+		{testName: "kilobytes", input: "8KB", expected: 8 * kb},
+		{testName: "lowercase with space", input: "8 kb", expected: 8 * kb},
+		{testName: "decimal megabytes", input: "1.5MB", expected: 3 * mb / 2},
+		{testName: "gigabytes", input: "1GB", expected: gb},
+	}
+
+	for _, test := range tests {
+		size, err := parseSize("target-size", test.input)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", test.testName)
+		assert.Equal(suite.T(), test.expected, size, "Test case '%s' failed", test.testName)
+	}
+}
+
+func (suite *MockSizeTestSuite) TestParseSize_InvalidInputs() {
+	tests := []struct {
+		testName      string
+		input         string
+		expectedError string
+	}{
+		{
+			testName:      "unknown unit",
+			input:         "8TB",
+			expectedError: "invalid --target-size '8TB' (must be a size such as '8KB', '50MB' or '1GB')",
+		},
+		{
+			testName:      "negative",
+			input:         "-1MB",
+			expectedError: "invalid --target-size '-1MB' (must be a size such as '8KB', '50MB' or '1GB')",
+		},
+		{
+			testName:      "zero",
+			input:         "0KB",
+			expectedError: "invalid --target-size '0KB' (must be greater than 0 bytes)",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := parseSize("target-size", test.input)
+		assert.EqualError(suite.T(), err, test.expectedError, "Test case '%s' failed", test.testName)
+	}
+}
+
+func (suite *MockSizeTestSuite) TestRecordPadder() {
+	csv := csvOptions{delimiter: ',', arrays: csvArraysJoin, arraySeparator: "|", quote: csvQuoteMinimal}
+	tests := []struct {
+		testName   string
+		output     *outputOptions
+		input      string
+		recordSize int64
+	}{
+		{testName: "json", output: &outputOptions{format: formatJson}, input: `{ "name": "Ann", "tags": ["a", "b"] }`, recordSize: 100},
+		{testName: "ndjson", output: &outputOptions{format: formatNdjson}, input: `{ "name": "Ann" }`, recordSize: 100},
+		{testName: "ndjson empty object", output: &outputOptions{format: formatNdjson}, input: `{}`, recordSize: 50},
+		{testName: "csv", output: &outputOptions{format: formatCsv, csv: csv}, input: `{ "name": "Ann, Jr.", "address": { "city": "Rome" } }`, recordSize: 120},
+		{testName: "sql", output: &outputOptions{format: formatSql, sql: sqlOptions{dialect: sqlDialectPostgres, batchSize: 1}}, input: `{ "name": "Ann", "age": 30 }`, recordSize: 200},
+		{testName: "batched sql", output: &outputOptions{format: formatSql, sql: sqlOptions{dialect: sqlDialectPostgres, batchSize: 500}}, input: `{ "name": "Ann", "age": 30 }`, recordSize: 80},
+		{testName: "yaml", output: &outputOptions{format: formatYaml}, input: `{ "name": "Ann", "tags": ["a", "b"] }`, recordSize: 150},
+		{testName: "xml", output: &outputOptions{format: formatXml, xml: xmlOptions{root: "companies", item: "company", attrPrefix: "@"}}, input: `{ "@id": 1, "name": "Ann" }`, recordSize: 150},
+		{testName: "toml", output: &outputOptions{format: formatToml}, input: `{ "name": "Ann", "age": 30 }`, recordSize: 150},
+	}
+
+	for _, test := range tests {
+		record, err := engine.UnmarshalTemplate([]byte(test.input))
+		suite.Require().NoError(err, "Test case '%s' failed", test.testName)
+		padder, err := newRecordPadder(test.output, "company", test.recordSize)
+		suite.Require().NoError(err, "Test case '%s' failed", test.testName)
+		assert.NoError(suite.T(), padder.pad(record), "Test case '%s' failed", test.testName)
+		// Measured by another padder, so its writer hasn't seen the record yet
+		measurer, err := newRecordPadder(test.output, "company", test.recordSize)
+		suite.Require().NoError(err, "Test case '%s' failed", test.testName)
+		size, err := measurer.measure(record)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", test.testName)
+		assert.Equal(suite.T(), test.recordSize, size, "Test case '%s' failed", test.testName)
+	}
+}
+
+func (suite *MockSizeTestSuite) TestRecordPadder_AlreadyLarger() {
+	record, err := engine.UnmarshalTemplate([]byte(`{ "name": "Ann" }`))
+	suite.Require().NoError(err)
+	padder, err := newRecordPadder(&outputOptions{format: formatNdjson}, "company", 5)
+	suite.Require().NoError(err)
+	assert.NoError(suite.T(), padder.pad(record))
+	recordJSON, err := json.Marshal(record)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), `{"name":"Ann","_padding":""}`, string(recordJSON), "every record keeps the padding key")
+
+	assert.EqualError(suite.T(), padder.pad([]any{"raw"}), "failed to pad record (--record-size is only available for templates whose root is an object)")
+}
+
+func (suite *MockSizeTestSuite) TestNewRecordPadder_WithoutRecordSize() {
+	padder, err := newRecordPadder(&outputOptions{format: formatJson}, "company", 0)
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), padder)
+}
+
+func (suite *MockSizeTestSuite) TestEstimateRecordCount() {
+	template, err := engine.UnmarshalTemplate([]byte(`{ "name": "raw" }`))
+	suite.Require().NoError(err)
+	output := &outputOptions{format: formatNdjson}
+
+	// Each record is `{"name":"raw"}` and a new line (15 bytes)
+	count, err := estimateRecordCount(template, 1, withoutRecordMocker, output, "company", 0, 150)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 10, count)
+
+	count, err = estimateRecordCount(template, 1, withoutRecordMocker, output, "company", 100, 1000)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 10, count, "padded records are 101 bytes")
+
+	count, err = estimateRecordCount(template, 1, withoutRecordMocker, output, "company", 0, 1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, count, "at least one record is generated")
+}
+
+func (suite *MockSizeTestSuite) TestTargetSizer() {
+	tests := []struct {
+		testName        string
+		output          *outputOptions
+		expectedRecords int
+	}{
+		// Each record is `{"name":"raw"}` and a new line (15 bytes), written as it comes
+		{testName: "ndjson", output: &outputOptions{format: formatNdjson}, expectedRecords: 7},
+		// Each row is `raw` and a new line (4 bytes), measured as the rows are only written when closed
+		{testName: "csv", output: &outputOptions{format: formatCsv, csv: csvOptions{delimiter: ',', arrays: csvArraysJoin, arraySeparator: "|", quote: csvQuoteMinimal}}, expectedRecords: 25},
+	}
+
+	for _, test := range tests {
+		file, err := os.Create(filepath.Join(suite.T().TempDir(), "company"))
+		suite.Require().NoError(err, "Test case '%s' failed", test.testName)
+		writer, err := newRecordWriter(test.output, "company", file, 100)
+		suite.Require().NoError(err, "Test case '%s' failed", test.testName)
+		sizer := newTargetSizer(file, writer, 100)
+		for range 100 {
+			record, err := engine.UnmarshalTemplate([]byte(`{ "name": "raw" }`))
+			suite.Require().NoError(err, "Test case '%s' failed", test.testName)
+			if err = writeRecords(writer, []any{record}, nil, sizer, nil); err != nil {
+				assert.ErrorIs(suite.T(), err, errTargetSizeReached, "Test case '%s' failed", test.testName)
+				break
+			}
+		}
+		assert.Equal(suite.T(), test.expectedRecords, sizer.written, "Test case '%s' failed, the records stop once the file reaches the size", test.testName)
+		assert.NoError(suite.T(), closeRecordOutput(file, writer, writer.close()), "Test case '%s' failed", test.testName)
+	}
+
+	assert.Nil(suite.T(), newTargetSizer(nil, &ndjsonWriter{}, 0), "without --target-size")
+}
@@ -3,7 +3,7 @@ package cmd
 import (
 	"testing"
 
-	"github.com/lfsc09/k-test-n-stress/mocker"
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -16,296 +16,6 @@ func TestMockCmdTestSuite(t *testing.T) {
 	suite.Run(t, new(MockCmdTestSuite))
 }
 
-func (suite *MockCmdTestSuite) TestExtractMockMethod_ValidInputs() {
-	tests := []struct {
-		testName         string
-		input            string
-		expectedFuncName string
-		expectedParams   []string
-	}{
-		{
-			testName:         "empty string",
-			input:            "",
-			expectedFuncName: "",
-			expectedParams:   nil,
-		},
-		{
-			testName:         "simple mock function",
-			input:            "Address.city",
-			expectedFuncName: "Address.city",
-			expectedParams:   []string{},
-		},
-		{
-			testName:         "mock function with params",
-			input:            "Boolean.booleanWithChance:10",
-			expectedFuncName: "Boolean.booleanWithChance",
-			expectedParams:   []string{"10"},
-		},
-		{
-			testName:         "mock function with multiple params",
-			input:            "Function.with:multiple:params",
-			expectedFuncName: "Function.with",
-			expectedParams:   []string{"multiple", "params"},
-		},
-		{
-			testName:         "regex mock function with empty regex",
-			input:            "Regex.regex://",
-			expectedFuncName: "Regex.regex",
-			expectedParams:   []string{"//"},
-		},
-		{
-			testName:         "regular regex mock function",
-			input:            "Regex.regex:/[a-z0-9]{1,64}/",
-			expectedFuncName: "Regex.regex",
-			expectedParams:   []string{"/[a-z0-9]{1,64}/"},
-		},
-		{
-			testName:         "regex mock function with params",
-			input:            "Regex.regex:/[a-z0-9]{1,64}/:param2",
-			expectedFuncName: "Regex.regex",
-			expectedParams:   []string{"/[a-z0-9]{1,64}/", "param2"},
-		},
-	}
-
-	for _, tt := range tests {
-		funcName, params := extractMockMethod(tt.input)
-		assert.Equal(suite.T(), tt.expectedFuncName, funcName, "Test case '%s' failed", tt.testName)
-		assert.Equal(suite.T(), tt.expectedParams, params, "Test case '%s' failed", tt.testName)
-	}
-}
-
-func (suite *MockCmdTestSuite) TestInterpretString_ValidInputs() {
-	tests := []struct {
-		testName       string
-		input          string
-		expectedValue  string
-		expectedIsMock bool
-	}{
-		{
-			testName:       "empty string",
-			input:          "",
-			expectedValue:  "",
-			expectedIsMock: false,
-		},
-		{
-			testName:       "no whitespace",
-			input:          "{{Address.city}}",
-			expectedValue:  "Address.city",
-			expectedIsMock: true,
-		},
-		{
-			testName:       "regular whitespaces",
-			input:          "{{ Address.city }}",
-			expectedValue:  "Address.city",
-			expectedIsMock: true,
-		},
-		{
-			testName:       "multiple whitespaces at begining",
-			input:          "{{    Address.city }}",
-			expectedValue:  "Address.city",
-			expectedIsMock: true,
-		},
-		{
-			testName:       "multiple whitespaces at end",
-			input:          "{{ Address.city    }}",
-			expectedValue:  "Address.city",
-			expectedIsMock: true,
-		},
-		{
-			testName:       "multiple whitespaces at begining and end",
-			input:          "{{     Address.city    }}",
-			expectedValue:  "Address.city",
-			expectedIsMock: true,
-		},
-		{
-			testName:       "whitespaces before brackets",
-			input:          "  {{     Address.city    }}",
-			expectedValue:  "Address.city",
-			expectedIsMock: true,
-		},
-		{
-			testName:       "content but no brackets",
-			input:          "Address.city",
-			expectedValue:  "Address.city",
-			expectedIsMock: false,
-		},
-		{
-			testName:       "brackets in middle of content",
-			input:          "{{ Address.cit}}y",
-			expectedValue:  "{{ Address.cit}}y",
-			expectedIsMock: false,
-		},
-	}
-
-	for _, tt := range tests {
-		value, isMock := interpretString(tt.input)
-		assert.Equal(suite.T(), tt.expectedValue, value, "Test case '%s' failed", tt.testName)
-		assert.Equal(suite.T(), tt.expectedIsMock, isMock, "Test case '%s' failed", tt.testName)
-	}
-}
-
-func (suite *MockCmdTestSuite) TestProcessJsonMap_ValidInputs() {
-	tests := []struct {
-		testName string
-		input    map[string]any
-	}{
-		{
-			testName: "string value",
-			input: map[string]any{
-				"key": "{{ Address.city }}",
-			},
-		},
-		{
-			testName: "string value with params",
-			input: map[string]any{
-				"key": "{{ Boolean.booleanWithChance:10 }}",
-			},
-		},
-		{
-			testName: "2 string values",
-			input: map[string]any{
-				"key":  "{{ Address.city }}",
-				"key2": "{{ Address.state }}",
-			},
-		},
-		{
-			testName: "string value asking for two results",
-			input: map[string]any{
-				"key[2]": "{{ Address.city }}",
-			},
-		},
-		{
-			testName: "nested map",
-			input: map[string]any{
-				"level": map[string]any{
-					"key": "{{ Address.city }}",
-				},
-			},
-		},
-		{
-			testName: "nested map with 2 values",
-			input: map[string]any{
-				"level": map[string]any{
-					"key":  "{{ Address.city }}",
-					"key2": "{{ Address.state }}",
-				},
-			},
-		},
-		{
-			testName: "nested map asking for two objects",
-			input: map[string]any{
-				"level[2]": map[string]any{
-					"key": "{{ Address.city }}",
-				},
-			},
-		},
-		{
-			testName: "2 nested map on same level",
-			input: map[string]any{
-				"level": map[string]any{
-					"key": "{{ Address.city }}",
-				},
-				"level2": map[string]any{
-					"key": "{{ Address.city }}",
-				},
-			},
-		},
-		{
-			testName: "2 nested map, one inside the other",
-			input: map[string]any{
-				"level_0": map[string]any{
-					"key": "{{ Address.city }}",
-					"level_1": map[string]any{
-						"key": "{{ Address.city }}",
-					},
-				},
-			},
-		},
-		{
-			testName: "arrays of 2 string values",
-			input: map[string]any{
-				"array": []any{"{{ Address.city }}", "{{ Person.firstName }}"},
-			},
-		},
-		{
-			testName: "2 arrays of 2 strings values",
-			input: map[string]any{
-				"array":  []any{"{{ Address.city }}", "{{ Person.firstName }}"},
-				"array2": []any{"{{ Address.city }}", "{{ Person.firstName }}"},
-			},
-		},
-		{
-			testName: "nested map with array of strings inside",
-			input: map[string]any{
-				"level": map[string]any{
-					"key":   "{{ Address.city }}",
-					"array": []any{"{{ Address.city }}", "{{ Person.firstName }}"},
-				},
-			},
-		},
-		{
-			testName: "array of nested maps",
-			input: map[string]any{
-				"array": []any{
-					map[string]any{"key": "{{ Address.city }}"},
-					map[string]any{"key": "{{ Person.firstName }}", "key2": "{{ Person.lastName }}"},
-				},
-			},
-		},
-		{
-			testName: "array of nested maps with more maps and arrays inside",
-			input: map[string]any{
-				"array": []any{
-					map[string]any{
-						"key":   "{{ Address.city }}",
-						"array": []any{"{{ Address.city }}", "{{ Person.firstName }}"},
-					},
-					map[string]any{
-						"key":  "{{ Person.firstName }}",
-						"key2": "{{ Person.lastName }}",
-						"map": map[string]any{
-							"key":   "{{ Address.city }}",
-							"array": []any{"{{ Address.city }}", "{{ Person.firstName }}", map[string]any{"key": "{{ Person.lastName }}"}},
-						},
-					},
-				},
-			},
-		},
-	}
-
-	for _, tt := range tests {
-		mockerObj := mocker.New()
-		err := processJsonMap(tt.input, mockerObj)
-		assert.NoError(suite.T(), err, "Test case '%s' failed", tt.testName)
-	}
-}
-
-func (suite *MockCmdTestSuite) TestProcessJsonMap_InvalidInputs() {
-	tests := []struct {
-		testName string
-		input    map[string]any
-	}{
-		{
-			testName: "invalid value in map [integer value]",
-			input: map[string]any{
-				"key": 123,
-			},
-		},
-		{
-			testName: "invalid type in array [integer value]",
-			input: map[string]any{
-				"array": []any{123},
-			},
-		},
-	}
-
-	for _, tt := range tests {
-		mockerObj := mocker.New()
-		err := processJsonMap(tt.input, mockerObj)
-		assert.Error(suite.T(), err, "Test case '%s' failed", tt.testName)
-	}
-}
-
 func (suite *MockCmdTestSuite) TestExtractDigitInBrackets_ValidInputs() {
 	tests := []struct {
 		testName      string
@@ -500,51 +210,27 @@ func (suite *MockCmdTestSuite) TestExtractDigitInBrackets_InvalidInputs() {
 	}
 }
 
-func (suite *MockCmdTestSuite) TestSanitizeKeyWithBrackets_ValidInputs() {
+func (suite *MockCmdTestSuite) TestTemplateExtension() {
 	tests := []struct {
-		testName          string
-		input             string
-		expectedSanitized string
+		testName     string
+		path         string
+		isTemplate   bool
+		expectedName string
 	}{
-		{
-			testName:          "test 1",
-			input:             "",
-			expectedSanitized: "",
-		},
-		{
-			testName:          "test 2",
-			input:             "text",
-			expectedSanitized: "text",
-		},
-		{
-			testName:          "test 3",
-			input:             "text[10]",
-			expectedSanitized: "text",
-		},
-		{
-			testName:          "test 4",
-			input:             "text[10].template.json",
-			expectedSanitized: "text.template.json",
-		},
-		{
-			testName:          "test 5",
-			input:             "te[5]xt",
-			expectedSanitized: "text",
-		},
-		{
-			testName:          "test 6",
-			input:             "text10]",
-			expectedSanitized: "text10]",
-		},
-		{
-			testName:          "test 7",
-			input:             "text]1[",
-			expectedSanitized: "text]1[",
-		},
+		{testName: "json", path: "dir/company[10].template.json", isTemplate: true, expectedName: "company"},
+		{testName: "yaml", path: "dir/company[10].template.yaml", isTemplate: true, expectedName: "company"},
+		{testName: "yml with format", path: "company[10].csv.template.yml", isTemplate: true, expectedName: "company"},
+		{testName: "jsonc", path: "company.template.jsonc", isTemplate: true, expectedName: "company"},
+		{testName: "not a template", path: "company.json", isTemplate: false},
 	}
 
 	for _, tt := range tests {
-		sanitized := sanitizeKeyWithBrackets(tt.input)
-		assert.Equal(suite.T(), tt.expectedSanitized, sanitized, "Test case '%s' failed", tt.testName)
+		assert.Equal(suite.T(), tt.isTemplate, engine.IsTemplateFile(tt.path), "Test case '%s' failed", tt.testName)
+		if tt.isTemplate {
+			assert.Equal(suite.T(), tt.expectedName, templateName(tt.path), "Test case '%s' failed", tt.testName)
+			generate, err := extractDigitInBrackets("file", tt.path)
+			assert.NoError(suite.T(), err, "Test case '%s' failed", tt.testName)
+			assert.NotZero(suite.T(), generate, "Test case '%s' failed", tt.testName)
+		}
 	}
 }
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// What is compared to know whether a watched file changed.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Stats the files, leaving out the ones that don't exist (anymore).
+func snapshotFiles(paths []string) map[string]fileState {
+	snapshot := make(map[string]fileState, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		snapshot[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+	}
+	return snapshot
+}
+
+// Returns the files created, modified or removed between two snapshots.
+func changedFiles(before map[string]fileState, after map[string]fileState) map[string]bool {
+	changed := make(map[string]bool)
+	for path, state := range after {
+		if previous, ok := before[path]; !ok || previous != state {
+			changed[path] = true
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changed[path] = true
+		}
+	}
+	return changed
+}
+
+// Returns the (absolute) paths of the template files found and of the files included by the loaded templates.
+func watchedFiles(foundTemplateFiles []string, jobs []*templateJob) []string {
+	var paths []string
+	for _, inPath := range foundTemplateFiles {
+		if absPath, err := filepath.Abs(inPath); err == nil {
+			paths = append(paths, absPath)
+		}
+	}
+	for _, job := range jobs {
+		paths = append(paths, job.files...)
+	}
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
+
+// Marks the templates not affected by the changed files as unchanged, so only the others are generated again: the
+// ones whose file (or an included file) changed, the ones referencing them (directly or not, or referencing a template
+// that is gone), and the templates they reference whose records are not registered (e.g. not referenced before).
+func selectChangedJobs(jobs []*templateJob, dependencies [][]string, changed map[string]bool, registry *refRegistry) {
+	byName := make(map[string]int, len(jobs))
+	selected := make([]bool, len(jobs))
+	for idx, job := range jobs {
+		byName[job.name] = idx
+		selected[idx] = slices.ContainsFunc(job.files, func(file string) bool { return changed[file] })
+	}
+
+	for updated := true; updated; {
+		updated = false
+		for idx := range jobs {
+			if selected[idx] {
+				continue
+			}
+			for _, dependency := range dependencies[idx] {
+				if dependencyIdx, ok := byName[dependency]; !ok || selected[dependencyIdx] {
+					selected[idx], updated = true, true
+					break
+				}
+			}
+		}
+	}
+
+	for updated := true; updated; {
+		updated = false
+		for idx := range jobs {
+			if !selected[idx] {
+				continue
+			}
+			for _, dependency := range dependencies[idx] {
+				if dependencyIdx, ok := byName[dependency]; ok && !selected[dependencyIdx] && !registry.has(dependency) {
+					selected[dependencyIdx], updated = true, true
+				}
+			}
+		}
+	}
+
+	for idx, job := range jobs {
+		job.unchanged = !selected[idx]
+	}
+}
+
+// Checks the template files of --parse-files (and the files they include) every `interval` until `ctx` is done,
+// calling `regenerate` with the template files found and the files changed, whenever any of them changes.
+// `regenerate` returns the templates it loaded, so the files they now include are watched as well.
+func watchTemplateFiles(ctx context.Context, parseFiles string, interval time.Duration, jobs []*templateJob, out io.Writer, regenerate func(foundTemplateFiles []string, changed map[string]bool) []*templateJob) {
+	foundTemplateFiles, _ := findTemplateFiles(parseFiles)
+	snapshot := snapshotFiles(watchedFiles(foundTemplateFiles, jobs))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		foundTemplateFiles, err := findTemplateFiles(parseFiles)
+		if err != nil {
+			fmt.Fprintf(out, "Error: failed to find template files from the provided --parse-files '%v'\n", err)
+			continue
+		}
+		current := snapshotFiles(watchedFiles(foundTemplateFiles, jobs))
+		changed := changedFiles(snapshot, current)
+		if len(changed) == 0 {
+			continue
+		}
+		snapshot = current
+
+		fmt.Fprintf(out, "\nChanged '%s'\n", strings.Join(slices.Sorted(maps.Keys(changed)), "', '"))
+		jobs = regenerate(foundTemplateFiles, changed)
+		// Files newly included are watched from now on
+		for path, state := range snapshotFiles(watchedFiles(nil, jobs)) {
+			if _, ok := snapshot[path]; !ok {
+				snapshot[path] = state
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockWatchTestSuite struct {
+	suite.Suite
+}
+
+func TestMockWatchTestSuite(t *testing.T) {
+	suite.Run(t, new(MockWatchTestSuite))
+}
+
+func (suite *MockWatchTestSuite) TestChangedFiles() {
+	dir := suite.T().TempDir()
+	kept, modified, removed, created := filepath.Join(dir, "kept"), filepath.Join(dir, "modified"), filepath.Join(dir, "removed"), filepath.Join(dir, "created")
+	for _, path := range []string{kept, modified, removed} {
+		suite.Require().NoError(os.WriteFile(path, []byte("{}"), 0644))
+	}
+	paths := []string{kept, modified, removed, created}
+	before := snapshotFiles(paths)
+	assert.Len(suite.T(), before, 3, "missing files are left out")
+
+	suite.Require().NoError(os.WriteFile(modified, []byte("{ }"), 0644))
+	suite.Require().NoError(os.Remove(removed))
+	suite.Require().NoError(os.WriteFile(created, []byte("{}"), 0644))
+	changed := changedFiles(before, snapshotFiles(paths))
+	assert.Equal(suite.T(), map[string]bool{modified: true, removed: true, created: true}, changed)
+}
+
+func (suite *MockWatchTestSuite) TestSelectChangedJobs() {
+	// company <- employee <- payslip, building (independent), person.template.json included by employee
+	newJobs := func() []*templateJob {
+		return []*templateJob{
+			{name: "company", files: []string{"/t/company[2].template.json"}},
+			{name: "employee", files: []string{"/t/employee[5].template.json", "/t/person.template.json"}},
+			{name: "payslip", files: []string{"/t/payslip[5].template.json"}},
+			{name: "building", files: []string{"/t/building[1].template.json"}},
+		}
+	}
+	dependencies := [][]string{nil, {"company"}, {"employee"}, nil}
+	registered := newRefRegistry()
+	registered.add("company", []any{})
+	registered.add("employee", []any{})
+
+	tests := []struct {
+		testName     string
+		dependencies [][]string
+		changed      []string
+		registry     *refRegistry
+		expected     []string
+	}{
+		{
+			testName:     "changed template and its dependents",
+			dependencies: dependencies,
+			changed:      []string{"/t/company[2].template.json"},
+			registry:     registered,
+			expected:     []string{"company", "employee", "payslip"},
+		},
+		{
+			testName:     "changed included file",
+			dependencies: dependencies,
+			changed:      []string{"/t/person.template.json"},
+			registry:     registered,
+			expected:     []string{"employee", "payslip"},
+		},
+		{
+			testName:     "independent template",
+			dependencies: dependencies,
+			changed:      []string{"/t/building[1].template.json"},
+			registry:     registered,
+			expected:     []string{"building"},
+		},
+		{
+			testName:     "referenced template not registered",
+			dependencies: dependencies,
+			changed:      []string{"/t/payslip[5].template.json"},
+			registry:     newRefRegistry(),
+			expected:     []string{"company", "employee", "payslip"},
+		},
+		{
+			testName:     "referenced template removed",
+			dependencies: [][]string{nil, {"company"}, {"employee"}, {"warehouse"}},
+			changed:      []string{"/t/warehouse[1].template.json"},
+			registry:     registered,
+			expected:     []string{"building"},
+		},
+	}
+
+	for _, test := range tests {
+		jobs := newJobs()
+		changed := make(map[string]bool)
+		for _, path := range test.changed {
+			changed[path] = true
+		}
+		selectChangedJobs(jobs, test.dependencies, changed, test.registry)
+		var selected []string
+		for _, job := range jobs {
+			if !job.unchanged {
+				selected = append(selected, job.name)
+			}
+		}
+		assert.Equal(suite.T(), test.expected, selected, "Test case '%s' failed", test.testName)
+	}
+}
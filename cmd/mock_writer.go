@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+)
+
+// Output formats of the generated records.
+const (
+	formatJson   = "json"
+	formatNdjson = "ndjson"
+	formatCsv    = "csv"
+	formatTsv    = "tsv"
+	formatSql    = "sql"
+	formatYaml   = "yaml"
+	formatXml    = "xml"
+	formatToml   = "toml"
+)
+
+// Writes the generated records one at a time, so they don't need to be kept in memory.
+type recordWriter interface {
+	// Writes the next record.
+	write(record any) error
+	// Finishes the output, after every record was written.
+	close() error
+}
+
+// Implemented by the writers holding more than their output (e.g. the spooled rows of csv/tsv), releasing it without
+// writing anything when the generation fails.
+type discardableWriter interface {
+	discard()
+}
+
+// Returns the writer of the output format, writing `total` records of the template `name` to `out`.
+func newRecordWriter(output *outputOptions, name string, out io.Writer, total int) (recordWriter, error) {
+	switch output.format {
+	case formatJson:
+		return &jsonArrayWriter{out: out, total: total}, nil
+	case formatNdjson:
+		return &ndjsonWriter{out: out}, nil
+	case formatCsv, formatTsv:
+		return newCsvWriter(out, output.csv), nil
+	case formatSql:
+		return newSqlWriter(out, output.sql, name), nil
+	case formatYaml:
+		return &yamlWriter{out: out, total: total}, nil
+	case formatXml:
+		return &xmlWriter{out: out, options: output.xml}, nil
+	case formatToml:
+		return &tomlWriter{out: out, total: total, table: strings.ReplaceAll(name, "-", "_")}, nil
+	default:
+		return nil, fmt.Errorf("invalid format '%s' (must be one of '%s')", output.format, strings.Join(outputFormats(), "', '"))
+	}
+}
+
+// Returns the available output formats.
+func outputFormats() []string {
+	return []string{formatJson, formatNdjson, formatCsv, formatTsv, formatSql, formatYaml, formatXml, formatToml}
+}
+
+// Returns the extension of the files written in a format (e.g. ".json").
+func formatExtension(format string) string {
+	return "." + format
+}
+
+// Returns the format a template file asks for in its name (e.g. "company[10].yaml.template.json"), or `fallback` when it doesn't.
+func templateFormat(inPath string, fallback string) string {
+	extension := filepath.Ext(engine.TrimTemplateExtension(filepath.Base(inPath)))
+	for _, format := range outputFormats() {
+		if extension == formatExtension(format) {
+			return format
+		}
+	}
+	return fallback
+}
+
+// Writes the records as an indented JSON array, or as a single object when there is only one record.
+type jsonArrayWriter struct {
+	out     io.Writer
+	total   int
+	written int
+}
+
+func (w *jsonArrayWriter) write(record any) error {
+	var buf bytes.Buffer
+	prefix := ""
+	if w.total > 1 {
+		prefix = "  "
+		if w.written == 0 {
+			buf.WriteString("[\n")
+		} else {
+			buf.WriteString(",\n")
+		}
+		buf.WriteString(prefix)
+	}
+	prettyJSON, err := json.MarshalIndent(record, prefix, "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling JSON '%w'", err)
+	}
+	buf.Write(prettyJSON)
+	w.written++
+	_, err = w.out.Write(buf.Bytes())
+	return err
+}
+
+func (w *jsonArrayWriter) close() error {
+	closing := ""
+	if w.total > 1 {
+		closing = "\n]"
+	}
+	_, err := io.WriteString(w.out, closing+"\n")
+	return err
+}
+
+// Writes each record as a compact JSON object in its own line (newline delimited JSON).
+type ndjsonWriter struct {
+	out io.Writer
+}
+
+func (w *ndjsonWriter) write(record any) error {
+	compactJSON, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("error marshalling JSON '%w'", err)
+	}
+	_, err = w.out.Write(append(compactJSON, '\n'))
+	return err
+}
+
+func (w *ndjsonWriter) close() error {
+	return nil
+}
@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+)
+
+// How arrays of the records are written in the columns of csv/tsv.
+const (
+	csvArraysJoin    = "join"
+	csvArraysIndex   = "index"
+	csvArraysExplode = "explode"
+)
+
+// Which fields of csv/tsv are quoted.
+const (
+	csvQuoteMinimal = "minimal"
+	csvQuoteAll     = "all"
+)
+
+// Column of a record that is not an object (e.g. a template with an array root, written with `--csv-arrays join`).
+const csvValueColumn = "value"
+
+type csvOptions struct {
+	delimiter      rune
+	arrays         string
+	arraySeparator string
+	quote          string
+}
+
+// Returns the available array modes of csv/tsv.
+func csvArrayModes() []string {
+	return []string{csvArraysJoin, csvArraysIndex, csvArraysExplode}
+}
+
+// Returns the available quote modes of csv/tsv.
+func csvQuoteModes() []string {
+	return []string{csvQuoteMinimal, csvQuoteAll}
+}
+
+// A row of csv/tsv, with its columns in the order they were found.
+type csvRow struct {
+	columns []string
+	values  map[string]string
+}
+
+func (r csvRow) with(other csvRow) csvRow {
+	merged := csvRow{
+		columns: append(append([]string(nil), r.columns...), other.columns...),
+		values:  make(map[string]string, len(r.values)+len(other.values)),
+	}
+	for column, value := range r.values {
+		merged.values[column] = value
+	}
+	for column, value := range other.values {
+		merged.values[column] = value
+	}
+	return merged
+}
+
+// Writes the records as rows of csv/tsv, with nested objects flattened into dotted column names (e.g. "address.city").
+// The header must have every column of every record, so the rows are spooled to a temporary file as they are written,
+// and only copied to the output in `close`, after the header (with the columns in the order they first appear, the
+// key order of the template). Only the columns are kept in memory.
+type csvWriter struct {
+	out     io.Writer
+	options csvOptions
+	columns []string
+	known   map[string]int
+	spool   *os.File
+	spooled *bufio.Writer
+	encoder *json.Encoder
+}
+
+func newCsvWriter(out io.Writer, options csvOptions) *csvWriter {
+	return &csvWriter{out: out, options: options, known: make(map[string]int)}
+}
+
+func (w *csvWriter) write(record any) error {
+	rows, err := w.flatten("", record)
+	if err != nil {
+		return err
+	}
+	if w.spool == nil {
+		w.spool, err = os.CreateTemp("", "ktns-*.spool")
+		if err != nil {
+			return fmt.Errorf("failed to spool the rows '%w'", err)
+		}
+		w.spooled = bufio.NewWriter(w.spool)
+		w.encoder = json.NewEncoder(w.spooled)
+	}
+	for _, row := range rows {
+		for _, column := range row.columns {
+			if _, ok := w.known[column]; !ok {
+				w.known[column] = len(w.columns)
+				w.columns = append(w.columns, column)
+			}
+		}
+		// The fields are spooled by the index of their columns, the columns found later are only appended
+		fields := make([]string, len(w.columns))
+		for column, value := range row.values {
+			fields[w.known[column]] = value
+		}
+		if err := w.encoder.Encode(fields); err != nil {
+			return fmt.Errorf("failed to spool the rows '%w'", err)
+		}
+	}
+	return nil
+}
+
+func (w *csvWriter) close() error {
+	defer w.discard()
+	if err := w.writeLine(w.columns); err != nil {
+		return err
+	}
+	if w.spool == nil {
+		return nil
+	}
+	if err := w.spooled.Flush(); err != nil {
+		return fmt.Errorf("failed to spool the rows '%w'", err)
+	}
+	if _, err := w.spool.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to spool the rows '%w'", err)
+	}
+	decoder := json.NewDecoder(bufio.NewReader(w.spool))
+	for {
+		var fields []string
+		if err := decoder.Decode(&fields); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to spool the rows '%w'", err)
+		}
+		// Rows spooled before the last columns were found miss their fields
+		fields = append(fields, make([]string, len(w.columns)-len(fields))...)
+		if err := w.writeLine(fields); err != nil {
+			return err
+		}
+	}
+}
+
+// Removes the spooled rows, without writing them (when the generation fails).
+func (w *csvWriter) discard() {
+	if w.spool == nil {
+		return
+	}
+	w.spool.Close()
+	os.Remove(w.spool.Name())
+	w.spool, w.spooled, w.encoder = nil, nil, nil
+}
+
+// Returns the bytes of the rows a record is written as, each with the fields of its own columns (the columns of the
+// other records are left out, so the rows are measured before every column is known).
+func (w *csvWriter) recordSize(record any) (int64, error) {
+	rows, err := w.flatten("", record)
+	if err != nil {
+		return 0, err
+	}
+	counter := &countingWriter{}
+	lines := &csvWriter{out: counter, options: w.options}
+	for _, row := range rows {
+		fields := make([]string, len(row.columns))
+		for idx, column := range row.columns {
+			fields[idx] = row.values[column]
+		}
+		if err := lines.writeLine(fields); err != nil {
+			return 0, err
+		}
+	}
+	return counter.size, nil
+}
+
+// Flattens a value into the rows it is written as. Only exploded arrays make more than one row.
+func (w *csvWriter) flatten(column string, value any) ([]csvRow, error) {
+	switch typedValue := value.(type) {
+	case *engine.OrderedMap:
+		if typedValue.Size() == 0 {
+			return w.flattenScalar(column, "")
+		}
+		rows := []csvRow{{}}
+		for _, objKey := range typedValue.Keys() {
+			objValue, _ := typedValue.Get(objKey)
+			childRows, err := w.flatten(joinColumn(column, objKey), objValue)
+			if err != nil {
+				return nil, err
+			}
+			rows = combineRows(rows, childRows)
+		}
+		return rows, nil
+	case []any:
+		if len(typedValue) == 0 {
+			return w.flattenScalar(column, "")
+		}
+		switch w.options.arrays {
+		case csvArraysIndex:
+			rows := []csvRow{{}}
+			for itemKey, item := range typedValue {
+				childRows, err := w.flatten(joinColumn(column, strconv.Itoa(itemKey)), item)
+				if err != nil {
+					return nil, err
+				}
+				rows = combineRows(rows, childRows)
+			}
+			return rows, nil
+		case csvArraysExplode:
+			var rows []csvRow
+			for _, item := range typedValue {
+				childRows, err := w.flatten(column, item)
+				if err != nil {
+					return nil, err
+				}
+				rows = append(rows, childRows...)
+			}
+			return rows, nil
+		default:
+			items := make([]string, len(typedValue))
+			for itemKey, item := range typedValue {
+				field, err := csvField(item)
+				if err != nil {
+					return nil, err
+				}
+				items[itemKey] = field
+			}
+			return w.flattenScalar(column, strings.Join(items, w.options.arraySeparator))
+		}
+	default:
+		field, err := csvField(value)
+		if err != nil {
+			return nil, err
+		}
+		return w.flattenScalar(column, field)
+	}
+}
+
+func (w *csvWriter) flattenScalar(column string, field string) ([]csvRow, error) {
+	if column == "" {
+		column = csvValueColumn
+	}
+	return []csvRow{{columns: []string{column}, values: map[string]string{column: field}}}, nil
+}
+
+// Writes a line of fields, quoting the ones that need it (or every one with `--csv-quote all`).
+func (w *csvWriter) writeLine(fields []string) error {
+	var line strings.Builder
+	for idx, field := range fields {
+		if idx > 0 {
+			line.WriteRune(w.options.delimiter)
+		}
+		if w.options.quote == csvQuoteAll || w.needsQuotes(field) {
+			line.WriteString(`"` + strings.ReplaceAll(field, `"`, `""`) + `"`)
+		} else {
+			line.WriteString(field)
+		}
+	}
+	line.WriteString("\n")
+	_, err := io.WriteString(w.out, line.String())
+	return err
+}
+
+func (w *csvWriter) needsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	return strings.ContainsRune(field, w.options.delimiter) || strings.ContainsAny(field, "\"\r\n") || field[0] == ' ' || field[0] == '\t'
+}
+
+// Returns the text of a value in a field. Strings are written as is, and every other value as JSON (e.g. objects inside joined arrays).
+func csvField(value any) (string, error) {
+	switch typedValue := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return typedValue, nil
+	default:
+		encoded, err := json.Marshal(typedValue)
+		if err != nil {
+			return "", fmt.Errorf("error marshalling JSON '%w'", err)
+		}
+		return string(encoded), nil
+	}
+}
+
+func joinColumn(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// Combines every row of `rows` with every row of `childRows` (exploding two arrays of a record writes every combination).
+func combineRows(rows []csvRow, childRows []csvRow) []csvRow {
+	combined := make([]csvRow, 0, len(rows)*len(childRows))
+	for _, row := range rows {
+		for _, childRow := range childRows {
+			combined = append(combined, row.with(childRow))
+		}
+	}
+	return combined
+}
@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+)
+
+// SQL dialects of `--format sql`.
+const (
+	sqlDialectPostgres = "postgres"
+	sqlDialectMysql    = "mysql"
+	sqlDialectSqlite   = "sqlite"
+	sqlDialectMssql    = "mssql"
+)
+
+// SQL Server doesn't accept more rows than this in a single INSERT.
+const mssqlMaxBatchSize = 1000
+
+type sqlOptions struct {
+	table       string
+	dialect     string
+	batchSize   int
+	transaction bool
+}
+
+// Returns the available SQL dialects.
+func sqlDialects() []string {
+	return []string{sqlDialectPostgres, sqlDialectMysql, sqlDialectSqlite, sqlDialectMssql}
+}
+
+// Returns the table the records of a template are inserted into, `--table` or the name of the template.
+func sqlTable(options sqlOptions, name string) string {
+	if options.table != "" {
+		return options.table
+	}
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// Writes the records as INSERT statements, a statement for every `batchSize` records.
+// The columns of a statement are the keys of its records (missing ones are NULL), and nested objects and arrays are written as JSON.
+type sqlWriter struct {
+	out     io.Writer
+	options sqlOptions
+	table   string
+	batch   []*engine.OrderedMap
+	started bool
+}
+
+func newSqlWriter(out io.Writer, options sqlOptions, name string) *sqlWriter {
+	return &sqlWriter{out: out, options: options, table: sqlTable(options, name)}
+}
+
+func (w *sqlWriter) write(record any) error {
+	recordMap, ok := record.(*engine.OrderedMap)
+	if !ok {
+		return fmt.Errorf("--format sql needs every record to be an object, got '%T'", record)
+	}
+	if err := w.begin(); err != nil {
+		return err
+	}
+	w.batch = append(w.batch, recordMap)
+	if len(w.batch) < w.options.batchSize {
+		return nil
+	}
+	return w.flush()
+}
+
+func (w *sqlWriter) close() error {
+	if err := w.begin(); err != nil {
+		return err
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	if !w.options.transaction {
+		return nil
+	}
+	_, err := io.WriteString(w.out, w.commitStatement()+"\n")
+	return err
+}
+
+// Returns the bytes a record adds to the INSERT statements: its row, and its share of the statement of its batch
+// (rounded down, so the records of a file never measure more than the file).
+func (w *sqlWriter) recordSize(record any) (int64, error) {
+	recordMap, ok := record.(*engine.OrderedMap)
+	if !ok {
+		return 0, fmt.Errorf("--format sql needs every record to be an object, got '%T'", record)
+	}
+	quotedColumns := make([]string, 0, recordMap.Size())
+	literals := make([]string, 0, recordMap.Size())
+	for _, column := range recordMap.Keys() {
+		value, _ := recordMap.Get(column)
+		literal, err := w.literal(value)
+		if err != nil {
+			return 0, err
+		}
+		quotedColumns = append(quotedColumns, w.quoteIdentifier(column))
+		literals = append(literals, literal)
+	}
+	header := fmt.Sprintf("INSERT INTO %s (%s) VALUES\n", w.quoteTable(w.table), strings.Join(quotedColumns, ", "))
+	row := fmt.Sprintf("  (%s),\n", strings.Join(literals, ", "))
+	batchSize := max(w.options.batchSize, 1)
+	return int64(len(row) + len(header)/batchSize), nil
+}
+
+// Starts the transaction (when asked), before the first statement.
+func (w *sqlWriter) begin() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	if !w.options.transaction {
+		return nil
+	}
+	_, err := io.WriteString(w.out, w.beginStatement()+"\n")
+	return err
+}
+
+// Writes the INSERT statement of the records in the batch.
+func (w *sqlWriter) flush() error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	var columns []string
+	known := make(map[string]bool)
+	for _, recordMap := range w.batch {
+		for _, objKey := range recordMap.Keys() {
+			if !known[objKey] {
+				known[objKey] = true
+				columns = append(columns, objKey)
+			}
+		}
+	}
+
+	var statement strings.Builder
+	quotedColumns := make([]string, len(columns))
+	for idx, column := range columns {
+		quotedColumns[idx] = w.quoteIdentifier(column)
+	}
+	fmt.Fprintf(&statement, "INSERT INTO %s (%s) VALUES\n", w.quoteTable(w.table), strings.Join(quotedColumns, ", "))
+	for rowIdx, recordMap := range w.batch {
+		literals := make([]string, len(columns))
+		for idx, column := range columns {
+			value, ok := recordMap.Get(column)
+			if !ok {
+				literals[idx] = "NULL"
+				continue
+			}
+			literal, err := w.literal(value)
+			if err != nil {
+				return err
+			}
+			literals[idx] = literal
+		}
+		separator := ","
+		if rowIdx == len(w.batch)-1 {
+			separator = ";"
+		}
+		fmt.Fprintf(&statement, "  (%s)%s\n", strings.Join(literals, ", "), separator)
+	}
+	w.batch = w.batch[:0]
+	_, err := io.WriteString(w.out, statement.String())
+	return err
+}
+
+func (w *sqlWriter) beginStatement() string {
+	switch w.options.dialect {
+	case sqlDialectMysql:
+		return "START TRANSACTION;"
+	case sqlDialectMssql:
+		return "BEGIN TRANSACTION;"
+	default:
+		return "BEGIN;"
+	}
+}
+
+func (w *sqlWriter) commitStatement() string {
+	if w.options.dialect == sqlDialectMssql {
+		return "COMMIT TRANSACTION;"
+	}
+	return "COMMIT;"
+}
+
+// Quotes a table name, each part of it when it has a schema (e.g. "public.company").
+func (w *sqlWriter) quoteTable(table string) string {
+	parts := strings.Split(table, ".")
+	for idx, part := range parts {
+		parts[idx] = w.quoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+func (w *sqlWriter) quoteIdentifier(identifier string) string {
+	switch w.options.dialect {
+	case sqlDialectMysql:
+		return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+	case sqlDialectMssql:
+		return "[" + strings.ReplaceAll(identifier, "]", "]]") + "]"
+	default:
+		return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+	}
+}
+
+// Returns the SQL literal of a value. Objects and arrays are written as JSON text, to be inserted in JSON columns.
+func (w *sqlWriter) literal(value any) (string, error) {
+	switch typedValue := value.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return w.quoteString(typedValue), nil
+	case bool:
+		if w.options.dialect == sqlDialectSqlite || w.options.dialect == sqlDialectMssql {
+			if typedValue {
+				return "1", nil
+			}
+			return "0", nil
+		}
+		if typedValue {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case *engine.OrderedMap, []any:
+		encoded, err := json.Marshal(typedValue)
+		if err != nil {
+			return "", fmt.Errorf("error marshalling JSON '%w'", err)
+		}
+		return w.quoteString(string(encoded)), nil
+	default:
+		encoded, err := json.Marshal(typedValue)
+		if err != nil {
+			return "", fmt.Errorf("error marshalling JSON '%w'", err)
+		}
+		return string(encoded), nil
+	}
+}
+
+func (w *sqlWriter) quoteString(text string) string {
+	switch w.options.dialect {
+	case sqlDialectMysql:
+		// MySQL also treats backslashes as escapes in string literals (unless NO_BACKSLASH_ESCAPES is set)
+		return "'" + strings.ReplaceAll(strings.ReplaceAll(text, `\`, `\\`), "'", "''") + "'"
+	case sqlDialectMssql:
+		return "N'" + strings.ReplaceAll(text, "'", "''") + "'"
+	default:
+		return "'" + strings.ReplaceAll(text, "'", "''") + "'"
+	}
+}
@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockWriterTestSuite struct {
+	suite.Suite
+}
+
+func TestMockWriterTestSuite(t *testing.T) {
+	suite.Run(t, new(MockWriterTestSuite))
+}
+
+func (suite *MockWriterTestSuite) TestRecordWriter_Formats() {
+	tests := []struct {
+		testName string
+		format   string
+		records  []any
+		expected func(records []any) string
+	}{
+		{
+			testName: "json with many records is an indented array",
+			format:   formatJson,
+			records: []any{
+				toOrderedValue(map[string]any{"name": "a", "tags": []any{"x"}}),
+				toOrderedValue(map[string]any{"name": "b", "tags": []any{}}),
+			},
+			expected: func(records []any) string {
+				content, _ := json.MarshalIndent(records, "", "  ")
+				return string(content) + "\n"
+			},
+		},
+		{
+			testName: "json with a single record is not wrapped in an array",
+			format:   formatJson,
+			records:  []any{toOrderedValue(map[string]any{"name": "a"})},
+			expected: func(records []any) string {
+				content, _ := json.MarshalIndent(records[0], "", "  ")
+				return string(content) + "\n"
+			},
+		},
+		{
+			testName: "ndjson writes a compact record per line",
+			format:   formatNdjson,
+			records: []any{
+				toOrderedValue(map[string]any{"name": "a", "nested": map[string]any{"key": 1}}),
+				toOrderedValue(map[string]any{"name": "b"}),
+			},
+			expected: func(records []any) string {
+				return "{\"name\":\"a\",\"nested\":{\"key\":1}}\n{\"name\":\"b\"}\n"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		writer, err := newRecordWriter(&outputOptions{format: tt.format}, "company", &out, len(tt.records))
+		suite.Require().NoError(err, "Test case '%s' failed", tt.testName)
+		for _, record := range tt.records {
+			assert.NoError(suite.T(), writer.write(record), "Test case '%s' failed", tt.testName)
+		}
+		assert.NoError(suite.T(), writer.close(), "Test case '%s' failed", tt.testName)
+		assert.Equal(suite.T(), tt.expected(tt.records), out.String(), "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *MockWriterTestSuite) TestRecordWriter_InvalidFormat() {
+	_, err := newRecordWriter(&outputOptions{format: "parquet"}, "company", &bytes.Buffer{}, 1)
+	assert.EqualError(suite.T(), err, "invalid format 'parquet' (must be one of 'json', 'ndjson', 'csv', 'tsv', 'sql', 'yaml', 'xml', 'toml')")
+}
+
+func (suite *MockWriterTestSuite) TestOutputName() {
+	assert.Equal(suite.T(), "company[10].ndjson", outputName("company[10].template.json", formatNdjson))
+	assert.Equal(suite.T(), "nested/company.json", outputName("nested/company.template.json", formatJson))
+	assert.Equal(suite.T(), "mocked-data.json", outputName("mocked-data", formatJson))
+}
+
+func (suite *MockWriterTestSuite) TestCsvWriter() {
+	records := []any{
+		toOrderedValue(map[string]any{
+			"address": map[string]any{"city": "Rio, RJ", "zip": "1"},
+			"items":   []any{map[string]any{"k": "1"}, map[string]any{"k": "2"}},
+			"name":    "Ann \"A\"",
+			"tags":    []any{"a", "b"},
+		}),
+		toOrderedValue(map[string]any{
+			"extra": true,
+			"name":  "Bob",
+			"tags":  []any{},
+		}),
+	}
+	tests := []struct {
+		testName string
+		options  csvOptions
+		expected string
+	}{
+		{
+			testName: "arrays joined in a single column",
+			options:  csvOptions{delimiter: ',', arrays: csvArraysJoin, arraySeparator: "|", quote: csvQuoteMinimal},
+			expected: "address.city,address.zip,items,name,tags,extra\n" +
+				"\"Rio, RJ\",1,\"{\"\"k\"\":\"\"1\"\"}|{\"\"k\"\":\"\"2\"\"}\",\"Ann \"\"A\"\"\",a|b,\n" +
+				",,,Bob,,true\n",
+		},
+		{
+			testName: "arrays in a column per item",
+			options:  csvOptions{delimiter: ';', arrays: csvArraysIndex, arraySeparator: "|", quote: csvQuoteMinimal},
+			expected: "address.city;address.zip;items.0.k;items.1.k;name;tags.0;tags.1;extra;tags\n" +
+				"Rio, RJ;1;1;2;\"Ann \"\"A\"\"\";a;b;;\n" +
+				";;;;Bob;;;true;\n",
+		},
+		{
+			testName: "arrays exploded in a row per item",
+			options:  csvOptions{delimiter: '\t', arrays: csvArraysExplode, arraySeparator: "|", quote: csvQuoteAll},
+			expected: "\"address.city\"\t\"address.zip\"\t\"items.k\"\t\"name\"\t\"tags\"\t\"extra\"\n" +
+				"\"Rio, RJ\"\t\"1\"\t\"1\"\t\"Ann \"\"A\"\"\"\t\"a\"\t\"\"\n" +
+				"\"Rio, RJ\"\t\"1\"\t\"1\"\t\"Ann \"\"A\"\"\"\t\"b\"\t\"\"\n" +
+				"\"Rio, RJ\"\t\"1\"\t\"2\"\t\"Ann \"\"A\"\"\"\t\"a\"\t\"\"\n" +
+				"\"Rio, RJ\"\t\"1\"\t\"2\"\t\"Ann \"\"A\"\"\"\t\"b\"\t\"\"\n" +
+				"\"\"\t\"\"\t\"\"\t\"Bob\"\t\"\"\t\"true\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		writer, err := newRecordWriter(&outputOptions{format: formatCsv, csv: tt.options}, "company", &out, len(records))
+		suite.Require().NoError(err, "Test case '%s' failed", tt.testName)
+		for _, record := range records {
+			assert.NoError(suite.T(), writer.write(engine.CloneValue(record)), "Test case '%s' failed", tt.testName)
+		}
+		assert.NoError(suite.T(), writer.close(), "Test case '%s' failed", tt.testName)
+		assert.Equal(suite.T(), tt.expected, out.String(), "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *MockWriterTestSuite) TestCsvWriter_SpoolsTheRows() {
+	options := csvOptions{delimiter: ',', arrays: csvArraysJoin, arraySeparator: "|", quote: csvQuoteMinimal}
+	var out bytes.Buffer
+	writer := newCsvWriter(&out, options)
+	suite.Require().NoError(writer.write(toOrderedValue(map[string]any{"note": "a\r\nb"})))
+	suite.Require().NotNil(writer.spool, "the rows are spooled as they are written")
+	spoolPath := writer.spool.Name()
+	assert.Empty(suite.T(), out.String(), "nothing is written before the header is known")
+	suite.Require().NoError(writer.write(toOrderedValue(map[string]any{"name": "Bob"})))
+
+	assert.NoError(suite.T(), writer.close())
+	assert.Equal(suite.T(), "note,name\n\"a\r\nb\",\n,Bob\n", out.String())
+	assert.NoFileExists(suite.T(), spoolPath, "the spool is removed once copied")
+
+	writer = newCsvWriter(&out, options)
+	suite.Require().NoError(writer.write(toOrderedValue(map[string]any{"name": "Ann"})))
+	spoolPath = writer.spool.Name()
+	writer.discard()
+	assert.NoFileExists(suite.T(), spoolPath, "the spool is removed when discarded")
+}
+
+func (suite *MockWriterTestSuite) TestSqlWriter() {
+	records := []any{
+		toOrderedValue(map[string]any{"address": map[string]any{"city": "Rio"}, "name": "O'Brien \\ Jr", "tags": []any{"a"}}),
+		toOrderedValue(map[string]any{"active": true, "name": "Bob"}),
+		toOrderedValue(map[string]any{"name": nil}),
+	}
+	tests := []struct {
+		testName string
+		options  sqlOptions
+		expected string
+	}{
+		{
+			testName: "postgres in batches",
+			options:  sqlOptions{dialect: sqlDialectPostgres, batchSize: 2},
+			expected: "INSERT INTO \"company\" (\"address\", \"name\", \"tags\", \"active\") VALUES\n" +
+				"  ('{\"city\":\"Rio\"}', 'O''Brien \\ Jr', '[\"a\"]', NULL),\n" +
+				"  (NULL, 'Bob', NULL, TRUE);\n" +
+				"INSERT INTO \"company\" (\"name\") VALUES\n" +
+				"  (NULL);\n",
+		},
+		{
+			testName: "mysql in a transaction",
+			options:  sqlOptions{table: "app.companies", dialect: sqlDialectMysql, batchSize: 100, transaction: true},
+			expected: "START TRANSACTION;\n" +
+				"INSERT INTO `app`.`companies` (`address`, `name`, `tags`, `active`) VALUES\n" +
+				"  ('{\"city\":\"Rio\"}', 'O''Brien \\\\ Jr', '[\"a\"]', NULL),\n" +
+				"  (NULL, 'Bob', NULL, TRUE),\n" +
+				"  (NULL, NULL, NULL, NULL);\n" +
+				"COMMIT;\n",
+		},
+		{
+			testName: "sqlite",
+			options:  sqlOptions{dialect: sqlDialectSqlite, batchSize: 3},
+			expected: "INSERT INTO \"company\" (\"address\", \"name\", \"tags\", \"active\") VALUES\n" +
+				"  ('{\"city\":\"Rio\"}', 'O''Brien \\ Jr', '[\"a\"]', NULL),\n" +
+				"  (NULL, 'Bob', NULL, 1),\n" +
+				"  (NULL, NULL, NULL, NULL);\n",
+		},
+		{
+			testName: "mssql in a transaction",
+			options:  sqlOptions{table: "my]table", dialect: sqlDialectMssql, batchSize: 3, transaction: true},
+			expected: "BEGIN TRANSACTION;\n" +
+				"INSERT INTO [my]]table] ([address], [name], [tags], [active]) VALUES\n" +
+				"  (N'{\"city\":\"Rio\"}', N'O''Brien \\ Jr', N'[\"a\"]', NULL),\n" +
+				"  (NULL, N'Bob', NULL, 1),\n" +
+				"  (NULL, NULL, NULL, NULL);\n" +
+				"COMMIT TRANSACTION;\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		writer, err := newRecordWriter(&outputOptions{format: formatSql, sql: tt.options}, "company", &out, len(records))
+		suite.Require().NoError(err, "Test case '%s' failed", tt.testName)
+		for _, record := range records {
+			assert.NoError(suite.T(), writer.write(record), "Test case '%s' failed", tt.testName)
+		}
+		assert.NoError(suite.T(), writer.close(), "Test case '%s' failed", tt.testName)
+		assert.Equal(suite.T(), tt.expected, out.String(), "Test case '%s' failed", tt.testName)
+	}
+
+	writer, err := newRecordWriter(&outputOptions{format: formatSql, sql: sqlOptions{dialect: sqlDialectPostgres, batchSize: 1}}, "company", &bytes.Buffer{}, 1)
+	suite.Require().NoError(err)
+	assert.EqualError(suite.T(), writer.write([]any{"a"}), "--format sql needs every record to be an object, got '[]interface {}'")
+}
+
+func (suite *MockWriterTestSuite) TestMarkupWriters() {
+	records := []any{
+		toOrderedValue(map[string]any{
+			"@id":     "1",
+			"address": map[string]any{"city": "Rio & Co"},
+			"items":   []any{map[string]any{"k": "a"}, map[string]any{"k": "b"}},
+			"name":    "Ann \"A\"",
+			"none":    nil,
+			"tags":    []any{"x", "true"},
+		}),
+		toOrderedValue(map[string]any{"name": "Bob"}),
+	}
+	tests := []struct {
+		testName string
+		output   outputOptions
+		records  []any
+		expected string
+	}{
+		{
+			testName: "yaml with many records is a sequence",
+			output:   outputOptions{format: formatYaml},
+			records:  records,
+			expected: "- '@id': \"1\"\n  address:\n    city: Rio & Co\n  items:\n    - k: a\n    - k: b\n  name: Ann \"A\"\n  none: null\n  tags:\n    - x\n    - \"true\"\n" +
+				"- name: Bob\n",
+		},
+		{
+			testName: "yaml with a single record is not wrapped in a sequence",
+			output:   outputOptions{format: formatYaml},
+			records:  records[1:],
+			expected: "name: Bob\n",
+		},
+		{
+			testName: "xml with attributes and repeated elements",
+			output:   outputOptions{format: formatXml, xml: xmlOptions{root: "companies", item: "company", attrPrefix: "@"}},
+			records:  records,
+			expected: "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<companies>\n" +
+				"  <company id=\"1\">\n" +
+				"    <address>\n      <city>Rio &amp; Co</city>\n    </address>\n" +
+				"    <items>\n      <k>a</k>\n    </items>\n    <items>\n      <k>b</k>\n    </items>\n" +
+				"    <name>Ann &#34;A&#34;</name>\n    <none></none>\n    <tags>x</tags>\n    <tags>true</tags>\n" +
+				"  </company>\n" +
+				"  <company>\n    <name>Bob</name>\n  </company>\n" +
+				"</companies>\n",
+		},
+		{
+			testName: "toml with many records is an array of tables",
+			output:   outputOptions{format: formatToml},
+			records:  records,
+			expected: "[[company]]\n\"@id\" = \"1\"\nname = \"Ann \\\"A\\\"\"\ntags = [\"x\", \"true\"]\n\n" +
+				"[company.address]\ncity = \"Rio & Co\"\n\n[[company.items]]\nk = \"a\"\n\n[[company.items]]\nk = \"b\"\n\n" +
+				"[[company]]\nname = \"Bob\"\n",
+		},
+		{
+			testName: "toml with a single record is the document",
+			output:   outputOptions{format: formatToml},
+			records:  []any{toOrderedValue(map[string]any{"address": map[string]any{"city": "Rio"}, "name": "Bob"})},
+			expected: "name = \"Bob\"\n\n[address]\ncity = \"Rio\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		writer, err := newRecordWriter(&tt.output, "company", &out, len(tt.records))
+		suite.Require().NoError(err, "Test case '%s' failed", tt.testName)
+		for _, record := range tt.records {
+			assert.NoError(suite.T(), writer.write(record), "Test case '%s' failed", tt.testName)
+		}
+		assert.NoError(suite.T(), writer.close(), "Test case '%s' failed", tt.testName)
+		assert.Equal(suite.T(), tt.expected, out.String(), "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *MockWriterTestSuite) TestTemplateFormat() {
+	tests := []struct {
+		testName       string
+		inPath         string
+		expectedFormat string
+		expectedOutput string
+		expectedName   string
+	}{
+		{testName: "without a format", inPath: "dir/company[10].template.json", expectedFormat: formatJson, expectedOutput: "dir/company[10].json", expectedName: "company"},
+		{testName: "with a format", inPath: "dir/company[10].yaml.template.json", expectedFormat: formatYaml, expectedOutput: "dir/company[10].yaml", expectedName: "company"},
+		{testName: "with a single record", inPath: "config.toml.template.json", expectedFormat: formatToml, expectedOutput: "config.toml", expectedName: "config"},
+		{testName: "with an unknown extension", inPath: "company.v2.template.json", expectedFormat: formatJson, expectedOutput: "company.v2.json", expectedName: "company.v2"},
+	}
+
+	for _, tt := range tests {
+		format := templateFormat(tt.inPath, formatJson)
+		assert.Equal(suite.T(), tt.expectedFormat, format, "Test case '%s' failed", tt.testName)
+		assert.Equal(suite.T(), tt.expectedOutput, outputName(tt.inPath, format), "Test case '%s' failed", tt.testName)
+		assert.Equal(suite.T(), tt.expectedName, templateName(tt.inPath), "Test case '%s' failed", tt.testName)
+	}
+}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+)
+
+var tomlBareKeyRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Writes the records as an array of tables named after the template (e.g. [[company]]), or as the document itself
+// when there is only one record. TOML has no null, so null values are left out.
+type tomlWriter struct {
+	out     io.Writer
+	total   int
+	table   string
+	written int
+}
+
+func (w *tomlWriter) write(record any) error {
+	recordMap, ok := record.(*engine.OrderedMap)
+	if !ok {
+		return fmt.Errorf("--format toml needs every record to be an object, got '%T'", record)
+	}
+	var buf strings.Builder
+	if w.written > 0 {
+		buf.WriteString("\n")
+	}
+	var err error
+	if w.total > 1 {
+		err = writeTomlTable(&buf, []string{w.table}, recordMap, true)
+	} else {
+		err = writeTomlTable(&buf, nil, recordMap, false)
+	}
+	if err != nil {
+		return err
+	}
+	w.written++
+	_, err = io.WriteString(w.out, buf.String())
+	return err
+}
+
+func (w *tomlWriter) close() error {
+	return nil
+}
+
+// Writes a table: its header, its values, then its nested tables (which must come after every value of the table).
+func writeTomlTable(buf *strings.Builder, path []string, table *engine.OrderedMap, arrayItem bool) error {
+	if len(path) > 0 {
+		header := tomlKeyPath(path)
+		if arrayItem {
+			buf.WriteString("[[" + header + "]]\n")
+		} else {
+			buf.WriteString("[" + header + "]\n")
+		}
+	}
+
+	var nested []string
+	for _, objKey := range table.Keys() {
+		objValue, _ := table.Get(objKey)
+		if objValue == nil {
+			continue
+		}
+		if isTomlTable(objValue) || isTomlArrayOfTables(objValue) {
+			nested = append(nested, objKey)
+			continue
+		}
+		literal, err := tomlValue(objValue)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(tomlKey(objKey) + " = " + literal + "\n")
+	}
+
+	for _, objKey := range nested {
+		objValue, _ := table.Get(objKey)
+		childPath := append(append([]string(nil), path...), objKey)
+		if childTable, ok := objValue.(*engine.OrderedMap); ok {
+			buf.WriteString("\n")
+			if err := writeTomlTable(buf, childPath, childTable, false); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, item := range objValue.([]any) {
+			buf.WriteString("\n")
+			if err := writeTomlTable(buf, childPath, item.(*engine.OrderedMap), true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isTomlTable(value any) bool {
+	_, ok := value.(*engine.OrderedMap)
+	return ok
+}
+
+// Arrays with only objects are written as arrays of tables, other arrays are written inline.
+func isTomlArrayOfTables(value any) bool {
+	array, ok := value.([]any)
+	if !ok || len(array) == 0 {
+		return false
+	}
+	for _, item := range array {
+		if !isTomlTable(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the inline TOML of a value.
+func tomlValue(value any) (string, error) {
+	switch typedValue := value.(type) {
+	case string:
+		return tomlString(typedValue), nil
+	case *engine.OrderedMap:
+		fields := make([]string, 0, typedValue.Size())
+		for _, objKey := range typedValue.Keys() {
+			objValue, _ := typedValue.Get(objKey)
+			if objValue == nil {
+				continue
+			}
+			literal, err := tomlValue(objValue)
+			if err != nil {
+				return "", err
+			}
+			fields = append(fields, tomlKey(objKey)+" = "+literal)
+		}
+		if len(fields) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(fields, ", ") + " }", nil
+	case []any:
+		items := make([]string, 0, len(typedValue))
+		for _, item := range typedValue {
+			if item == nil {
+				continue
+			}
+			literal, err := tomlValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, literal)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	default:
+		encoded, err := json.Marshal(typedValue)
+		if err != nil {
+			return "", fmt.Errorf("error marshalling JSON '%w'", err)
+		}
+		return string(encoded), nil
+	}
+}
+
+func tomlKeyPath(path []string) string {
+	keys := make([]string, len(path))
+	for idx, objKey := range path {
+		keys[idx] = tomlKey(objKey)
+	}
+	return strings.Join(keys, ".")
+}
+
+func tomlKey(objKey string) string {
+	if tomlBareKeyRegex.MatchString(objKey) {
+		return objKey
+	}
+	return tomlString(objKey)
+}
+
+// Returns a TOML basic string, escaping quotes, backslashes and control characters.
+func tomlString(text string) string {
+	var buf strings.Builder
+	buf.WriteByte('"')
+	for _, char := range text {
+		switch char {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\r':
+			buf.WriteString(`\r`)
+		default:
+			if char < 0x20 || char == 0x7f {
+				fmt.Fprintf(&buf, `\u%04X`, char)
+			} else {
+				buf.WriteRune(char)
+			}
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
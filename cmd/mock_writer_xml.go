@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+)
+
+// Key of an object written as the text of its element, next to its attributes (e.g. { "@currency": "BRL", "#text": "10" }).
+const xmlTextKey = "#text"
+
+var xmlInvalidNameCharRegex = regexp.MustCompile(`[^A-Za-z0-9_.\-]`)
+
+type xmlOptions struct {
+	root       string
+	item       string
+	attrPrefix string
+}
+
+// Writes the records as elements (named `item`) of a root element (named `root`).
+// Keys starting with `attrPrefix` are written as attributes of their element, and arrays as repeated elements named after their key.
+type xmlWriter struct {
+	out     io.Writer
+	options xmlOptions
+	started bool
+}
+
+func (w *xmlWriter) write(record any) error {
+	if err := w.begin(); err != nil {
+		return err
+	}
+	var buf strings.Builder
+	if err := w.writeElement(&buf, w.options.item, record, 1); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w.out, buf.String())
+	return err
+}
+
+func (w *xmlWriter) close() error {
+	if err := w.begin(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w.out, "</"+w.options.root+">\n")
+	return err
+}
+
+// Writes the declaration and opens the root element, before the first record.
+func (w *xmlWriter) begin() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	_, err := io.WriteString(w.out, xml.Header+"<"+w.options.root+">\n")
+	return err
+}
+
+// Writes a value as an element. Arrays are written as repeated elements with the same name.
+func (w *xmlWriter) writeElement(buf *strings.Builder, name string, value any, depth int) error {
+	indent := strings.Repeat("  ", depth)
+	name = xmlName(name)
+	switch typedValue := value.(type) {
+	case []any:
+		for _, item := range typedValue {
+			// Arrays inside arrays need an element of their own, the items are named as the records
+			if _, isArray := item.([]any); isArray {
+				buf.WriteString(indent + "<" + name + ">\n")
+				if err := w.writeElement(buf, w.options.item, item, depth+1); err != nil {
+					return err
+				}
+				buf.WriteString(indent + "</" + name + ">\n")
+				continue
+			}
+			if err := w.writeElement(buf, name, item, depth); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *engine.OrderedMap:
+		var attributes strings.Builder
+		var children strings.Builder
+		text := ""
+		hasText := false
+		for _, objKey := range typedValue.Keys() {
+			objValue, _ := typedValue.Get(objKey)
+			if objKey == xmlTextKey {
+				field, err := xmlText(objKey, objValue)
+				if err != nil {
+					return err
+				}
+				text, hasText = field, true
+				continue
+			}
+			if w.options.attrPrefix != "" && strings.HasPrefix(objKey, w.options.attrPrefix) {
+				field, err := xmlText(objKey, objValue)
+				if err != nil {
+					return err
+				}
+				attributes.WriteString(" " + xmlName(strings.TrimPrefix(objKey, w.options.attrPrefix)) + "=\"" + xmlEscape(field) + "\"")
+				continue
+			}
+			if err := w.writeElement(&children, objKey, objValue, depth+1); err != nil {
+				return err
+			}
+		}
+		switch {
+		case hasText && children.Len() == 0:
+			buf.WriteString(indent + "<" + name + attributes.String() + ">" + xmlEscape(text) + "</" + name + ">\n")
+		case children.Len() == 0:
+			buf.WriteString(indent + "<" + name + attributes.String() + "/>\n")
+		default:
+			buf.WriteString(indent + "<" + name + attributes.String() + ">\n")
+			if hasText {
+				buf.WriteString(indent + "  " + xmlEscape(text) + "\n")
+			}
+			buf.WriteString(children.String())
+			buf.WriteString(indent + "</" + name + ">\n")
+		}
+		return nil
+	default:
+		field, err := xmlText(name, value)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(indent + "<" + name + ">" + xmlEscape(field) + "</" + name + ">\n")
+		return nil
+	}
+}
+
+// Returns the text of a scalar value (in an attribute or an element).
+func xmlText(objKey string, value any) (string, error) {
+	switch typedValue := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return typedValue, nil
+	case *engine.OrderedMap, []any:
+		return "", fmt.Errorf("the value of '%s' must not be an object or an array to be written as an XML attribute or text", objKey)
+	default:
+		encoded, err := json.Marshal(typedValue)
+		if err != nil {
+			return "", fmt.Errorf("error marshalling JSON '%w'", err)
+		}
+		return string(encoded), nil
+	}
+}
+
+func xmlEscape(text string) string {
+	var buf strings.Builder
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
+
+// Returns a valid element (or attribute) name from a key, replacing the characters XML doesn't allow in names.
+func xmlName(key string) string {
+	name := xmlInvalidNameCharRegex.ReplaceAllString(key, "_")
+	if name == "" || !(name[0] == '_' || (name[0] >= 'A' && name[0] <= 'Z') || (name[0] >= 'a' && name[0] <= 'z')) {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+)
+
+// Writes the records as a YAML sequence, or as a single document when there is only one record.
+type yamlWriter struct {
+	out   io.Writer
+	total int
+}
+
+func (w *yamlWriter) write(record any) error {
+	node, err := yamlNode(record)
+	if err != nil {
+		return err
+	}
+	// Every record is written as a sequence of a single item, which together make the sequence of all records
+	if w.total > 1 {
+		node = &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{node}}
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return fmt.Errorf("error marshalling YAML '%w'", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("error marshalling YAML '%w'", err)
+	}
+	_, err = w.out.Write(buf.Bytes())
+	return err
+}
+
+func (w *yamlWriter) close() error {
+	return nil
+}
+
+// Returns the YAML node of a value, keeping the key order of its objects.
+func yamlNode(value any) (*yaml.Node, error) {
+	switch typedValue := value.(type) {
+	case *engine.OrderedMap:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, objKey := range typedValue.Keys() {
+			objValue, _ := typedValue.Get(objKey)
+			valueNode, err := yamlNode(objValue)
+			if err != nil {
+				return nil, err
+			}
+			keyNode, err := yamlNode(objKey)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, keyNode, valueNode)
+		}
+		return node, nil
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range typedValue {
+			itemNode, err := yamlNode(item)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, itemNode)
+		}
+		return node, nil
+	default:
+		node := &yaml.Node{}
+		if err := node.Encode(typedValue); err != nil {
+			return nil, fmt.Errorf("error marshalling YAML '%w'", err)
+		}
+		return node, nil
+	}
+}
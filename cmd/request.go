@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
 	"github.com/lfsc09/k-test-n-stress/mocker"
 	"github.com/spf13/cobra"
 )
@@ -50,7 +51,7 @@ func NewRequestCmd(opts *CommandOptions) *cobra.Command {
 			urlStr = urlPrefix + urlStr
 
 			// Mock Url params if present
-			urlStr = processStr(urlStr, mocker)
+			urlStr = engine.ProcessStr(urlStr, mocker)
 
 			// Parse the URL
 			parsedUrl, err := url.Parse(urlStr)
@@ -64,7 +65,7 @@ func NewRequestCmd(opts *CommandOptions) *cobra.Command {
 				parts := strings.SplitN(queryParam, "=", 2)
 				if len(parts) == 2 {
 					key := strings.TrimSpace(parts[0])
-					value := processStr(strings.TrimSpace(parts[1]), mocker)
+					value := engine.ProcessStr(strings.TrimSpace(parts[1]), mocker)
 					query.Add(key, value)
 				}
 			}
@@ -74,21 +75,26 @@ func NewRequestCmd(opts *CommandOptions) *cobra.Command {
 			var body io.Reader
 			if data != "" {
 				// Parse the string object content
-				var parseMap map[string]any
-				if err := json.Unmarshal([]byte(data), &parseMap); err != nil {
+				template, err := engine.UnmarshalTemplate([]byte(data))
+				if err != nil {
 					return fmt.Errorf("failed to parse JSON from the provided --data '%w'", err)
 				}
+				template, err = engine.ResolveTemplateRefs(template, ".")
+				if err != nil {
+					return fmt.Errorf("failed to resolve the provided --data '%w'", err)
+				}
 
-				// Process the parsed map
-				if err := processJsonMap(parseMap, mocker); err != nil {
+				// Process the parsed template
+				record, err := engine.ProcessTemplate(template, mocker)
+				if err != nil {
 					return fmt.Errorf("%w", err)
 				}
 
-				// Sanitize the parsed map
-				sanitizeJsonMap(parseMap)
+				// Sanitize the processed record
+				engine.SanitizeValue(record)
 
 				// Convert back to JSON string
-				jsonBytes, err := json.Marshal(parseMap)
+				jsonBytes, err := json.Marshal(record)
 				if err != nil {
 					return fmt.Errorf("failed to convert JSON map to string '%w'", err)
 				}
@@ -27,6 +27,7 @@ func NewRootCmd(opts *CommandOptions) *cobra.Command {
 
 	rootCmd.AddCommand(NewMockCmd(opts))
 	rootCmd.AddCommand(NewRequestCmd(opts))
+	rootCmd.AddCommand(NewMaskCmd(opts))
 
 	// Disable automatic call of `--help` during errors
 	rootCmd.SilenceUsage = true
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"sort"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+)
+
+// Converts the maps of a value (written as map literals in the tests) into ordered maps, with their keys sorted.
+func toOrderedValue(value any) any {
+	switch typedValue := value.(type) {
+	case map[string]any:
+		objKeys := make([]string, 0, len(typedValue))
+		for objKey := range typedValue {
+			objKeys = append(objKeys, objKey)
+		}
+		sort.Strings(objKeys)
+		parseMap := engine.NewOrderedMap()
+		for _, objKey := range objKeys {
+			parseMap.Set(objKey, toOrderedValue(typedValue[objKey]))
+		}
+		return parseMap
+	case []any:
+		converted := make([]any, len(typedValue))
+		for itemKey, item := range typedValue {
+			converted[itemKey] = toOrderedValue(item)
+		}
+		return converted
+	default:
+		return value
+	}
+}
+
+// Converts the ordered maps of a value back into maps, so they can be compared with map literals.
+func fromOrderedValue(value any) any {
+	switch typedValue := value.(type) {
+	case *engine.OrderedMap:
+		converted := make(map[string]any, typedValue.Size())
+		for _, objKey := range typedValue.Keys() {
+			objValue, _ := typedValue.Get(objKey)
+			converted[objKey] = fromOrderedValue(objValue)
+		}
+		return converted
+	case []any:
+		converted := make([]any, len(typedValue))
+		for itemKey, item := range typedValue {
+			converted[itemKey] = fromOrderedValue(item)
+		}
+		return converted
+	default:
+		return value
+	}
+}
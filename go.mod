@@ -4,11 +4,11 @@ go 1.24.1
 
 require (
 	github.com/jaswdr/faker/v2 v2.3.3
-	github.com/mohae/deepcopy v0.0.0-20170929034955-c48cc78d4826
 	github.com/spf13/cobra v1.9.1
 	github.com/stretchr/testify v1.10.0
 	github.com/vbauerster/mpb/v8 v8.9.3
 	github.com/zach-klippenstein/goregen v0.0.0-20160303162051-795b5e3961ea
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -25,5 +25,4 @@ require (
 	github.com/spf13/pflag v1.0.6 // indirect
 	golang.org/x/sys v0.32.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,312 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lfsc09/k-test-n-stress/mocker"
+)
+
+const (
+	ifKey     = "$if"
+	thenKey   = "$then"
+	elseKey   = "$else"
+	oneOfKey  = "$oneOf"
+	weightKey = "$weight"
+)
+
+var conditionPlaceholderRegex = regexp.MustCompile(`{{\s*([^}]+?)\s*}}`)
+
+// Checks if an object key is one of the branching keys ("$if", "$then", "$else" or "$oneOf"), which are not processed as regular keys.
+func isBranchKey(objKey string) bool {
+	return objKey == ifKey || objKey == thenKey || objKey == elseKey || objKey == oneOfKey
+}
+
+// Resolves the branching nodes ("$if"/"$then"/"$else" and "$oneOf") of a processed record.
+type branchResolver struct {
+	root      *OrderedMap
+	mocker    mocker.Mocker
+	onlyOneOf bool
+}
+
+// Resolves every branching node of a record whose regular keys were already processed, returning the resolved record.
+// All "$oneOf" are resolved first, so the "$if" conditions can reference values from any chosen alternative.
+// When the root of the template is an array, each of its objects is a record on its own.
+func resolveBranches(template any, mocker mocker.Mocker) (any, error) {
+	switch typedValue := template.(type) {
+	case *OrderedMap:
+		resolver := &branchResolver{root: typedValue, mocker: mocker, onlyOneOf: true}
+		resolved, err := resolver.resolveValue(typedValue)
+		if err != nil {
+			return nil, err
+		}
+		// The root itself was replaced by a non object alternative
+		resolvedMap, ok := resolved.(*OrderedMap)
+		if !ok {
+			return resolveBranches(resolved, mocker)
+		}
+		resolver.root = resolvedMap
+		resolver.onlyOneOf = false
+		return resolver.resolveValue(resolvedMap)
+	case []any:
+		for itemKey, item := range typedValue {
+			resolvedItem, err := resolveBranches(item, mocker)
+			if err != nil {
+				return nil, err
+			}
+			typedValue[itemKey] = resolvedItem
+		}
+		return typedValue, nil
+	default:
+		return template, nil
+	}
+}
+
+// Resolves the branching nodes inside a value, returning the value that replaces it.
+func (b *branchResolver) resolveValue(value any) (any, error) {
+	switch typedValue := value.(type) {
+	case *OrderedMap:
+		resolved, err := b.resolveNode(typedValue)
+		if err != nil {
+			return nil, err
+		}
+		resolvedMap, ok := resolved.(*OrderedMap)
+		if !ok {
+			return b.resolveValue(resolved)
+		}
+		for _, objKey := range resolvedMap.Keys() {
+			// Branches not yet chosen are only processed when chosen
+			if isBranchKey(objKey) {
+				continue
+			}
+			objValue, _ := resolvedMap.Get(objKey)
+			resolvedValue, err := b.resolveValue(objValue)
+			if err != nil {
+				return nil, err
+			}
+			resolvedMap.Set(objKey, resolvedValue)
+		}
+		return resolvedMap, nil
+	case []any:
+		for itemKey, item := range typedValue {
+			resolvedItem, err := b.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			typedValue[itemKey] = resolvedItem
+		}
+		return typedValue, nil
+	default:
+		return value, nil
+	}
+}
+
+// Resolves the branching keys of an object, until there is none left.
+// The chosen branch is processed and merged into the object (in the place of the branching key), or replaces it
+// when the object has no other keys.
+func (b *branchResolver) resolveNode(node *OrderedMap) (any, error) {
+	for {
+		var branch any
+		var hasBranch bool
+		var err error
+		branchKey := oneOfKey
+		if node.Has(oneOfKey) {
+			branch, hasBranch, err = b.chooseOneOf(node)
+		} else if node.Has(ifKey) && !b.onlyOneOf {
+			branchKey = ifKey
+			branch, hasBranch, err = b.chooseIf(node)
+		} else {
+			if node.Has(thenKey) && !b.onlyOneOf {
+				return nil, fmt.Errorf("'%s' without '%s'", thenKey, ifKey)
+			}
+			if node.Has(elseKey) && !b.onlyOneOf {
+				return nil, fmt.Errorf("'%s' without '%s'", elseKey, ifKey)
+			}
+			return node, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !hasBranch {
+			node.Remove(branchKey)
+			continue
+		}
+
+		// The chosen branch is processed as any other template value
+		branch, err = processValue(branch, b.mocker)
+		if err != nil {
+			return nil, err
+		}
+		branchMap, isMap := branch.(*OrderedMap)
+		if !isMap {
+			if node.Size() > 1 {
+				return nil, fmt.Errorf("cannot merge the non object branch '%v' into an object", branch)
+			}
+			return branch, nil
+		}
+		node.Merge(branchKey, branchMap, true)
+	}
+}
+
+// Picks one of the "$oneOf" alternatives, considering their "$weight".
+func (b *branchResolver) chooseOneOf(node *OrderedMap) (any, bool, error) {
+	rawAlternatives, _ := node.Get(oneOfKey)
+	alternatives, ok := rawAlternatives.([]any)
+	if !ok || len(alternatives) == 0 {
+		return nil, false, fmt.Errorf("'%s' must be a non empty array of alternatives", oneOfKey)
+	}
+
+	weights := make([]float64, len(alternatives))
+	totalWeight := 0.0
+	for idx, alternative := range alternatives {
+		weights[idx] = 1
+		if alternativeMap, ok := alternative.(*OrderedMap); ok {
+			if rawWeight, hasWeight := alternativeMap.Get(weightKey); hasWeight {
+				weight, err := parseWeight(rawWeight)
+				if err != nil {
+					return nil, false, err
+				}
+				weights[idx] = weight
+			}
+		}
+		totalWeight += weights[idx]
+	}
+
+	chosen := len(alternatives) - 1
+	target := mocker.RandOf(b.mocker).Float64() * totalWeight
+	for idx, weight := range weights {
+		if target < weight {
+			chosen = idx
+			break
+		}
+		target -= weight
+	}
+
+	alternative := CloneValue(alternatives[chosen])
+	if alternativeMap, ok := alternative.(*OrderedMap); ok {
+		alternativeMap.Remove(weightKey)
+	}
+	return alternative, true, nil
+}
+
+// Evaluates the "$if" condition, returning the "$then" or "$else" branch and removing them from the object.
+func (b *branchResolver) chooseIf(node *OrderedMap) (any, bool, error) {
+	rawCondition, _ := node.Get(ifKey)
+	condition, ok := rawCondition.(string)
+	if !ok {
+		return nil, false, fmt.Errorf("'%s' must be a string condition (e.g. \"{{ $.type }} == pj\")", ifKey)
+	}
+	thenBranch, hasThen := node.Get(thenKey)
+	elseBranch, hasElse := node.Get(elseKey)
+	node.Remove(thenKey)
+	node.Remove(elseKey)
+	if !hasThen && !hasElse {
+		return nil, false, fmt.Errorf("'%s' must have a '%s' or an '%s'", ifKey, thenKey, elseKey)
+	}
+
+	result, err := b.evaluateCondition(condition)
+	if err != nil {
+		return nil, false, err
+	}
+	if result {
+		return CloneValue(thenBranch), hasThen, nil
+	}
+	return CloneValue(elseBranch), hasElse, nil
+}
+
+// Evaluates a condition in the format "<left> == <right>", "<left> != <right>" or just "<value>".
+// Values between double brackets are replaced by either the record's values ({{ $.path.to.key }}) or by mock functions.
+// A condition without operator is true when its value is not empty, "false" or "0".
+func (b *branchResolver) evaluateCondition(condition string) (bool, error) {
+	// Find the operator outside of the double brackets, before rendering any value
+	masked := conditionPlaceholderRegex.ReplaceAllStringFunc(condition, func(match string) string {
+		return strings.Repeat(" ", len(match))
+	})
+	for _, operator := range []string{"!=", "=="} {
+		operatorIdx := strings.Index(masked, operator)
+		if operatorIdx == -1 {
+			continue
+		}
+		left, err := b.renderCondition(condition[:operatorIdx])
+		if err != nil {
+			return false, err
+		}
+		right, err := b.renderCondition(condition[operatorIdx+len(operator):])
+		if err != nil {
+			return false, err
+		}
+		return (left == right) == (operator == "=="), nil
+	}
+
+	value, err := b.renderCondition(condition)
+	if err != nil {
+		return false, err
+	}
+	return value != "" && value != "false" && value != "0", nil
+}
+
+// Replaces the values between double brackets of one side of a condition.
+func (b *branchResolver) renderCondition(conditionSide string) (string, error) {
+	var renderErr error
+	rendered := conditionPlaceholderRegex.ReplaceAllStringFunc(conditionSide, func(match string) string {
+		interpretedValue := strings.TrimSpace(conditionPlaceholderRegex.FindStringSubmatch(match)[1])
+		if strings.HasPrefix(interpretedValue, "$.") {
+			return recordValueAtPath(b.root, strings.Split(strings.TrimPrefix(interpretedValue, "$."), "."))
+		}
+		functionName, params := ExtractMockMethod(interpretedValue)
+		mockValue, err := b.mocker.Generate(functionName, params)
+		if err != nil {
+			renderErr = err
+		}
+		return mockValue
+	})
+	if renderErr != nil {
+		return "", fmt.Errorf("failed to evaluate condition '%s' '%w'", conditionSide, renderErr)
+	}
+	return strings.TrimSpace(rendered), nil
+}
+
+// Follows a path of keys (ignoring the [digit] in them) through a processed record, returning the value as a string.
+// Missing keys, objects and arrays result in an empty string.
+func recordValueAtPath(record *OrderedMap, keyPath []string) string {
+	for _, objKey := range record.Keys() {
+		if SanitizeKeyWithBrackets(objKey) != keyPath[0] {
+			continue
+		}
+		objValue, _ := record.Get(objKey)
+		if len(keyPath) > 1 {
+			if nested, ok := objValue.(*OrderedMap); ok {
+				return recordValueAtPath(nested, keyPath[1:])
+			}
+			return ""
+		}
+		switch objValue.(type) {
+		case *OrderedMap, []any:
+			return ""
+		}
+		return fmt.Sprint(objValue)
+	}
+	return ""
+}
+
+func parseWeight(rawWeight any) (float64, error) {
+	var weight float64
+	switch typedWeight := rawWeight.(type) {
+	case float64:
+		weight = typedWeight
+	case string:
+		parsed, err := strconv.ParseFloat(typedWeight, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid '%s' value '%s' (must be a number)", weightKey, typedWeight)
+		}
+		weight = parsed
+	default:
+		return 0, fmt.Errorf("invalid '%s' value '%v' (must be a number)", weightKey, rawWeight)
+	}
+	if weight <= 0 {
+		return 0, fmt.Errorf("invalid '%s' value '%v' (must be greater than 0)", weightKey, rawWeight)
+	}
+	return weight, nil
+}
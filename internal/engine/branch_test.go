@@ -0,0 +1,226 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/lfsc09/k-test-n-stress/mocker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type BranchTestSuite struct {
+	suite.Suite
+}
+
+func TestBranchTestSuite(t *testing.T) {
+	suite.Run(t, new(BranchTestSuite))
+}
+
+func (suite *BranchTestSuite) TestProcessTemplate_Branches() {
+	tests := []struct {
+		testName string
+		input    map[string]any
+		expected map[string]any
+	}{
+		{
+			testName: "if with then branch merged",
+			input: map[string]any{
+				"type":  "pj",
+				"$if":   "{{ $.type }} == pj",
+				"$then": map[string]any{"document": "cnpj"},
+				"$else": map[string]any{"document": "cpf"},
+			},
+			expected: map[string]any{"type": "pj", "document": "cnpj"},
+		},
+		{
+			testName: "if with else branch merged",
+			input: map[string]any{
+				"type":  "pf",
+				"$if":   "{{ $.type }} == pj",
+				"$then": map[string]any{"document": "cnpj"},
+				"$else": map[string]any{"document": "cpf"},
+			},
+			expected: map[string]any{"type": "pf", "document": "cpf"},
+		},
+		{
+			testName: "if without the chosen branch",
+			input: map[string]any{
+				"type":  "pf",
+				"$if":   "{{ $.type }} != pf",
+				"$then": map[string]any{"document": "cnpj"},
+			},
+			expected: map[string]any{"type": "pf"},
+		},
+		{
+			testName: "if replacing the value of a key",
+			input: map[string]any{
+				"person": map[string]any{"type": "pj"},
+				"document": map[string]any{
+					"$if":   "{{ $.person.type }} == pj",
+					"$then": "cnpj",
+					"$else": "cpf",
+				},
+			},
+			expected: map[string]any{"person": map[string]any{"type": "pj"}, "document": "cnpj"},
+		},
+		{
+			testName: "if referencing a key with count",
+			input: map[string]any{
+				"type[1]": "pj",
+				"$if":     "{{ $.type }}",
+				"$then":   map[string]any{"valid": "yes"},
+			},
+			expected: map[string]any{"type[1]": "pj", "valid": "yes"},
+		},
+		{
+			testName: "nested if inside the chosen branch",
+			input: map[string]any{
+				"type":   "pj",
+				"active": "false",
+				"$if":    "{{ $.type }} == pj",
+				"$then": map[string]any{
+					"document": "cnpj",
+					"status": map[string]any{
+						"$if":   "{{ $.active }}",
+						"$then": "active",
+						"$else": "inactive",
+					},
+				},
+			},
+			expected: map[string]any{"type": "pj", "active": "false", "document": "cnpj", "status": "inactive"},
+		},
+		{
+			testName: "one of with a single alternative, referenced by a condition",
+			input: map[string]any{
+				"$oneOf": []any{
+					map[string]any{"type": "pj", "$weight": 2.0},
+				},
+				"document": map[string]any{
+					"$if":   "{{ $.type }} == pj",
+					"$then": "cnpj",
+				},
+			},
+			expected: map[string]any{"type": "pj", "document": "cnpj"},
+		},
+		{
+			testName: "one of replacing values of a counted key",
+			input: map[string]any{
+				"values[3]": map[string]any{
+					"$oneOf": []any{"raw"},
+				},
+			},
+			expected: map[string]any{"values[3]": []any{"raw", "raw", "raw"}},
+		},
+	}
+
+	for _, tt := range tests {
+		result, err := ProcessTemplate(toOrderedValue(tt.input), mocker.New())
+		assert.NoError(suite.T(), err, "Test case '%s' failed", tt.testName)
+		assert.Equal(suite.T(), tt.expected, fromOrderedValue(result), "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *BranchTestSuite) TestProcessTemplate_OneOfChoices() {
+	chosen := make(map[string]int)
+	for range 200 {
+		input := map[string]any{
+			"$oneOf": []any{
+				map[string]any{"type": "pf", "cpf": "{{ Person.cpf }}", "$weight": 3.0},
+				map[string]any{"type": "pj", "cnpj": "{{ Company.cnpj }}", "$weight": "1"},
+			},
+		}
+		result, err := ProcessTemplate(toOrderedValue(input), mocker.New())
+		assert.NoError(suite.T(), err)
+		record := fromOrderedValue(result).(map[string]any)
+		assert.NotContains(suite.T(), record, "$weight")
+		switch record["type"] {
+		case "pf":
+			assert.Regexp(suite.T(), `^\d{3}\.\d{3}\.\d{3}-\d{2}$`, record["cpf"])
+		case "pj":
+			assert.Regexp(suite.T(), `^\d{2}\.\d{3}\.\d{3}/\d{4}-\d{2}$`, record["cnpj"])
+		}
+		chosen[record["type"].(string)]++
+	}
+	assert.Len(suite.T(), chosen, 2)
+	assert.Greater(suite.T(), chosen["pf"], chosen["pj"])
+}
+
+func (suite *BranchTestSuite) TestProcessTemplate_InvalidBranches() {
+	tests := []struct {
+		testName string
+		input    map[string]any
+	}{
+		{
+			testName: "then without if",
+			input:    map[string]any{"$then": map[string]any{"a": "b"}},
+		},
+		{
+			testName: "if without branches",
+			input:    map[string]any{"$if": "true"},
+		},
+		{
+			testName: "if not being a string",
+			input:    map[string]any{"$if": map[string]any{}, "$then": map[string]any{}},
+		},
+		{
+			testName: "one of not being an array",
+			input:    map[string]any{"$oneOf": "a"},
+		},
+		{
+			testName: "empty one of",
+			input:    map[string]any{"$oneOf": []any{}},
+		},
+		{
+			testName: "invalid weight",
+			input:    map[string]any{"$oneOf": []any{map[string]any{"$weight": 0.0}}},
+		},
+		{
+			testName: "non object branch merged with other keys",
+			input:    map[string]any{"key": "value", "$if": "true", "$then": "raw"},
+		},
+		{
+			testName: "unknown mock function in condition",
+			input:    map[string]any{"$if": "{{ Unknown.function }}", "$then": map[string]any{}},
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := ProcessTemplate(toOrderedValue(tt.input), mocker.New())
+		assert.Error(suite.T(), err, "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *BranchTestSuite) TestEvaluateCondition_ValidInputs() {
+	resolver := &branchResolver{
+		root: toOrderedValue(map[string]any{
+			"type":     "pj",
+			"empty":    "",
+			"equation": "a == b",
+			"nested":   map[string]any{"key": "value"},
+		}).(*OrderedMap),
+		mocker: mocker.New(),
+	}
+	tests := []struct {
+		testName       string
+		input          string
+		expectedResult bool
+	}{
+		{testName: "equal", input: "{{ $.type }} == pj", expectedResult: true},
+		{testName: "not equal", input: "{{ $.type }} != pj", expectedResult: false},
+		{testName: "equal without spaces", input: "{{$.type}}==pf", expectedResult: false},
+		{testName: "nested key", input: "{{ $.nested.key }} == value", expectedResult: true},
+		{testName: "operator inside the value", input: "{{ $.equation }} == a == b", expectedResult: true},
+		{testName: "missing key", input: "{{ $.missing }} == ", expectedResult: true},
+		{testName: "truthy value", input: "{{ $.type }}", expectedResult: true},
+		{testName: "empty value", input: "{{ $.empty }}", expectedResult: false},
+		{testName: "false value", input: "false", expectedResult: false},
+		{testName: "zero value", input: "0", expectedResult: false},
+		{testName: "mock function", input: "{{ Boolean.booleanWithChance:100 }}", expectedResult: true},
+	}
+
+	for _, tt := range tests {
+		result, err := resolver.evaluateCondition(tt.input)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", tt.testName)
+		assert.Equal(suite.T(), tt.expectedResult, result, "Test case '%s' failed", tt.testName)
+	}
+}
@@ -0,0 +1,446 @@
+// Package engine parses ktns templates and generates their records, for the commands of ktns and the `template` package.
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lfsc09/k-test-n-stress/mocker"
+)
+
+var objKeyNumberRegex = regexp.MustCompile(`^[^\[\]\s]+\[(\d+)\]$`)
+var objKeyDimensionsRegex = regexp.MustCompile(`^[^\[\]\s]+((?:\[\d+\])+)$`)
+var digitInBracketsRegex = regexp.MustCompile(`\[(\d+)\]`)
+var dynamicKeyRegex = regexp.MustCompile(`^\s*{{\s*(.*?)\s*}}\s*(\[[^\[\]]*\])?$`)
+
+// Max attempts to generate a dynamic key not yet used in the object.
+const dynamicKeyMaxAttempts = 10
+
+// Splits a raw string of format "func:arg1:arg2:...".
+// It handles regex args wrapped with slashes (/.../) to avoid splitting inside them.
+// Returns: function name, and slice of parameter strings.
+func ExtractMockMethod(rawValue string) (string, []string) {
+	if rawValue == "" {
+		return "", nil
+	}
+	var parts []string
+	var buf strings.Builder
+	inRegex := false
+
+	trimmed := strings.TrimSpace(rawValue)
+
+	for _, char := range trimmed {
+		if char == '/' {
+			inRegex = !inRegex
+			// Always include slash
+			buf.WriteByte(byte(char))
+			continue
+		}
+		// If ':' outside regex — treat as delimiter
+		if char == ':' && !inRegex {
+			parts = append(parts, buf.String())
+			// Start building next segment
+			buf.Reset()
+			continue
+		}
+		// Default: build the current token
+		buf.WriteByte(byte(char))
+	}
+
+	// Add the final piece (there's no trailing `:`)
+	if buf.Len() > 0 {
+		parts = append(parts, buf.String())
+	}
+
+	return parts[0], parts[1:]
+}
+
+// Interprets a string value, checking if it contains a mock function between {{ }}.
+// If it does, it returns the function name and true.
+// If not, it returns the original string and false.
+func InterpretString(rawValue string) (string, bool) {
+	if rawValue == "" {
+		return "", false
+	}
+
+	re := regexp.MustCompile(`^\s*{{\s*(.*?)\s*}}\s*$`)
+	matches := re.FindStringSubmatch(rawValue)
+
+	if len(matches) > 0 {
+		return matches[1], true
+	}
+
+	return rawValue, false
+}
+
+// Parses the content of a template, which must be either a JSON object or a JSON array.
+// Objects keep the order of their keys, so the generated data follows the order of the template.
+func UnmarshalTemplate(content []byte) (any, error) {
+	template, err := UnmarshalOrdered(content)
+	if err != nil {
+		return nil, err
+	}
+	return checkTemplateRoot(template)
+}
+
+// Checks the root of a parsed template is an object or an array.
+func checkTemplateRoot(template any) (any, error) {
+	switch template.(type) {
+	case *OrderedMap, []any:
+		return template, nil
+	default:
+		return nil, fmt.Errorf("template must be a JSON object or a JSON array")
+	}
+}
+
+// Parses a template written in `language`, resolving its "$include" and "$ref" nodes relative to `baseDir`.
+// The parsed template can be rendered many times with `Render`.
+func Parse(content []byte, language string, baseDir string) (any, error) {
+	if !slices.Contains(TemplateLanguages(), language) {
+		return nil, fmt.Errorf("invalid template language '%s' (must be one of 'JSON', 'YAML' or 'JSON5')", language)
+	}
+	template, err := UnmarshalTemplateLanguage(language, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s template '%w'", language, err)
+	}
+	template, err = ResolveTemplateRefs(template, baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve template '%w'", err)
+	}
+	return template, nil
+}
+
+// Generates a record from a parsed template, as the mock command does for each of its records.
+// The parsed template is left untouched, and the record marshals to JSON keeping the key order of the template.
+func Render(template any, mocker mocker.Mocker) (any, error) {
+	record, err := ProcessTemplate(CloneValue(template), mocker)
+	if err != nil {
+		return nil, err
+	}
+	SanitizeValue(record)
+	return record, nil
+}
+
+// Returns the random source of a mocker (see mocker.RandOf), for the functions whose `mocker` parameter hides the package.
+func randOf(m mocker.Mocker) *rand.Rand {
+	return mocker.RandOf(m)
+}
+
+// Processes a whole template record, generating its values and then resolving its branches ("$if" and "$oneOf").
+func ProcessTemplate(template any, mocker mocker.Mocker) (any, error) {
+	processed, err := processValue(template, mocker)
+	if err != nil {
+		return nil, err
+	}
+	return resolveBranches(processed, mocker)
+}
+
+// Processes a template value, returning the generated value.
+// Strings with mock functions are replaced by the generated data, objects and arrays (of any depth) are processed recursively.
+// Nulls are kept as they are. Returns an error if the value is not a string, map, array or null.
+func processValue(value any, mocker mocker.Mocker) (any, error) {
+	switch typedValue := value.(type) {
+	case string:
+		// try to find the mock function in the "value"
+		interpretedValue, isMockFunction := InterpretString(typedValue)
+		// if it's not a mock function, just keep the value
+		if !isMockFunction {
+			return interpretedValue, nil
+		}
+		functionName, params := ExtractMockMethod(interpretedValue)
+		return mocker.Generate(functionName, params)
+	case *OrderedMap:
+		if err := processJsonMap(typedValue, mocker); err != nil {
+			return nil, err
+		}
+		return typedValue, nil
+	case []any:
+		for itemKey, item := range typedValue {
+			processedItem, err := processValue(item, mocker)
+			if err != nil {
+				return nil, err
+			}
+			typedValue[itemKey] = processedItem
+		}
+		return typedValue, nil
+	case valueGenerator:
+		return typedValue.generateValue(mocker)
+	case nil:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("value '%v' is not a string, map or array", typedValue)
+	}
+}
+
+// Iterates through the parsed json map and processes each value.
+// It replaces string values with generated mock data based on the function name and parameters.
+// Keys with [digit] (or multiple [digit][digit]...) are replaced by arrays (or arrays of arrays) of generated values.
+// Returns an error if any value is not a string, map, array or null.
+func processJsonMap(parseMap *OrderedMap, mocker mocker.Mocker) error {
+	literalKeys, err := sanitizedLiteralKeys(parseMap)
+	if err != nil {
+		return err
+	}
+	for _, objKey := range parseMap.Keys() {
+		// branches are processed only after being chosen (check `resolveBranches`)
+		if isBranchKey(objKey) {
+			continue
+		}
+		// keys generated by mock functions are replaced by the generated ones, with their values already processed
+		if functionName, params, generateAmount, isDynamicKey, err := ExtractDynamicKey(objKey); isDynamicKey {
+			if err != nil {
+				return err
+			}
+			if err := expandDynamicKey(parseMap, objKey, functionName, params, generateAmount, literalKeys, mocker); err != nil {
+				return err
+			}
+			continue
+		}
+		// try to find [digit] (or [digit][digit]...) in the "key"
+		dimensions, err := ExtractDimensionsInBrackets(objKey)
+		if err != nil {
+			return err
+		}
+		objValue, _ := parseMap.Get(objKey)
+		generatedValue, err := generateDimensions(objValue, dimensions, mocker)
+		if err != nil {
+			return err
+		}
+		parseMap.Set(objKey, generatedValue)
+	}
+	return nil
+}
+
+// Generates a template value once for each position of the dimensions (e.g. [3, 4] generates 3 arrays of 4 values).
+// Without dimensions, the value itself is processed.
+func generateDimensions(value any, dimensions []int, mocker mocker.Mocker) (any, error) {
+	if len(dimensions) == 0 {
+		return processValue(value, mocker)
+	}
+	generated := make([]any, dimensions[0])
+	for i := range generated {
+		generatedValue, err := generateDimensions(CloneValue(value), dimensions[1:], mocker)
+		if err != nil {
+			return nil, err
+		}
+		generated[i] = generatedValue
+	}
+	return generated, nil
+}
+
+// Splits a dynamic key into its mock function and its brackets (e.g. "{{ UUID.uuidv4 }}[5]" -> "UUID.uuidv4", "[5]").
+// Returns false when the key isn't a dynamic key.
+func SplitDynamicKey(objKey string) (string, string, bool) {
+	matches := dynamicKeyRegex.FindStringSubmatch(objKey)
+	if len(matches) == 0 {
+		return "", "", false
+	}
+	return matches[1], matches[2], true
+}
+
+// Checks if an object key is a mock function (e.g. "{{ UUID.uuidv4 }}" or "{{ UUID.uuidv4 }}[5]").
+// Returns the function name and parameters, the amount of keys to generate and if it is a dynamic key.
+func ExtractDynamicKey(objKey string) (string, []string, int, bool, error) {
+	mockFunction, brackets, isDynamicKey := SplitDynamicKey(objKey)
+	if !isDynamicKey {
+		return "", nil, 0, false, nil
+	}
+	functionName, params := ExtractMockMethod(mockFunction)
+	generateAmount, err := ExtractKeyCount("key" + brackets)
+	if err != nil {
+		return "", nil, 0, true, fmt.Errorf("invalid dynamic key '%s' '%w'", objKey, err)
+	}
+	return functionName, params, generateAmount, true, nil
+}
+
+// Returns the keys of an object written in the template, without their brackets (as they are written), failing when two
+// of them end up the same (e.g. "phones" and "phones[2]"). Dynamic, generated and branch keys are left out.
+func sanitizedLiteralKeys(parseMap *OrderedMap) (map[string]bool, error) {
+	literalKeys := make(map[string]bool)
+	originalKeys := make(map[string]string)
+	for _, objKey := range parseMap.Keys() {
+		if isBranchKey(objKey) || parseMap.IsGenerated(objKey) || dynamicKeyRegex.MatchString(objKey) {
+			continue
+		}
+		sanitizedKey := SanitizeKeyWithBrackets(objKey)
+		if originalKey, ok := originalKeys[sanitizedKey]; ok {
+			return nil, fmt.Errorf("duplicate key '%s' (both '%s' and '%s' are written as it)", sanitizedKey, originalKey, objKey)
+		}
+		originalKeys[sanitizedKey] = objKey
+		literalKeys[sanitizedKey] = true
+	}
+	return literalKeys, nil
+}
+
+// Replaces a dynamic key by `generateAmount` keys generated by the mock function, each with a processed copy of the original value.
+// The generated keys take the place of the dynamic key, and are kept as generated (even with brackets). Generating a
+// key already written in the template (`literalKeys`) fails, instead of one of them replacing the other.
+func expandDynamicKey(parseMap *OrderedMap, objKey string, functionName string, params []string, generateAmount int, literalKeys map[string]bool, mocker mocker.Mocker) error {
+	objValue, _ := parseMap.Get(objKey)
+	generated := NewOrderedMap()
+	for range generateAmount {
+		generatedKey := ""
+		for attempt := 0; ; attempt++ {
+			if attempt == dynamicKeyMaxAttempts {
+				return fmt.Errorf("unable to generate %d different keys for '%s'", generateAmount, objKey)
+			}
+			mockValue, err := mocker.Generate(functionName, params)
+			if err != nil {
+				return err
+			}
+			if literalKeys[mockValue] {
+				return fmt.Errorf("duplicate key '%s' (generated by '%s', and also written in the template)", mockValue, objKey)
+			}
+			if !parseMap.Has(mockValue) && !generated.Has(mockValue) {
+				generatedKey = mockValue
+				break
+			}
+		}
+		// process the value on its own, so the generated key is used as is
+		generatedValue, err := processValue(CloneValue(objValue), mocker)
+		if err != nil {
+			return err
+		}
+		generated.Set(generatedKey, generatedValue)
+	}
+	parseMap.Merge(objKey, generated, true)
+	for _, generatedKey := range generated.Keys() {
+		parseMap.MarkGenerated(generatedKey)
+	}
+	return nil
+}
+
+// Iterates through the parsed json map and sanitizes the keys by removing segments between bracketes (e.g. [digits]).
+// It handles nested maps and arrays, and the sanitized keys keep their position.
+func sanitizeJsonMap(parseMap *OrderedMap) {
+	// Clone keys to avoid modifying map during iteration
+	for _, objKey := range parseMap.Keys() {
+		objValue, _ := parseMap.Get(objKey)
+
+		// Recurse on nested maps and arrays
+		SanitizeValue(objValue)
+
+		// Keys generated by mock functions are kept as they were generated
+		if parseMap.IsGenerated(objKey) {
+			continue
+		}
+		sanitizedKey := SanitizeKeyWithBrackets(objKey)
+		if sanitizedKey != objKey {
+			parseMap.Rename(objKey, sanitizedKey)
+		}
+	}
+}
+
+// Sanitizes the keys of every map inside a generated value. (Check `sanitizeJsonMap`)
+func SanitizeValue(value any) {
+	switch typedValue := value.(type) {
+	case *OrderedMap:
+		sanitizeJsonMap(typedValue)
+	case []any:
+		for _, item := range typedValue {
+			SanitizeValue(item)
+		}
+	}
+}
+
+// Process a simple string value, checking if it contains a mock function.
+// If it does, it generates the mock value using the mocker.
+// If not, it returns the original string.
+func ProcessStr(parseStr string, mocker mocker.Mocker) string {
+	all, _ := RenderStr(parseStr, mocker)
+	return all
+}
+
+// Replaces every mock function of a string by its generated value.
+// Mock functions that fail are replaced by their error between brackets, and the first error is also returned.
+func RenderStr(parseStr string, mocker mocker.Mocker) (string, error) {
+	dBracketsPatterns := regexp.MustCompile(`{{\s*([^}]+?)\s*}}`)
+
+	var firstErr error
+	all := dBracketsPatterns.ReplaceAllStringFunc(parseStr, func(match string) string {
+		interpretedValue := dBracketsPatterns.FindStringSubmatch(match)[1]
+		interpretedValue = strings.TrimSpace(interpretedValue)
+
+		functionName, params := ExtractMockMethod(interpretedValue)
+
+		mockValue, err := mocker.Generate(functionName, params)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return fmt.Sprintf("[%v]", err)
+		}
+		return mockValue
+	})
+
+	return all, firstErr
+}
+
+// Extracts the count of an object key in the format "content[<digit>]". If the key doesn't contain brackets, it returns 1.
+func ExtractKeyCount(str string) (int, error) {
+	matches := objKeyNumberRegex.FindStringSubmatch(str)
+	if len(matches) != 2 {
+		if !strings.ContainsAny(str, "[]") {
+			return 1, nil
+		}
+		return 0, fmt.Errorf("invalid format '%s' (must be 'text[digit]')", str)
+	}
+
+	digit, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid content inside brackets in '%s'", str)
+	}
+
+	if digit <= 0 {
+		return 0, fmt.Errorf("invalid digit in brackets '%s'", str)
+	}
+
+	return digit, nil
+}
+
+// Extracts the dimensions from an object key in the format "content[<digit>]" or "content[<digit>][<digit>]...".
+// If the key doesn't contain brackets (or is just "content[1]"), it returns no dimensions.
+func ExtractDimensionsInBrackets(str string) ([]int, error) {
+	if !strings.ContainsAny(str, "[]") {
+		return nil, nil
+	}
+	matches := objKeyDimensionsRegex.FindStringSubmatch(str)
+	if len(matches) != 2 {
+		return nil, fmt.Errorf("invalid format '%s' (must be 'text[digit]' or 'text[digit][digit]...')", str)
+	}
+
+	var dimensions []int
+	for _, digitMatches := range digitInBracketsRegex.FindAllStringSubmatch(matches[1], -1) {
+		digit, err := strconv.Atoi(digitMatches[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid content inside brackets in '%s'", str)
+		}
+		if digit <= 0 {
+			return nil, fmt.Errorf("invalid digit in brackets '%s'", str)
+		}
+		dimensions = append(dimensions, digit)
+	}
+
+	if len(dimensions) == 1 && dimensions[0] == 1 {
+		return nil, nil
+	}
+	return dimensions, nil
+}
+
+// Removes the segments of a string between brackets, including the brackets themselves.
+// It returns the cleaned string.
+func SanitizeKeyWithBrackets(str string) string {
+	for {
+		startBracket := strings.Index(str, "[")
+		endBracket := strings.Index(str, "]")
+		if startBracket == -1 || endBracket == -1 || endBracket < startBracket {
+			return str
+		}
+		// Remove the segment from the original string
+		str = str[:startBracket] + str[endBracket+1:]
+	}
+}
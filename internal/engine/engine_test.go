@@ -0,0 +1,627 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/lfsc09/k-test-n-stress/mocker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type EngineTestSuite struct {
+	suite.Suite
+}
+
+func TestEngineTestSuite(t *testing.T) {
+	suite.Run(t, new(EngineTestSuite))
+}
+
+func (suite *EngineTestSuite) TestExtractMockMethod_ValidInputs() {
+	tests := []struct {
+		testName         string
+		input            string
+		expectedFuncName string
+		expectedParams   []string
+	}{
+		{
+			testName:         "empty string",
+			input:            "",
+			expectedFuncName: "",
+			expectedParams:   nil,
+		},
+		{
+			testName:         "simple mock function",
+			input:            "Address.city",
+			expectedFuncName: "Address.city",
+			expectedParams:   []string{},
+		},
+		{
+			testName:         "mock function with params",
+			input:            "Boolean.booleanWithChance:10",
+			expectedFuncName: "Boolean.booleanWithChance",
+			expectedParams:   []string{"10"},
+		},
+		{
+			testName:         "mock function with multiple params",
+			input:            "Function.with:multiple:params",
+			expectedFuncName: "Function.with",
+			expectedParams:   []string{"multiple", "params"},
+		},
+		{
+			testName:         "regex mock function with empty regex",
+			input:            "Regex.regex://",
+			expectedFuncName: "Regex.regex",
+			expectedParams:   []string{"//"},
+		},
+		{
+			testName:         "regular regex mock function",
+			input:            "Regex.regex:/[a-z0-9]{1,64}/",
+			expectedFuncName: "Regex.regex",
+			expectedParams:   []string{"/[a-z0-9]{1,64}/"},
+		},
+		{
+			testName:         "regex mock function with params",
+			input:            "Regex.regex:/[a-z0-9]{1,64}/:param2",
+			expectedFuncName: "Regex.regex",
+			expectedParams:   []string{"/[a-z0-9]{1,64}/", "param2"},
+		},
+	}
+
+	for _, tt := range tests {
+		funcName, params := ExtractMockMethod(tt.input)
+		assert.Equal(suite.T(), tt.expectedFuncName, funcName, "Test case '%s' failed", tt.testName)
+		assert.Equal(suite.T(), tt.expectedParams, params, "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *EngineTestSuite) TestInterpretString_ValidInputs() {
+	tests := []struct {
+		testName       string
+		input          string
+		expectedValue  string
+		expectedIsMock bool
+	}{
+		{
+			testName:       "empty string",
+			input:          "",
+			expectedValue:  "",
+			expectedIsMock: false,
+		},
+		{
+			testName:       "no whitespace",
+			input:          "{{Address.city}}",
+			expectedValue:  "Address.city",
+			expectedIsMock: true,
+		},
+		{
+			testName:       "regular whitespaces",
+			input:          "{{ Address.city }}",
+			expectedValue:  "Address.city",
+			expectedIsMock: true,
+		},
+		{
+			testName:       "multiple whitespaces at begining",
+			input:          "{{    Address.city }}",
+			expectedValue:  "Address.city",
+			expectedIsMock: true,
+		},
+		{
+			testName:       "multiple whitespaces at end",
+			input:          "{{ Address.city    }}",
+			expectedValue:  "Address.city",
+			expectedIsMock: true,
+		},
+		{
+			testName:       "multiple whitespaces at begining and end",
+			input:          "{{     Address.city    }}",
+			expectedValue:  "Address.city",
+			expectedIsMock: true,
+		},
+		{
+			testName:       "whitespaces before brackets",
+			input:          "  {{     Address.city    }}",
+			expectedValue:  "Address.city",
+			expectedIsMock: true,
+		},
+		{
+			testName:       "content but no brackets",
+			input:          "Address.city",
+			expectedValue:  "Address.city",
+			expectedIsMock: false,
+		},
+		{
+			testName:       "brackets in middle of content",
+			input:          "{{ Address.cit}}y",
+			expectedValue:  "{{ Address.cit}}y",
+			expectedIsMock: false,
+		},
+	}
+
+	for _, tt := range tests {
+		value, isMock := InterpretString(tt.input)
+		assert.Equal(suite.T(), tt.expectedValue, value, "Test case '%s' failed", tt.testName)
+		assert.Equal(suite.T(), tt.expectedIsMock, isMock, "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *EngineTestSuite) TestProcessJsonMap_ValidInputs() {
+	tests := []struct {
+		testName string
+		input    map[string]any
+	}{
+		{
+			testName: "string value",
+			input: map[string]any{
+				"key": "{{ Address.city }}",
+			},
+		},
+		{
+			testName: "string value with params",
+			input: map[string]any{
+				"key": "{{ Boolean.booleanWithChance:10 }}",
+			},
+		},
+		{
+			testName: "2 string values",
+			input: map[string]any{
+				"key":  "{{ Address.city }}",
+				"key2": "{{ Address.state }}",
+			},
+		},
+		{
+			testName: "string value asking for two results",
+			input: map[string]any{
+				"key[2]": "{{ Address.city }}",
+			},
+		},
+		{
+			testName: "nested map",
+			input: map[string]any{
+				"level": map[string]any{
+					"key": "{{ Address.city }}",
+				},
+			},
+		},
+		{
+			testName: "nested map with 2 values",
+			input: map[string]any{
+				"level": map[string]any{
+					"key":  "{{ Address.city }}",
+					"key2": "{{ Address.state }}",
+				},
+			},
+		},
+		{
+			testName: "nested map asking for two objects",
+			input: map[string]any{
+				"level[2]": map[string]any{
+					"key": "{{ Address.city }}",
+				},
+			},
+		},
+		{
+			testName: "2 nested map on same level",
+			input: map[string]any{
+				"level": map[string]any{
+					"key": "{{ Address.city }}",
+				},
+				"level2": map[string]any{
+					"key": "{{ Address.city }}",
+				},
+			},
+		},
+		{
+			testName: "2 nested map, one inside the other",
+			input: map[string]any{
+				"level_0": map[string]any{
+					"key": "{{ Address.city }}",
+					"level_1": map[string]any{
+						"key": "{{ Address.city }}",
+					},
+				},
+			},
+		},
+		{
+			testName: "arrays of 2 string values",
+			input: map[string]any{
+				"array": []any{"{{ Address.city }}", "{{ Person.firstName }}"},
+			},
+		},
+		{
+			testName: "2 arrays of 2 strings values",
+			input: map[string]any{
+				"array":  []any{"{{ Address.city }}", "{{ Person.firstName }}"},
+				"array2": []any{"{{ Address.city }}", "{{ Person.firstName }}"},
+			},
+		},
+		{
+			testName: "nested map with array of strings inside",
+			input: map[string]any{
+				"level": map[string]any{
+					"key":   "{{ Address.city }}",
+					"array": []any{"{{ Address.city }}", "{{ Person.firstName }}"},
+				},
+			},
+		},
+		{
+			testName: "array of nested maps",
+			input: map[string]any{
+				"array": []any{
+					map[string]any{"key": "{{ Address.city }}"},
+					map[string]any{"key": "{{ Person.firstName }}", "key2": "{{ Person.lastName }}"},
+				},
+			},
+		},
+		{
+			testName: "array of nested maps with more maps and arrays inside",
+			input: map[string]any{
+				"array": []any{
+					map[string]any{
+						"key":   "{{ Address.city }}",
+						"array": []any{"{{ Address.city }}", "{{ Person.firstName }}"},
+					},
+					map[string]any{
+						"key":  "{{ Person.firstName }}",
+						"key2": "{{ Person.lastName }}",
+						"map": map[string]any{
+							"key":   "{{ Address.city }}",
+							"array": []any{"{{ Address.city }}", "{{ Person.firstName }}", map[string]any{"key": "{{ Person.lastName }}"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			testName: "array of arrays",
+			input: map[string]any{
+				"matrix": []any{
+					[]any{"{{ Address.city }}", "raw"},
+					[]any{[]any{"{{ Person.firstName }}"}, map[string]any{"key": "{{ Person.lastName }}"}},
+				},
+			},
+		},
+		{
+			testName: "null values",
+			input: map[string]any{
+				"key":   nil,
+				"array": []any{"{{ Address.city }}", nil},
+			},
+		},
+		{
+			testName: "string value asking for an array of arrays",
+			input: map[string]any{
+				"matrix[3][4]": "{{ Number.number }}",
+			},
+		},
+		{
+			testName: "nested map asking for an array of arrays",
+			input: map[string]any{
+				"grid[2][2]": map[string]any{
+					"key": "{{ Address.city }}",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		mockerObj := mocker.New()
+		err := processJsonMap(toOrderedValue(tt.input).(*OrderedMap), mockerObj)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *EngineTestSuite) TestProcessTemplate_Dimensions() {
+	result, err := ProcessTemplate(toOrderedValue(map[string]any{
+		"matrix[3][4]": "{{ Number.number }}",
+		"grid[2][1]": map[string]any{
+			"phones[2]": "{{ Person.phoneNumber }}",
+		},
+		"single[1]": "raw",
+	}), mocker.New())
+	assert.NoError(suite.T(), err)
+	SanitizeValue(result)
+
+	record := fromOrderedValue(result).(map[string]any)
+	matrix := record["matrix"].([]any)
+	assert.Len(suite.T(), matrix, 3)
+	for _, row := range matrix {
+		assert.Len(suite.T(), row, 4)
+		assert.IsType(suite.T(), "", row.([]any)[0])
+	}
+	grid := record["grid"].([]any)
+	assert.Len(suite.T(), grid, 2)
+	for _, row := range grid {
+		assert.Len(suite.T(), row, 1)
+		assert.Len(suite.T(), row.([]any)[0].(map[string]any)["phones"], 2)
+	}
+	assert.Equal(suite.T(), "raw", record["single"])
+}
+
+func (suite *EngineTestSuite) TestProcessTemplate_RootArray() {
+	result, err := ProcessTemplate(toOrderedValue([]any{
+		map[string]any{"names[2]": "{{ Person.name }}"},
+		[]any{"raw", "{{ Address.city }}"},
+		"{{ UUID.uuidv4 }}",
+	}), mocker.New())
+	assert.NoError(suite.T(), err)
+	SanitizeValue(result)
+
+	items := fromOrderedValue(result).([]any)
+	assert.Len(suite.T(), items, 3)
+	assert.Len(suite.T(), items[0].(map[string]any)["names"], 2)
+	assert.Equal(suite.T(), "raw", items[1].([]any)[0])
+	assert.NotContains(suite.T(), items[2], "{{")
+}
+
+func (suite *EngineTestSuite) TestUnmarshalTemplate_InvalidInputs() {
+	tests := []struct {
+		testName string
+		input    string
+	}{
+		{testName: "invalid json", input: `{ "key": `},
+		{testName: "string root", input: `"{{ Person.name }}"`},
+		{testName: "number root", input: `10`},
+	}
+
+	for _, tt := range tests {
+		_, err := UnmarshalTemplate([]byte(tt.input))
+		assert.Error(suite.T(), err, "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *EngineTestSuite) TestProcessJsonMap_InvalidInputs() {
+	tests := []struct {
+		testName string
+		input    map[string]any
+	}{
+		{
+			testName: "invalid value in map [integer value]",
+			input: map[string]any{
+				"key": 123,
+			},
+		},
+		{
+			testName: "invalid type in array [integer value]",
+			input: map[string]any{
+				"array": []any{123},
+			},
+		},
+		{
+			testName: "invalid type in array of arrays [integer value]",
+			input: map[string]any{
+				"array": []any{[]any{"raw", 123}},
+			},
+		},
+		{
+			testName: "invalid dimensions in the key",
+			input: map[string]any{
+				"matrix[3][0]": "{{ Address.city }}",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		mockerObj := mocker.New()
+		err := processJsonMap(toOrderedValue(tt.input).(*OrderedMap), mockerObj)
+		assert.Error(suite.T(), err, "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *EngineTestSuite) TestProcessJsonMap_DynamicKeys() {
+	uuidRegex := `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[0-9a-f]{4}-[0-9a-f]{12}$`
+	tests := []struct {
+		testName     string
+		input        map[string]any
+		expectedKeys int
+	}{
+		{
+			testName: "single dynamic key",
+			input: map[string]any{
+				"{{ UUID.uuidv4 }}": "{{ Person.name }}",
+			},
+			expectedKeys: 1,
+		},
+		{
+			testName: "multiple dynamic keys with object values",
+			input: map[string]any{
+				"{{ UUID.uuidv4 }}[5]": map[string]any{
+					"name":      "{{ Person.name }}",
+					"phones[2]": "{{ Person.phoneNumber }}",
+				},
+			},
+			expectedKeys: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		mockerObj := mocker.New()
+		input := toOrderedValue(tt.input).(*OrderedMap)
+		err := processJsonMap(input, mockerObj)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", tt.testName)
+		output := fromOrderedValue(input).(map[string]any)
+		assert.Len(suite.T(), output, tt.expectedKeys, "Test case '%s' failed", tt.testName)
+		for objKey, objValue := range output {
+			assert.Regexp(suite.T(), uuidRegex, objKey, "Test case '%s' failed", tt.testName)
+			if mapValue, ok := objValue.(map[string]any); ok {
+				assert.IsType(suite.T(), "", mapValue["name"], "Test case '%s' failed", tt.testName)
+				assert.Len(suite.T(), mapValue["phones[2]"], 2, "Test case '%s' failed", tt.testName)
+			} else {
+				assert.NotContains(suite.T(), objValue, "{{", "Test case '%s' failed", tt.testName)
+			}
+		}
+	}
+}
+
+func (suite *EngineTestSuite) TestProcessJsonMap_GeneratedKeysKeepTheirBrackets() {
+	input := toOrderedValue(map[string]any{
+		"{{ Regex.regex:/code\\[[0-9]\\]/ }}[3]": "{{ Person.name }}",
+		"tags[2]":                                "{{ Lorem.word }}",
+	}).(*OrderedMap)
+	err := processJsonMap(input, mocker.New())
+	assert.NoError(suite.T(), err)
+	SanitizeValue(input)
+
+	output := fromOrderedValue(input).(map[string]any)
+	assert.Len(suite.T(), output, 4)
+	assert.Len(suite.T(), output["tags"], 2)
+	for objKey := range output {
+		if objKey != "tags" {
+			assert.Regexp(suite.T(), `^code\[[0-9]\]$`, objKey)
+		}
+	}
+}
+
+func (suite *EngineTestSuite) TestProcessJsonMap_InvalidDynamicKeys() {
+	tests := []struct {
+		testName string
+		input    map[string]any
+	}{
+		{
+			testName: "invalid count",
+			input:    map[string]any{"{{ UUID.uuidv4 }}[0]": "value"},
+		},
+		{
+			testName: "unknown mock function",
+			input:    map[string]any{"{{ Unknown.function }}": "value"},
+		},
+		{
+			testName: "not enough different keys",
+			input:    map[string]any{"{{ Boolean.booleanWithChance:100 }}[2]": "value"},
+		},
+		{
+			testName: "generated key also written in the template",
+			input: map[string]any{
+				"{{ Regex.regex:/name/ }}": "value",
+				"name":                     "{{ Person.name }}",
+			},
+		},
+		{
+			testName: "literal keys written the same without brackets",
+			input: map[string]any{
+				"phones":    "{{ Person.phoneNumber }}",
+				"phones[2]": "{{ Person.phoneNumber }}",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		mockerObj := mocker.New()
+		err := processJsonMap(toOrderedValue(tt.input).(*OrderedMap), mockerObj)
+		assert.Error(suite.T(), err, "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *EngineTestSuite) TestExtractDimensionsInBrackets_ValidInputs() {
+	tests := []struct {
+		testName           string
+		input              string
+		expectedDimensions []int
+	}{
+		{testName: "empty", input: "", expectedDimensions: nil},
+		{testName: "no brackets", input: "text", expectedDimensions: nil},
+		{testName: "single [1]", input: "text[1]", expectedDimensions: nil},
+		{testName: "single dimension", input: "text[10]", expectedDimensions: []int{10}},
+		{testName: "two dimensions", input: "text[3][4]", expectedDimensions: []int{3, 4}},
+		{testName: "two dimensions of 1", input: "text[1][1]", expectedDimensions: []int{1, 1}},
+		{testName: "three dimensions", input: "text[2][1][5]", expectedDimensions: []int{2, 1, 5}},
+	}
+
+	for _, tt := range tests {
+		dimensions, err := ExtractDimensionsInBrackets(tt.input)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", tt.testName)
+		assert.Equal(suite.T(), tt.expectedDimensions, dimensions, "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *EngineTestSuite) TestExtractDimensionsInBrackets_InvalidInputs() {
+	tests := []struct {
+		testName string
+		input    string
+	}{
+		{testName: "zero", input: "text[0]"},
+		{testName: "zero in second dimension", input: "text[3][0]"},
+		{testName: "negative", input: "text[-2]"},
+		{testName: "empty brackets", input: "text[3][]"},
+		{testName: "brackets at the start", input: "[5]text"},
+		{testName: "brackets in the middle", input: "te[5]xt[2]"},
+		{testName: "nested brackets", input: "text[[5]]"},
+		{testName: "text inside brackets", input: "text[3][a]"},
+		{testName: "spaces inside brackets", input: "text[3][ 4]"},
+		{testName: "space between brackets", input: "text[3] [4]"},
+	}
+
+	for _, tt := range tests {
+		_, err := ExtractDimensionsInBrackets(tt.input)
+		assert.Error(suite.T(), err, "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *EngineTestSuite) TestSanitizeJsonMap_NestedArrays() {
+	input := toOrderedValue(map[string]any{
+		"employees[2]": []any{
+			map[string]any{"phones[2]": []any{"a", "b"}},
+			[]any{map[string]any{"matrix[1][1]": []any{[]any{"c"}}}},
+		},
+	}).(*OrderedMap)
+	sanitizeJsonMap(input)
+	assert.Equal(suite.T(), map[string]any{
+		"employees": []any{
+			map[string]any{"phones": []any{"a", "b"}},
+			[]any{map[string]any{"matrix": []any{[]any{"c"}}}},
+		},
+	}, fromOrderedValue(input))
+}
+
+func (suite *EngineTestSuite) TestSanitizeKeyWithBrackets_ValidInputs() {
+	tests := []struct {
+		testName          string
+		input             string
+		expectedSanitized string
+	}{
+		{
+			testName:          "test 1",
+			input:             "",
+			expectedSanitized: "",
+		},
+		{
+			testName:          "test 2",
+			input:             "text",
+			expectedSanitized: "text",
+		},
+		{
+			testName:          "test 3",
+			input:             "text[10]",
+			expectedSanitized: "text",
+		},
+		{
+			testName:          "test 4",
+			input:             "text[10].template.json",
+			expectedSanitized: "text.template.json",
+		},
+		{
+			testName:          "test 5",
+			input:             "te[5]xt",
+			expectedSanitized: "text",
+		},
+		{
+			testName:          "test 6",
+			input:             "text10]",
+			expectedSanitized: "text10]",
+		},
+		{
+			testName:          "test 7",
+			input:             "text]1[",
+			expectedSanitized: "text]1[",
+		},
+		{
+			testName:          "test 8",
+			input:             "matrix[3][4]",
+			expectedSanitized: "matrix",
+		},
+	}
+
+	for _, tt := range tests {
+		sanitized := SanitizeKeyWithBrackets(tt.input)
+		assert.Equal(suite.T(), tt.expectedSanitized, sanitized, "Test case '%s' failed", tt.testName)
+	}
+}
@@ -0,0 +1,265 @@
+package engine
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	includeKey = "$include"
+	refKey     = "$ref"
+	defsKey    = "$defs"
+	defsPrefix = "#/$defs/"
+)
+
+// Key of the settings section of a template, read by the commands and dropped from included templates.
+const MetaKey = "$meta"
+
+// Where a template (or an included template) lives, and the "$defs" declared in it.
+type templateScope struct {
+	dir  string
+	file string
+	defs *OrderedMap
+}
+
+// Resolves "$include" and "$ref" nodes, caching loaded files and tracking what is being expanded to detect cycles.
+type templateResolver struct {
+	loaded map[string]any
+	stack  []string
+}
+
+// Resolves every "$include" and "$ref" node of a parsed template, returning the resolved template.
+// Included files are resolved relative to `baseDir`, which must be the directory of the template being parsed.
+// The root "$defs" section is removed from the template after resolution.
+func ResolveTemplateRefs(template any, baseDir string) (any, error) {
+	resolved, _, err := ResolveTemplateRefsAndFiles(template, baseDir)
+	return resolved, err
+}
+
+// Resolves a parsed template as ResolveTemplateRefs does, also returning the (absolute) paths of the files it loaded.
+func ResolveTemplateRefsAndFiles(template any, baseDir string) (any, []string, error) {
+	resolver := &templateResolver{loaded: make(map[string]any)}
+	resolved, err := resolver.resolveRoot(template, baseDir, "")
+	files := slices.Sorted(maps.Keys(resolver.loaded))
+	return resolved, files, err
+}
+
+// Resolves the root of a template, which is the only place where "$defs" may be declared.
+// The "$meta" section of included templates is dropped, only the settings of the generated template apply.
+func (r *templateResolver) resolveRoot(template any, dir string, file string) (any, error) {
+	rootMap, ok := template.(*OrderedMap)
+	if !ok {
+		return r.resolveValue(template, &templateScope{dir: dir, file: file, defs: NewOrderedMap()})
+	}
+	rootMap.Remove(MetaKey)
+	scope, err := newTemplateScope(rootMap, dir, file)
+	if err != nil {
+		return nil, err
+	}
+	return r.resolveNode(rootMap, scope)
+}
+
+// Extracts the "$defs" section of a template (removing it from the template).
+func newTemplateScope(parseMap *OrderedMap, dir string, file string) (*templateScope, error) {
+	scope := &templateScope{dir: dir, file: file, defs: NewOrderedMap()}
+	rawDefs, ok := parseMap.Get(defsKey)
+	if !ok {
+		return scope, nil
+	}
+	defs, ok := rawDefs.(*OrderedMap)
+	if !ok {
+		return nil, fmt.Errorf("invalid '%s' in '%s' (must be an object)", defsKey, scope.name())
+	}
+	parseMap.Remove(defsKey)
+	scope.defs = defs
+	return scope, nil
+}
+
+func (s *templateScope) name() string {
+	if s.file == "" {
+		return "template"
+	}
+	return s.file
+}
+
+// Resolves a single template value, recursing into objects and arrays.
+func (r *templateResolver) resolveValue(value any, scope *templateScope) (any, error) {
+	switch typedValue := value.(type) {
+	case *OrderedMap:
+		return r.resolveNode(typedValue, scope)
+	case []any:
+		for itemKey, item := range typedValue {
+			resolvedItem, err := r.resolveValue(item, scope)
+			if err != nil {
+				return nil, err
+			}
+			typedValue[itemKey] = resolvedItem
+		}
+		return typedValue, nil
+	default:
+		return value, nil
+	}
+}
+
+// Resolves an object node. If the object has an "$include" or "$ref", its content is expanded in its place and the
+// remaining keys of the object are merged over it (overriding the keys with the same name).
+func (r *templateResolver) resolveNode(node *OrderedMap, scope *templateScope) (any, error) {
+	includePath, hasInclude := node.Get(includeKey)
+	refPath, hasRef := node.Get(refKey)
+	if hasInclude && hasRef {
+		return nil, fmt.Errorf("an object cannot have both '%s' and '%s' in '%s'", includeKey, refKey, scope.name())
+	}
+
+	// Resolve the other keys of the object first
+	for _, objKey := range node.Keys() {
+		if objKey == includeKey || objKey == refKey {
+			continue
+		}
+		objValue, _ := node.Get(objKey)
+		resolvedValue, err := r.resolveValue(objValue, scope)
+		if err != nil {
+			return nil, err
+		}
+		node.Set(objKey, resolvedValue)
+	}
+
+	if !hasInclude && !hasRef {
+		return node, nil
+	}
+
+	var expanded any
+	var err error
+	expandedKey := refKey
+	if hasInclude {
+		expandedKey = includeKey
+		includeStr, ok := includePath.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid '%s' value '%v' (must be a file path)", includeKey, includePath)
+		}
+		expanded, err = r.expandInclude(includeStr, scope)
+	} else {
+		refStr, ok := refPath.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid '%s' value '%v' (must be '%s<name>')", refKey, refPath, defsPrefix)
+		}
+		expanded, err = r.expandRef(refStr, scope)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Nothing to merge, the node is fully replaced
+	if node.Size() == 1 {
+		return expanded, nil
+	}
+	expandedMap, ok := expanded.(*OrderedMap)
+	if !ok {
+		return nil, fmt.Errorf("cannot merge keys into a non object '%s'/'%s' in '%s'", includeKey, refKey, scope.name())
+	}
+	node.Merge(expandedKey, expandedMap, false)
+	return node, nil
+}
+
+// Loads and resolves an included template file, relative to the including template.
+func (r *templateResolver) expandInclude(includePath string, scope *templateScope) (any, error) {
+	absPath, err := r.absPath(includePath, scope)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.push(absPath); err != nil {
+		return nil, err
+	}
+	defer r.pop()
+
+	included, err := r.load(absPath)
+	if err != nil {
+		return nil, err
+	}
+	return r.resolveRoot(included, filepath.Dir(absPath), absPath)
+}
+
+// Resolves a reference to a definition, either local ("#/$defs/name") or from another file ("file.template.json#/$defs/name").
+func (r *templateResolver) expandRef(ref string, scope *templateScope) (any, error) {
+	filePart, defName, found := strings.Cut(ref, defsPrefix)
+	if !found || defName == "" {
+		return nil, fmt.Errorf("invalid format '%s' (must be '%s<name>' or '<file>%s<name>')", ref, defsPrefix, defsPrefix)
+	}
+
+	defScope := scope
+	if filePart != "" {
+		absPath, err := r.absPath(filePart, scope)
+		if err != nil {
+			return nil, err
+		}
+		refFile, err := r.load(absPath)
+		if err != nil {
+			return nil, err
+		}
+		refMap, ok := refFile.(*OrderedMap)
+		if !ok {
+			return nil, fmt.Errorf("'%s' has no '%s' (it is not an object)", absPath, defsKey)
+		}
+		defScope, err = newTemplateScope(refMap, filepath.Dir(absPath), absPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	def, ok := defScope.defs.Get(defName)
+	if !ok {
+		return nil, fmt.Errorf("definition '%s' not found in '%s'", defName, defScope.name())
+	}
+
+	if err := r.push(defScope.file + defsPrefix + defName); err != nil {
+		return nil, err
+	}
+	defer r.pop()
+
+	return r.resolveValue(CloneValue(def), defScope)
+}
+
+func (r *templateResolver) absPath(path string, scope *templateScope) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(scope.dir, path)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path '%s' '%w'", path, err)
+	}
+	return absPath, nil
+}
+
+// Reads and parses a template file, returning a copy of it so it can be freely modified.
+func (r *templateResolver) load(absPath string) (any, error) {
+	if parsed, ok := r.loaded[absPath]; ok {
+		return CloneValue(parsed), nil
+	}
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read included file '%w'", err)
+	}
+	parsed, err := UnmarshalTemplateFile(absPath, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse included file '%s' '%w'", absPath, err)
+	}
+	r.loaded[absPath] = parsed
+	return CloneValue(parsed), nil
+}
+
+func (r *templateResolver) push(id string) error {
+	for _, stacked := range r.stack {
+		if stacked == id {
+			return fmt.Errorf("cyclic reference detected '%s'", strings.Join(append(r.stack, id), "' -> '"))
+		}
+	}
+	r.stack = append(r.stack, id)
+	return nil
+}
+
+func (r *templateResolver) pop() {
+	r.stack = r.stack[:len(r.stack)-1]
+}
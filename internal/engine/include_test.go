@@ -0,0 +1,244 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type IncludeTestSuite struct {
+	suite.Suite
+}
+
+func TestIncludeTestSuite(t *testing.T) {
+	suite.Run(t, new(IncludeTestSuite))
+}
+
+// Writes template files (relative path -> content) into a temporary directory, returning the directory.
+func (suite *IncludeTestSuite) writeTemplates(files map[string]string) string {
+	dir := suite.T().TempDir()
+	for relPath, content := range files {
+		path := filepath.Join(dir, relPath)
+		suite.Require().NoError(os.MkdirAll(filepath.Dir(path), 0755))
+		suite.Require().NoError(os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func (suite *IncludeTestSuite) TestResolveTemplateRefs_ValidInputs() {
+	tests := []struct {
+		testName string
+		files    map[string]string
+		input    any
+		expected any
+	}{
+		{
+			testName: "no references",
+			input: map[string]any{
+				"name": "{{ Person.name }}",
+			},
+			expected: map[string]any{
+				"name": "{{ Person.name }}",
+			},
+		},
+		{
+			testName: "local definition with count in the key",
+			input: map[string]any{
+				"$defs": map[string]any{
+					"address": map[string]any{"city": "{{ Address.city }}"},
+				},
+				"addresses[3]": map[string]any{"$ref": "#/$defs/address"},
+			},
+			expected: map[string]any{
+				"addresses[3]": map[string]any{"city": "{{ Address.city }}"},
+			},
+		},
+		{
+			testName: "definition referencing another definition",
+			input: map[string]any{
+				"$defs": map[string]any{
+					"address": map[string]any{"city": "{{ Address.city }}"},
+					"person":  map[string]any{"name": "{{ Person.name }}", "address": map[string]any{"$ref": "#/$defs/address"}},
+				},
+				"employee": map[string]any{"$ref": "#/$defs/person"},
+			},
+			expected: map[string]any{
+				"employee": map[string]any{"name": "{{ Person.name }}", "address": map[string]any{"city": "{{ Address.city }}"}},
+			},
+		},
+		{
+			testName: "string definition",
+			input: map[string]any{
+				"$defs": map[string]any{"name": "{{ Person.name }}"},
+				"names": []any{map[string]any{"$ref": "#/$defs/name"}, "raw"},
+			},
+			expected: map[string]any{
+				"names": []any{"{{ Person.name }}", "raw"},
+			},
+		},
+		{
+			testName: "include with keys overriding the included ones",
+			files: map[string]string{
+				"common/address.template.json": `{ "city": "{{ Address.city }}", "country": "{{ Address.country }}" }`,
+			},
+			input: map[string]any{
+				"address": map[string]any{"$include": "common/address.template.json", "country": "Brazil"},
+			},
+			expected: map[string]any{
+				"address": map[string]any{"city": "{{ Address.city }}", "country": "Brazil"},
+			},
+		},
+		{
+			testName: "include relative to the included file, using its own definitions",
+			files: map[string]string{
+				"common/person.template.json":  `{ "$defs": { "name": "{{ Person.name }}" }, "name": { "$ref": "#/$defs/name" }, "address": { "$include": "address.template.json" } }`,
+				"common/address.template.json": `{ "city": "{{ Address.city }}" }`,
+			},
+			input: map[string]any{
+				"people[2]": map[string]any{"$include": "common/person.template.json"},
+			},
+			expected: map[string]any{
+				"people[2]": map[string]any{"name": "{{ Person.name }}", "address": map[string]any{"city": "{{ Address.city }}"}},
+			},
+		},
+		{
+			testName: "definition from another file",
+			files: map[string]string{
+				"defs.template.json": `{ "$defs": { "address": { "city": "{{ Address.city }}" } } }`,
+			},
+			input: map[string]any{
+				"address": map[string]any{"$ref": "defs.template.json#/$defs/address"},
+			},
+			expected: map[string]any{
+				"address": map[string]any{"city": "{{ Address.city }}"},
+			},
+		},
+		{
+			testName: "include at the root",
+			files: map[string]string{
+				"base.template.json": `{ "id": "{{ UUID.uuidv4 }}" }`,
+			},
+			input: map[string]any{
+				"$include": "base.template.json",
+				"name":     "{{ Person.name }}",
+			},
+			expected: map[string]any{
+				"id":   "{{ UUID.uuidv4 }}",
+				"name": "{{ Person.name }}",
+			},
+		},
+		{
+			testName: "array root with an included array",
+			files: map[string]string{
+				"names.template.json": `[ "{{ Person.name }}", "{{ Person.firstName }}" ]`,
+			},
+			input: []any{
+				map[string]any{"$include": "names.template.json"},
+				"raw",
+			},
+			expected: []any{
+				[]any{"{{ Person.name }}", "{{ Person.firstName }}"},
+				"raw",
+			},
+		},
+		{
+			testName: "same definition used twice (not a cycle)",
+			input: map[string]any{
+				"$defs": map[string]any{"city": "{{ Address.city }}"},
+				"a":     map[string]any{"$ref": "#/$defs/city"},
+				"b":     map[string]any{"$ref": "#/$defs/city"},
+			},
+			expected: map[string]any{
+				"a": "{{ Address.city }}",
+				"b": "{{ Address.city }}",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		dir := suite.writeTemplates(tt.files)
+		result, err := ResolveTemplateRefs(toOrderedValue(tt.input), dir)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", tt.testName)
+		assert.Equal(suite.T(), tt.expected, fromOrderedValue(result), "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *IncludeTestSuite) TestResolveTemplateRefs_InvalidInputs() {
+	tests := []struct {
+		testName string
+		files    map[string]string
+		input    map[string]any
+	}{
+		{
+			testName: "missing definition",
+			input: map[string]any{
+				"address": map[string]any{"$ref": "#/$defs/address"},
+			},
+		},
+		{
+			testName: "invalid reference format",
+			input: map[string]any{
+				"$defs":   map[string]any{"address": map[string]any{}},
+				"address": map[string]any{"$ref": "#/definitions/address"},
+			},
+		},
+		{
+			testName: "definitions not being an object",
+			input: map[string]any{
+				"$defs": "address",
+			},
+		},
+		{
+			testName: "missing included file",
+			input: map[string]any{
+				"address": map[string]any{"$include": "missing.template.json"},
+			},
+		},
+		{
+			testName: "both include and reference",
+			files: map[string]string{
+				"address.template.json": `{}`,
+			},
+			input: map[string]any{
+				"$defs":   map[string]any{"address": map[string]any{}},
+				"address": map[string]any{"$include": "address.template.json", "$ref": "#/$defs/address"},
+			},
+		},
+		{
+			testName: "merging keys into a string definition",
+			input: map[string]any{
+				"$defs":   map[string]any{"city": "{{ Address.city }}"},
+				"address": map[string]any{"$ref": "#/$defs/city", "country": "Brazil"},
+			},
+		},
+		{
+			testName: "cyclic definitions",
+			input: map[string]any{
+				"$defs": map[string]any{
+					"a": map[string]any{"b": map[string]any{"$ref": "#/$defs/b"}},
+					"b": map[string]any{"a": map[string]any{"$ref": "#/$defs/a"}},
+				},
+				"root": map[string]any{"$ref": "#/$defs/a"},
+			},
+		},
+		{
+			testName: "cyclic includes",
+			files: map[string]string{
+				"a.template.json": `{ "b": { "$include": "b.template.json" } }`,
+				"b.template.json": `{ "a": { "$include": "a.template.json" } }`,
+			},
+			input: map[string]any{
+				"a": map[string]any{"$include": "a.template.json"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		dir := suite.writeTemplates(tt.files)
+		_, err := ResolveTemplateRefs(toOrderedValue(tt.input), dir)
+		assert.Error(suite.T(), err, "Test case '%s' failed", tt.testName)
+	}
+}
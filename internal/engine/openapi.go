@@ -0,0 +1,332 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lfsc09/k-test-n-stress/mocker"
+)
+
+// Parts of an OpenAPI operation that can be generated with --openapi-part.
+const (
+	OpenapiPartAll      = "all"
+	OpenapiPartRequest  = "request"
+	OpenapiPartQuery    = "query"
+	OpenapiPartResponse = "response"
+)
+
+// Returns the valid values of --openapi-part.
+func OpenapiParts() []string {
+	return []string{OpenapiPartAll, OpenapiPartRequest, OpenapiPartQuery, OpenapiPartResponse}
+}
+
+// Methods of the operations of an OpenAPI path item, in the order they are searched.
+var openapiMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// A parameter of an OpenAPI operation (e.g. a query parameter) and the schema of its values.
+type openapiParameter struct {
+	name     string
+	in       string
+	required bool
+	schema   *schemaValue
+}
+
+// Generates the requests (path, query parameters and body) and responses of an operation of an OpenAPI document.
+type openapiOperation struct {
+	method         string
+	path           string
+	part           string
+	parameters     []openapiParameter
+	requestBody    *schemaValue
+	responseStatus any
+	responseBody   *schemaValue
+}
+
+// Reads an OpenAPI 3 document (and the files it references), finding the operation by its operationId or by its
+// method and path (e.g. "POST /users"). `status` picks the response, the first successful one when empty.
+func NewOpenapiOperation(path string, operation string, part string, status string) (*openapiOperation, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path '%w'", err)
+	}
+	documents := make(map[string]any)
+	if err := loadSchemaDocument(absPath, documents); err != nil {
+		return nil, err
+	}
+	document, ok := documents[absPath].(*OrderedMap)
+	if ok {
+		version, _ := schemaKeyword(document, "openapi").(string)
+		ok = strings.HasPrefix(version, "3.")
+	}
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not an OpenAPI 3 document", path)
+	}
+	// Every lookup goes through a schemaValue, to resolve the `$ref`s of parameters, bodies and responses as well
+	lookup := newSchemaValueFrom(document, documents, absPath)
+
+	method, operationPath, operationNode, pathItem, err := findOpenapiOperation(document, operation)
+	if err != nil {
+		return nil, err
+	}
+	parsed := &openapiOperation{method: strings.ToUpper(method), path: operationPath, part: part}
+
+	// Parameters of the path item apply to all of its operations, unless the operation redefines them
+	for _, parametersOwner := range []*OrderedMap{pathItem, operationNode} {
+		parameters, _ := schemaKeyword(parametersOwner, "parameters").([]any)
+		for _, item := range parameters {
+			parameter, err := lookup.openapiParameter(item, absPath)
+			if err != nil {
+				return nil, fmt.Errorf("invalid parameter of '%s' '%w'", operation, err)
+			}
+			parsed.parameters = slices.DeleteFunc(parsed.parameters, func(existing openapiParameter) bool {
+				return existing.name == parameter.name && existing.in == parameter.in
+			})
+			parsed.parameters = append(parsed.parameters, parameter)
+		}
+	}
+
+	if requestBody, ok := schemaKeyword(operationNode, "requestBody").(*OrderedMap); ok {
+		parsed.requestBody, err = lookup.openapiMediaSchema(requestBody, absPath, "readOnly")
+		if err != nil {
+			return nil, fmt.Errorf("invalid request body of '%s' '%w'", operation, err)
+		}
+	}
+
+	if responses, ok := schemaKeyword(operationNode, "responses").(*OrderedMap); ok && responses.Size() > 0 {
+		responseStatus := pickOpenapiStatus(responses, status)
+		if responseStatus == "" {
+			return nil, fmt.Errorf("operation '%s' has no '%s' response", operation, status)
+		}
+		response, _ := responses.Get(responseStatus)
+		responseMap, ok := response.(*OrderedMap)
+		if !ok {
+			return nil, fmt.Errorf("invalid '%s' response of '%s'", responseStatus, operation)
+		}
+		parsed.responseBody, err = lookup.openapiMediaSchema(responseMap, absPath, "writeOnly")
+		if err != nil {
+			return nil, fmt.Errorf("invalid '%s' response of '%s' '%w'", responseStatus, operation, err)
+		}
+		parsed.responseStatus = responseStatus
+		if code, err := strconv.Atoi(responseStatus); err == nil {
+			parsed.responseStatus = float64(code)
+		}
+	} else if status != "" {
+		return nil, fmt.Errorf("operation '%s' has no '%s' response", operation, status)
+	}
+
+	switch {
+	case part == OpenapiPartRequest && parsed.requestBody == nil:
+		return nil, fmt.Errorf("operation '%s' has no request body", operation)
+	case part == OpenapiPartResponse && parsed.responseBody == nil:
+		return nil, fmt.Errorf("operation '%s' has no response body", operation)
+	}
+	return parsed, nil
+}
+
+// Finds an operation of the document by its operationId, or by its method and path (e.g. "POST /users").
+// Returns its method, path, node and the node of its path item.
+func findOpenapiOperation(document *OrderedMap, operation string) (string, string, *OrderedMap, *OrderedMap, error) {
+	paths, _ := schemaKeyword(document, "paths").(*OrderedMap)
+	if paths == nil {
+		return "", "", nil, nil, fmt.Errorf("the OpenAPI document has no 'paths'")
+	}
+	wantedMethod, wantedPath, byPath := strings.Cut(strings.TrimSpace(operation), " ")
+	wantedPath = strings.TrimSpace(wantedPath)
+	for _, operationPath := range paths.Keys() {
+		pathValue, _ := paths.Get(operationPath)
+		pathItem, ok := pathValue.(*OrderedMap)
+		if !ok {
+			continue
+		}
+		for _, method := range openapiMethods {
+			operationNode, ok := schemaKeyword(pathItem, method).(*OrderedMap)
+			if !ok {
+				continue
+			}
+			operationId, _ := schemaKeyword(operationNode, "operationId").(string)
+			if operationId == operation || (byPath && strings.EqualFold(method, wantedMethod) && operationPath == wantedPath) {
+				return method, operationPath, operationNode, pathItem, nil
+			}
+		}
+	}
+	return "", "", nil, nil, fmt.Errorf("operation '%s' not found (pass an operationId or a method and path, e.g. \"POST /users\")", operation)
+}
+
+// Returns the response of the given status (e.g. "201", "2XX" or "default"), or the first successful one when
+// no status is given (falling back to "default" and then to the first response).
+func pickOpenapiStatus(responses *OrderedMap, status string) string {
+	if status != "" {
+		if responses.Has(status) {
+			return status
+		}
+		return ""
+	}
+	for _, responseStatus := range responses.Keys() {
+		if strings.HasPrefix(responseStatus, "2") {
+			return responseStatus
+		}
+	}
+	if responses.Has("default") {
+		return "default"
+	}
+	return responses.Keys()[0]
+}
+
+// Parses a parameter of an operation, resolving its `$ref` (e.g. "#/components/parameters/Limit").
+func (s *schemaValue) openapiParameter(item any, path string) (openapiParameter, error) {
+	node, ok := item.(*OrderedMap)
+	if !ok {
+		return openapiParameter{}, fmt.Errorf("parameters must be objects")
+	}
+	node, path, err := s.resolve(node, path, 0)
+	if err != nil {
+		return openapiParameter{}, err
+	}
+	name, _ := schemaKeyword(node, "name").(string)
+	in, _ := schemaKeyword(node, "in").(string)
+	if name == "" || in == "" {
+		return openapiParameter{}, fmt.Errorf("parameters must have a 'name' and an 'in'")
+	}
+	required, _ := schemaKeyword(node, "required").(bool)
+	schema, ok := node.Get("schema")
+	if !ok {
+		// Parameters may describe their value with a media type instead of a schema
+		content, _ := schemaKeyword(node, "content").(*OrderedMap)
+		schema = pickOpenapiMediaSchema(content)
+	}
+	if schema == nil {
+		schema = true
+	}
+	return openapiParameter{
+		name:     name,
+		in:       in,
+		required: required || in == "path",
+		schema:   &schemaValue{schema: schema, documents: s.documents, path: path},
+	}, nil
+}
+
+// Returns the schema of the content of a request body or response (resolving their `$ref`s), or nil when it has no
+// content. Properties with `skipKeyword` (e.g. "readOnly" in requests) are left out.
+func (s *schemaValue) openapiMediaSchema(node *OrderedMap, path string, skipKeyword string) (*schemaValue, error) {
+	node, path, err := s.resolve(node, path, 0)
+	if err != nil {
+		return nil, err
+	}
+	content, _ := schemaKeyword(node, "content").(*OrderedMap)
+	schema := pickOpenapiMediaSchema(content)
+	if schema == nil {
+		return nil, nil
+	}
+	return &schemaValue{schema: schema, documents: s.documents, path: path, skipKeyword: skipKeyword}, nil
+}
+
+// Returns the schema of the JSON media type of a content, or of its first media type with a schema.
+func pickOpenapiMediaSchema(content *OrderedMap) any {
+	if content == nil {
+		return nil
+	}
+	var first any
+	for _, mediaType := range content.Keys() {
+		media, _ := content.Get(mediaType)
+		mediaMap, ok := media.(*OrderedMap)
+		if !ok {
+			continue
+		}
+		schema, ok := mediaMap.Get("schema")
+		if !ok {
+			continue
+		}
+		baseType, _, _ := strings.Cut(mediaType, ";")
+		if baseType == "application/json" || strings.HasSuffix(baseType, "+json") {
+			return schema
+		}
+		if first == nil {
+			first = schema
+		}
+	}
+	return first
+}
+
+func (o *openapiOperation) generateValue(mocker mocker.Mocker) (any, error) {
+	switch o.part {
+	case OpenapiPartRequest:
+		return o.requestBody.generateValue(mocker)
+	case OpenapiPartQuery:
+		return o.generateParameters("query", mocker)
+	case OpenapiPartResponse:
+		return o.responseBody.generateValue(mocker)
+	}
+
+	record := NewOrderedMap()
+	record.Set("method", o.method)
+	pathParameters, err := o.generateParameters("path", mocker)
+	if err != nil {
+		return nil, err
+	}
+	requestPath := o.path
+	for _, name := range pathParameters.Keys() {
+		value, _ := pathParameters.Get(name)
+		requestPath = strings.ReplaceAll(requestPath, "{"+name+"}", url.PathEscape(openapiParameterText(value)))
+	}
+	record.Set("path", requestPath)
+	query, err := o.generateParameters("query", mocker)
+	if err != nil {
+		return nil, err
+	}
+	record.Set("query", query)
+	if o.requestBody != nil {
+		body, err := o.requestBody.generateValue(mocker)
+		if err != nil {
+			return nil, fmt.Errorf("request body: %w", err)
+		}
+		record.Set("body", body)
+	}
+	if o.responseStatus != nil {
+		response := NewOrderedMap()
+		response.Set("status", o.responseStatus)
+		if o.responseBody != nil {
+			body, err := o.responseBody.generateValue(mocker)
+			if err != nil {
+				return nil, fmt.Errorf("response body: %w", err)
+			}
+			response.Set("body", body)
+		}
+		record.Set("response", response)
+	}
+	return record, nil
+}
+
+// Generates the parameters of a location (e.g. "query"). Required parameters are always generated, and the others
+// half of the times.
+func (o *openapiOperation) generateParameters(in string, mocker mocker.Mocker) (*OrderedMap, error) {
+	parameters := NewOrderedMap()
+	for _, parameter := range o.parameters {
+		if parameter.in != in || (!parameter.required && randOf(mocker).Intn(2) == 0) {
+			continue
+		}
+		value, err := parameter.schema.generateValue(mocker)
+		if err != nil {
+			return nil, fmt.Errorf("%s parameter '%s': %w", in, parameter.name, err)
+		}
+		parameters.Set(parameter.name, value)
+	}
+	return parameters, nil
+}
+
+// Returns the text of a parameter value, as written in a path.
+func openapiParameterText(value any) string {
+	switch typedValue := value.(type) {
+	case string:
+		return typedValue
+	case float64:
+		return strconv.FormatFloat(typedValue, 'f', -1, 64)
+	default:
+		encoded, _ := json.Marshal(typedValue)
+		return string(encoded)
+	}
+}
@@ -0,0 +1,215 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lfsc09/k-test-n-stress/mocker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type OpenapiTestSuite struct {
+	suite.Suite
+	documentPath string
+}
+
+func TestOpenapiTestSuite(t *testing.T) {
+	suite.Run(t, new(OpenapiTestSuite))
+}
+
+const openapiTestDocument = `
+openapi: 3.0.3
+info:
+  title: Users
+  version: "1.0"
+paths:
+  /users:
+    post:
+      operationId: createUser
+      parameters:
+        - $ref: "#/components/parameters/DryRun"
+      requestBody:
+        $ref: "#/components/requestBodies/User"
+      responses:
+        "201":
+          description: Created
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/User"
+        "400":
+          description: Invalid
+          content:
+            application/problem+json:
+              schema:
+                type: object
+                required: [title]
+                properties:
+                  title: { type: string, const: Invalid user }
+  /users/{id}:
+    parameters:
+      - name: id
+        in: path
+        schema: { type: integer, minimum: 1, maximum: 9 }
+    get:
+      parameters:
+        - name: fields
+          in: query
+          required: true
+          schema: { type: string, enum: [name, email] }
+      responses:
+        default:
+          description: The user
+          content:
+            application/json:
+              schema:
+                $ref: "shared.schema.json#/User"
+components:
+  parameters:
+    DryRun:
+      name: dryRun
+      in: query
+      required: true
+      schema: { type: boolean }
+  requestBodies:
+    User:
+      content:
+        application/json:
+          schema:
+            $ref: "#/components/schemas/User"
+  schemas:
+    User:
+      type: object
+      required: [id, email, password]
+      properties:
+        id: { type: string, format: uuid, readOnly: true }
+        email: { type: string, format: email }
+        password: { type: string, minLength: 8, writeOnly: true }
+`
+
+func (suite *OpenapiTestSuite) SetupTest() {
+	dir := suite.T().TempDir()
+	suite.documentPath = filepath.Join(dir, "api.yaml")
+	suite.Require().NoError(os.WriteFile(suite.documentPath, []byte(openapiTestDocument), 0644))
+	suite.Require().NoError(os.WriteFile(filepath.Join(dir, "shared.schema.json"), []byte(`{ "User": { "type": "object", "required": ["name"], "properties": { "name": { "const": "Ada" } } } }`), 0644))
+}
+
+func (suite *OpenapiTestSuite) generate(operation string, part string, status string) (*OrderedMap, error) {
+	parsed, err := NewOpenapiOperation(suite.documentPath, operation, part, status)
+	if err != nil {
+		return nil, err
+	}
+	value, err := parsed.generateValue(mocker.NewWithSeed(7))
+	if err != nil {
+		return nil, err
+	}
+	return value.(*OrderedMap), nil
+}
+
+func (suite *OpenapiTestSuite) TestOpenapiOperation_Parts() {
+	tests := []struct {
+		testName  string
+		operation string
+		part      string
+		status    string
+		check     func(value *OrderedMap)
+	}{
+		{
+			testName:  "request body leaves readOnly properties out",
+			operation: "createUser",
+			part:      OpenapiPartRequest,
+			check: func(value *OrderedMap) {
+				assert.Equal(suite.T(), []string{"email", "password"}, value.Keys())
+			},
+		},
+		{
+			testName:  "response body leaves writeOnly properties out",
+			operation: "POST /users",
+			part:      OpenapiPartResponse,
+			check: func(value *OrderedMap) {
+				assert.Equal(suite.T(), []string{"id", "email"}, value.Keys())
+			},
+		},
+		{
+			testName:  "response of another status",
+			operation: "createUser",
+			part:      OpenapiPartResponse,
+			status:    "400",
+			check: func(value *OrderedMap) {
+				assert.Equal(suite.T(), "Invalid user", schemaKeyword(value, "title"))
+			},
+		},
+		{
+			testName:  "query parameters",
+			operation: "createUser",
+			part:      OpenapiPartQuery,
+			check: func(value *OrderedMap) {
+				_, isBool := schemaKeyword(value, "dryRun").(bool)
+				assert.True(suite.T(), isBool)
+			},
+		},
+		{
+			testName:  "whole operation with path parameters and a response from another file",
+			operation: "get /users/{id}",
+			part:      OpenapiPartAll,
+			check: func(value *OrderedMap) {
+				assert.Equal(suite.T(), []string{"method", "path", "query", "response"}, value.Keys())
+				assert.Equal(suite.T(), "GET", schemaKeyword(value, "method"))
+				assert.Regexp(suite.T(), `^/users/[1-9]$`, schemaKeyword(value, "path"))
+				assert.Contains(suite.T(), []any{"name", "email"}, schemaKeyword(schemaKeyword(value, "query").(*OrderedMap), "fields"))
+				response := schemaKeyword(value, "response").(*OrderedMap)
+				assert.Equal(suite.T(), "default", schemaKeyword(response, "status"))
+				assert.Equal(suite.T(), "Ada", schemaKeyword(schemaKeyword(response, "body").(*OrderedMap), "name"))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		value, err := suite.generate(test.operation, test.part, test.status)
+		if assert.NoError(suite.T(), err, "Test case '%s' failed", test.testName) {
+			test.check(value)
+		}
+	}
+}
+
+func (suite *OpenapiTestSuite) TestOpenapiOperation_InvalidInputs() {
+	tests := []struct {
+		testName      string
+		operation     string
+		part          string
+		status        string
+		expectedError string
+	}{
+		{
+			testName:      "unknown operation",
+			operation:     "deleteUser",
+			part:          OpenapiPartAll,
+			expectedError: "operation 'deleteUser' not found (pass an operationId or a method and path, e.g. \"POST /users\")",
+		},
+		{
+			testName:      "unknown status",
+			operation:     "createUser",
+			part:          OpenapiPartAll,
+			status:        "404",
+			expectedError: "operation 'createUser' has no '404' response",
+		},
+		{
+			testName:      "operation without request body",
+			operation:     "GET /users/{id}",
+			part:          OpenapiPartRequest,
+			expectedError: "operation 'GET /users/{id}' has no request body",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := suite.generate(test.operation, test.part, test.status)
+		assert.EqualError(suite.T(), err, test.expectedError, "Test case '%s' failed", test.testName)
+	}
+
+	schemaPath := filepath.Join(suite.T().TempDir(), "user.schema.json")
+	suite.Require().NoError(os.WriteFile(schemaPath, []byte(`{ "type": "object" }`), 0644))
+	_, err := NewOpenapiOperation(schemaPath, "createUser", OpenapiPartAll, "")
+	assert.EqualError(suite.T(), err, "'"+schemaPath+"' is not an OpenAPI 3 document")
+}
@@ -0,0 +1,258 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// A JSON object that remembers the order of its keys, so the generated data keeps the key order of the template.
+// Keys generated by mock functions are marked in `generated`, as they are not template keys (e.g. their brackets are kept).
+type OrderedMap struct {
+	keys      []string
+	values    map[string]any
+	generated map[string]bool
+}
+
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{values: make(map[string]any)}
+}
+
+func (m *OrderedMap) Get(key string) (any, bool) {
+	value, ok := m.values[key]
+	return value, ok
+}
+
+// Sets the value of a key, keeping its position if it already exists or appending it otherwise.
+func (m *OrderedMap) Set(key string, value any) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *OrderedMap) Remove(key string) {
+	if _, ok := m.values[key]; !ok {
+		return
+	}
+	delete(m.values, key)
+	delete(m.generated, key)
+	for idx, objKey := range m.keys {
+		if objKey == key {
+			m.keys = append(m.keys[:idx], m.keys[idx+1:]...)
+			return
+		}
+	}
+}
+
+func (m *OrderedMap) Has(key string) bool {
+	_, ok := m.values[key]
+	return ok
+}
+
+// Marks a key as generated by a mock function.
+func (m *OrderedMap) MarkGenerated(key string) {
+	if m.generated == nil {
+		m.generated = make(map[string]bool)
+	}
+	m.generated[key] = true
+}
+
+func (m *OrderedMap) IsGenerated(key string) bool {
+	return m.generated[key]
+}
+
+func (m *OrderedMap) Size() int {
+	return len(m.keys)
+}
+
+// Returns a copy of the keys in order, so the map can be modified while iterating.
+func (m *OrderedMap) Keys() []string {
+	return append([]string(nil), m.keys...)
+}
+
+// Renames a key keeping its position. If the new key already exists, it is replaced.
+func (m *OrderedMap) Rename(oldKey string, newKey string) {
+	value, ok := m.values[oldKey]
+	if !ok || oldKey == newKey {
+		return
+	}
+	m.Remove(newKey)
+	delete(m.values, oldKey)
+	m.values[newKey] = value
+	for idx, objKey := range m.keys {
+		if objKey == oldKey {
+			m.keys[idx] = newKey
+			return
+		}
+	}
+}
+
+// Replaces the key `at` by the keys of `other`, in the position `at` was.
+// Keys of `other` that already exist keep their position, and their value is only replaced when `override` is true.
+func (m *OrderedMap) Merge(at string, other *OrderedMap, override bool) {
+	var inserted []string
+	for _, objKey := range other.keys {
+		if objKey != at && m.Has(objKey) {
+			if override {
+				m.values[objKey] = other.values[objKey]
+			}
+			continue
+		}
+		inserted = append(inserted, objKey)
+	}
+
+	position := len(m.keys)
+	for idx, objKey := range m.keys {
+		if objKey == at {
+			position = idx
+			break
+		}
+	}
+	if position < len(m.keys) {
+		delete(m.values, at)
+		m.keys = append(m.keys[:position], m.keys[position+1:]...)
+	}
+
+	keys := make([]string, 0, len(m.keys)+len(inserted))
+	keys = append(keys, m.keys[:position]...)
+	keys = append(keys, inserted...)
+	keys = append(keys, m.keys[position:]...)
+	m.keys = keys
+	for _, objKey := range inserted {
+		m.values[objKey] = other.values[objKey]
+	}
+}
+
+// Returns a deep copy of the map.
+func (m *OrderedMap) Clone() *OrderedMap {
+	cloned := &OrderedMap{
+		keys:   m.Keys(),
+		values: make(map[string]any, len(m.values)),
+	}
+	for objKey, objValue := range m.values {
+		cloned.values[objKey] = CloneValue(objValue)
+	}
+	for objKey := range m.generated {
+		cloned.MarkGenerated(objKey)
+	}
+	return cloned
+}
+
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for idx, objKey := range m.keys {
+		if idx > 0 {
+			buf.WriteByte(',')
+		}
+		encodedKey, err := json.Marshal(objKey)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		encodedValue, err := json.Marshal(m.values[objKey])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encodedValue)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Returns a deep copy of a template value (objects and arrays are copied, other values are immutable).
+func CloneValue(value any) any {
+	switch typedValue := value.(type) {
+	case *OrderedMap:
+		return typedValue.Clone()
+	case []any:
+		cloned := make([]any, len(typedValue))
+		for itemKey, item := range typedValue {
+			cloned[itemKey] = CloneValue(item)
+		}
+		return cloned
+	default:
+		return value
+	}
+}
+
+// Decodes a JSON document, keeping the order of the keys of its objects.
+// Objects are decoded as *OrderedMap, and every other value as `json.Unmarshal` would decode them into an `any`.
+func UnmarshalOrdered(content []byte) (any, error) {
+	return decodeOrdered(json.NewDecoder(bytes.NewReader(content)))
+}
+
+// Decodes a JSON document as UnmarshalOrdered does, but with its numbers as json.Number, so they are written back
+// exactly as they were read (e.g. integers larger than 2^53, which a float64 can't hold).
+func UnmarshalOrderedNumbers(content []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	return decodeOrdered(decoder)
+}
+
+func decodeOrdered(decoder *json.Decoder) (any, error) {
+	value, err := decodeOrderedValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid content after the top-level value")
+	}
+	return value, nil
+}
+
+func decodeOrderedValue(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+	switch delim {
+	case '{':
+		parseMap := NewOrderedMap()
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			objKey, ok := keyToken.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid object key '%v'", keyToken)
+			}
+			objValue, err := decodeOrderedValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			parseMap.Set(objKey, objValue)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return parseMap, nil
+	case '[':
+		array := []any{}
+		for decoder.More() {
+			item, err := decodeOrderedValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, item)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return array, nil
+	default:
+		return nil, fmt.Errorf("unexpected delimiter '%v'", delim)
+	}
+}
@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/lfsc09/k-test-n-stress/mocker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type OrderedMapTestSuite struct {
+	suite.Suite
+}
+
+func TestOrderedMapTestSuite(t *testing.T) {
+	suite.Run(t, new(OrderedMapTestSuite))
+}
+
+// Converts the maps of a value (written as map literals in the tests) into ordered maps, with their keys sorted.
+func toOrderedValue(value any) any {
+	switch typedValue := value.(type) {
+	case map[string]any:
+		objKeys := make([]string, 0, len(typedValue))
+		for objKey := range typedValue {
+			objKeys = append(objKeys, objKey)
+		}
+		sort.Strings(objKeys)
+		parseMap := NewOrderedMap()
+		for _, objKey := range objKeys {
+			parseMap.Set(objKey, toOrderedValue(typedValue[objKey]))
+		}
+		return parseMap
+	case []any:
+		converted := make([]any, len(typedValue))
+		for itemKey, item := range typedValue {
+			converted[itemKey] = toOrderedValue(item)
+		}
+		return converted
+	default:
+		return value
+	}
+}
+
+// Converts the ordered maps of a value back into maps, so they can be compared with map literals.
+func fromOrderedValue(value any) any {
+	switch typedValue := value.(type) {
+	case *OrderedMap:
+		converted := make(map[string]any, typedValue.Size())
+		for _, objKey := range typedValue.Keys() {
+			objValue, _ := typedValue.Get(objKey)
+			converted[objKey] = fromOrderedValue(objValue)
+		}
+		return converted
+	case []any:
+		converted := make([]any, len(typedValue))
+		for itemKey, item := range typedValue {
+			converted[itemKey] = fromOrderedValue(item)
+		}
+		return converted
+	default:
+		return value
+	}
+}
+
+func (suite *OrderedMapTestSuite) TestUnmarshalOrdered_ValidInputs() {
+	tests := []struct {
+		testName     string
+		input        string
+		expectedJSON string
+	}{
+		{
+			testName:     "keys not in alphabetical order",
+			input:        `{ "zeta": "z", "alpha": "a", "mid": "m" }`,
+			expectedJSON: `{"zeta":"z","alpha":"a","mid":"m"}`,
+		},
+		{
+			testName:     "nested objects and arrays",
+			input:        `[ { "b": { "y": 1, "x": [true, null] }, "a": 2.5 } ]`,
+			expectedJSON: `[{"b":{"y":1,"x":[true,null]},"a":2.5}]`,
+		},
+		{
+			testName:     "duplicated key keeps its first position",
+			input:        `{ "b": 1, "a": 2, "b": 3 }`,
+			expectedJSON: `{"b":3,"a":2}`,
+		},
+		{
+			testName:     "empty object and array",
+			input:        `{ "b": {}, "a": [] }`,
+			expectedJSON: `{"b":{},"a":[]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		value, err := UnmarshalOrdered([]byte(tt.input))
+		assert.NoError(suite.T(), err, "Test case '%s' failed", tt.testName)
+		encoded, err := json.Marshal(value)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", tt.testName)
+		assert.Equal(suite.T(), tt.expectedJSON, string(encoded), "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *OrderedMapTestSuite) TestUnmarshalOrdered_InvalidInputs() {
+	tests := []struct {
+		testName string
+		input    string
+	}{
+		{testName: "empty", input: ``},
+		{testName: "unterminated object", input: `{ "a": 1`},
+		{testName: "missing value", input: `{ "a": }`},
+		{testName: "content after the value", input: `{ "a": 1 } { "b": 2 }`},
+	}
+
+	for _, tt := range tests {
+		_, err := UnmarshalOrdered([]byte(tt.input))
+		assert.Error(suite.T(), err, "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *OrderedMapTestSuite) TestOrderedMap_Operations() {
+	parseMap := NewOrderedMap()
+	parseMap.Set("c", 1)
+	parseMap.Set("a", 2)
+	parseMap.Set("b", 3)
+	parseMap.Set("a", 4)
+	assert.Equal(suite.T(), []string{"c", "a", "b"}, parseMap.Keys())
+
+	parseMap.Rename("a", "d")
+	assert.Equal(suite.T(), []string{"c", "d", "b"}, parseMap.Keys())
+	value, _ := parseMap.Get("d")
+	assert.Equal(suite.T(), 4, value)
+
+	// Renaming into an existing key replaces it
+	parseMap.Rename("d", "b")
+	assert.Equal(suite.T(), []string{"c", "b"}, parseMap.Keys())
+
+	parseMap.Remove("c")
+	assert.Equal(suite.T(), []string{"b"}, parseMap.Keys())
+	assert.False(suite.T(), parseMap.Has("c"))
+
+	// Merging in the place of a key, keeping (or overriding) existing keys
+	parseMap = toOrderedValue(map[string]any{"a": 1, "b": 2, "c": 3}).(*OrderedMap)
+	parseMap.Merge("b", toOrderedValue(map[string]any{"a": 10, "x": 20, "y": 30}).(*OrderedMap), false)
+	assert.Equal(suite.T(), []string{"a", "x", "y", "c"}, parseMap.Keys())
+	value, _ = parseMap.Get("a")
+	assert.Equal(suite.T(), 1, value)
+	parseMap.Merge("x", toOrderedValue(map[string]any{"c": 40, "z": 50}).(*OrderedMap), true)
+	assert.Equal(suite.T(), []string{"a", "z", "y", "c"}, parseMap.Keys())
+	value, _ = parseMap.Get("c")
+	assert.Equal(suite.T(), 40, value)
+}
+
+func (suite *OrderedMapTestSuite) TestCloneValue() {
+	original := toOrderedValue(map[string]any{
+		"list":   []any{map[string]any{"key": "value"}},
+		"nested": map[string]any{"key": "value"},
+	})
+	cloned := CloneValue(original).(*OrderedMap)
+	nested, _ := cloned.Get("nested")
+	nested.(*OrderedMap).Set("key", "changed")
+	list, _ := cloned.Get("list")
+	list.([]any)[0].(*OrderedMap).Set("other", "added")
+
+	assert.Equal(suite.T(), map[string]any{
+		"list":   []any{map[string]any{"key": "value"}},
+		"nested": map[string]any{"key": "value"},
+	}, fromOrderedValue(original))
+}
+
+func (suite *OrderedMapTestSuite) TestProcessTemplate_KeepsKeyOrder() {
+	template, err := UnmarshalTemplate([]byte(`{
+		"zipCode": "{{ Address.city }}",
+		"name": "raw",
+		"phones[2]": "{{ Person.phoneNumber }}",
+		"$if": "true",
+		"$then": { "document": "cnpj", "name": "overridden" },
+		"{{ UUID.uuidv4 }}": "generated",
+		"address": { "street": "raw", "city": "raw" }
+	}`))
+	assert.NoError(suite.T(), err)
+	template, err = ResolveTemplateRefs(template, ".")
+	assert.NoError(suite.T(), err)
+
+	record, err := ProcessTemplate(template, mocker.New())
+	suite.Require().NoError(err)
+	SanitizeValue(record)
+
+	recordMap := record.(*OrderedMap)
+	objKeys := recordMap.Keys()
+	assert.Len(suite.T(), objKeys, 6)
+	assert.Equal(suite.T(), []string{"zipCode", "name", "phones", "document"}, objKeys[:4])
+	assert.Regexp(suite.T(), `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[0-9a-f]{4}-[0-9a-f]{12}$`, objKeys[4])
+	assert.Equal(suite.T(), "address", objKeys[5])
+	name, _ := recordMap.Get("name")
+	assert.Equal(suite.T(), "overridden", name)
+
+	encoded, err := json.Marshal(recordMap.values["address"])
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), `{"street":"raw","city":"raw"}`, string(encoded))
+}
@@ -0,0 +1,701 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lfsc09/k-test-n-stress/mocker"
+)
+
+// Annotation of a schema pinning the value of an instance to a template value (e.g. "x-ktns": "{{ Person.name }}").
+const schemaAnnotationKey = "x-ktns"
+
+// Below this depth optional properties are left out and arrays get their minimum amount of items, so recursive schemas end.
+const schemaOptionalDepth = 8
+
+// Depth at which a schema is considered infinitely recursive.
+const schemaMaxDepth = 64
+
+// Amount of items generated in arrays without `minItems` and `maxItems`.
+const schemaDefaultMaxItems = 3
+
+// Range of numbers generated without `minimum` and `maximum`.
+const schemaDefaultNumberRange = 1000.0
+
+// Largest amount of multiples of `multipleOf` from 0 that numbers are generated with, the integers exact in a float64.
+const schemaMaxMultiples = 1 << 53
+
+// Times a string of a `pattern` or `format` is generated until its length is between `minLength` and `maxLength`.
+const schemaStringAttempts = 100
+
+// A template value generated by code instead of mock functions, e.g. an instance of a JSON Schema.
+// It must be safe for concurrent use, the same value is generated by every worker.
+type valueGenerator interface {
+	generateValue(mocker mocker.Mocker) (any, error)
+}
+
+// Generates instances of a JSON Schema, resolving its `$ref`s in the documents loaded when it was created.
+type schemaValue struct {
+	schema    any
+	documents map[string]any
+	path      string
+	// Properties whose schema has this keyword (e.g. "readOnly") are left out, even when required.
+	skipKeyword string
+}
+
+// Reads a JSON Schema (written in any of the template file formats), loading every file it references.
+func NewSchemaValue(path string) (*schemaValue, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path '%w'", err)
+	}
+	documents := make(map[string]any)
+	if err := loadSchemaDocument(absPath, documents); err != nil {
+		return nil, err
+	}
+	return &schemaValue{schema: documents[absPath], documents: documents, path: absPath}, nil
+}
+
+// Wraps a schema of the document in `path`, among the already loaded `documents` (check `loadSchemaDocument`).
+func newSchemaValueFrom(schema any, documents map[string]any, path string) *schemaValue {
+	return &schemaValue{schema: schema, documents: documents, path: path}
+}
+
+func loadSchemaDocument(absPath string, documents map[string]any) error {
+	if _, ok := documents[absPath]; ok {
+		return nil
+	}
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to read schema '%w'", err)
+	}
+	document, err := UnmarshalTemplateFile(absPath, content)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s from the schema '%s' '%w'", TemplateLanguage(absPath), absPath, err)
+	}
+	documents[absPath] = document
+
+	// Load the other files referenced by the document, so generating never reads files
+	var walkErr error
+	var walk func(value any)
+	walk = func(value any) {
+		switch typedValue := value.(type) {
+		case *OrderedMap:
+			if ref, ok := typedValue.Get("$ref"); ok {
+				if refString, ok := ref.(string); ok {
+					file, _, _ := strings.Cut(refString, "#")
+					if file != "" && walkErr == nil {
+						walkErr = loadSchemaDocument(resolveSchemaFile(absPath, file), documents)
+					}
+				}
+			}
+			for _, objKey := range typedValue.Keys() {
+				objValue, _ := typedValue.Get(objKey)
+				walk(objValue)
+			}
+		case []any:
+			for _, item := range typedValue {
+				walk(item)
+			}
+		}
+	}
+	walk(document)
+	return walkErr
+}
+
+func resolveSchemaFile(fromPath string, file string) string {
+	if filepath.IsAbs(file) {
+		return filepath.Clean(file)
+	}
+	return filepath.Join(filepath.Dir(fromPath), file)
+}
+
+func (s *schemaValue) generateValue(mocker mocker.Mocker) (any, error) {
+	return s.generate(s.schema, s.path, mocker, 0)
+}
+
+// Generates an instance of a schema, `path` being the document the schema is in (to resolve its `$ref`s).
+func (s *schemaValue) generate(schema any, path string, mocker mocker.Mocker, depth int) (any, error) {
+	if depth > schemaMaxDepth {
+		return nil, fmt.Errorf("schema is nested too deep (is a required $ref recursive?)")
+	}
+	switch typedSchema := schema.(type) {
+	case bool:
+		if !typedSchema {
+			return nil, fmt.Errorf("schema 'false' has no valid instances")
+		}
+		return mocker.Generate("Lorem.word", nil)
+	case *OrderedMap:
+	default:
+		return nil, fmt.Errorf("invalid schema '%v' (must be an object or a boolean)", schema)
+	}
+
+	node, path, err := s.resolve(schema.(*OrderedMap), path, depth)
+	if err != nil {
+		return nil, err
+	}
+	node, err = s.pickSubschema(node, path, mocker, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	// Annotations pin the value to a template value (e.g. a mock function)
+	if annotation, ok := node.Get(schemaAnnotationKey); ok {
+		value, err := ProcessTemplate(CloneValue(annotation), mocker)
+		if err != nil {
+			return nil, fmt.Errorf("failed to process '%s' '%w'", schemaAnnotationKey, err)
+		}
+		return CoerceSchemaValue(value, schemaType(node, mocker)), nil
+	}
+	if constValue, ok := node.Get("const"); ok {
+		return CloneValue(constValue), nil
+	}
+	if enum, ok := node.Get("enum"); ok {
+		values, ok := enum.([]any)
+		if !ok || len(values) == 0 {
+			return nil, fmt.Errorf("'enum' must be a non-empty array")
+		}
+		return CloneValue(values[randOf(mocker).Intn(len(values))]), nil
+	}
+
+	switch schemaType(node, mocker) {
+	case "null":
+		return nil, nil
+	case "boolean":
+		return randOf(mocker).Intn(2) == 1, nil
+	case "integer":
+		return generateSchemaNumber(node, mocker, true)
+	case "number":
+		return generateSchemaNumber(node, mocker, false)
+	case "array":
+		return s.generateArray(node, path, mocker, depth)
+	case "object":
+		return s.generateObject(node, path, mocker, depth)
+	default:
+		return generateSchemaString(node, mocker)
+	}
+}
+
+// Resolves the `$ref`s and `allOf` of a schema into a single schema.
+func (s *schemaValue) resolve(node *OrderedMap, path string, depth int) (*OrderedMap, string, error) {
+	for range schemaMaxDepth {
+		ref, ok := node.Get("$ref")
+		if !ok {
+			break
+		}
+		refString, ok := ref.(string)
+		if !ok {
+			return nil, "", fmt.Errorf("'$ref' must be a string")
+		}
+		target, targetPath, err := s.lookupRef(refString, path)
+		if err != nil {
+			return nil, "", err
+		}
+		// Keywords next to a $ref apply as well
+		siblings := node.Clone()
+		siblings.Remove("$ref")
+		node, path = mergeSchemas(target, siblings), targetPath
+	}
+	if _, ok := node.Get("$ref"); ok {
+		return nil, "", fmt.Errorf("schema is nested too deep (is a $ref recursive?)")
+	}
+
+	if allOf, ok := node.Get("allOf"); ok {
+		subschemas, ok := allOf.([]any)
+		if !ok {
+			return nil, "", fmt.Errorf("'allOf' must be an array")
+		}
+		merged := node.Clone()
+		merged.Remove("allOf")
+		for _, subschema := range subschemas {
+			subMap, ok := subschema.(*OrderedMap)
+			if !ok {
+				continue
+			}
+			resolved, _, err := s.resolve(subMap, path, depth+1)
+			if err != nil {
+				return nil, "", err
+			}
+			merged = mergeSchemas(merged, resolved)
+		}
+		node = merged
+	}
+	return node, path, nil
+}
+
+// Returns the schema a `$ref` points to, and the path of its document.
+func (s *schemaValue) lookupRef(ref string, path string) (*OrderedMap, string, error) {
+	file, pointer, _ := strings.Cut(ref, "#")
+	if file != "" {
+		path = resolveSchemaFile(path, file)
+	}
+	document, ok := s.documents[path]
+	if !ok {
+		return nil, "", fmt.Errorf("failed to resolve '$ref' '%s' (its file was not loaded)", ref)
+	}
+	target, err := lookupJsonPointer(document, pointer)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve '$ref' '%s' '%w'", ref, err)
+	}
+	targetMap, ok := target.(*OrderedMap)
+	if !ok {
+		return nil, "", fmt.Errorf("failed to resolve '$ref' '%s' (it is not a schema)", ref)
+	}
+	return targetMap, path, nil
+}
+
+// Returns the value a JSON pointer (e.g. "/components/schemas/User") points to in a document.
+func lookupJsonPointer(document any, pointer string) (any, error) {
+	pointer, err := url.PathUnescape(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if pointer == "" || pointer == "/" {
+		return document, nil
+	}
+	current := document
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch typedValue := current.(type) {
+		case *OrderedMap:
+			value, ok := typedValue.Get(token)
+			if !ok {
+				return nil, fmt.Errorf("'%s' not found", token)
+			}
+			current = value
+		case []any:
+			idx, err := strconv.Atoi(token)
+			if err != nil || idx < 0 || idx >= len(typedValue) {
+				return nil, fmt.Errorf("'%s' not found", token)
+			}
+			current = typedValue[idx]
+		default:
+			return nil, fmt.Errorf("'%s' not found", token)
+		}
+	}
+	return current, nil
+}
+
+// Merges the keywords of two schemas, `other` taking precedence. Properties are merged and required properties joined.
+func mergeSchemas(base *OrderedMap, other *OrderedMap) *OrderedMap {
+	merged := base.Clone()
+	for _, objKey := range other.Keys() {
+		objValue, _ := other.Get(objKey)
+		existing, exists := merged.Get(objKey)
+		switch {
+		case exists && objKey == "properties":
+			existingMap, okExisting := existing.(*OrderedMap)
+			otherMap, okOther := objValue.(*OrderedMap)
+			if okExisting && okOther {
+				properties := existingMap.Clone()
+				for _, property := range otherMap.Keys() {
+					propertySchema, _ := otherMap.Get(property)
+					properties.Set(property, CloneValue(propertySchema))
+				}
+				merged.Set(objKey, properties)
+				continue
+			}
+		case exists && objKey == "required":
+			existingList, okExisting := existing.([]any)
+			otherList, okOther := objValue.([]any)
+			if okExisting && okOther {
+				merged.Set(objKey, append(append([]any(nil), existingList...), otherList...))
+				continue
+			}
+		}
+		merged.Set(objKey, CloneValue(objValue))
+	}
+	return merged
+}
+
+// Returns the type of the instances of a schema, picking one when it allows many, or guessing it from its keywords.
+func schemaType(node *OrderedMap, mocker mocker.Mocker) string {
+	switch typedValue := schemaKeyword(node, "type").(type) {
+	case string:
+		return typedValue
+	case []any:
+		var types []string
+		for _, item := range typedValue {
+			if itemType, ok := item.(string); ok && itemType != "null" {
+				types = append(types, itemType)
+			}
+		}
+		if len(types) == 0 {
+			return "null"
+		}
+		return types[randOf(mocker).Intn(len(types))]
+	}
+	switch {
+	case node.Has("properties") || node.Has("required") || node.Has("additionalProperties"):
+		return "object"
+	case node.Has("items") || node.Has("prefixItems") || node.Has("minItems") || node.Has("maxItems"):
+		return "array"
+	case node.Has("minimum") || node.Has("maximum") || node.Has("exclusiveMinimum") || node.Has("exclusiveMaximum") || node.Has("multipleOf"):
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+// Returns the value of a keyword of a schema, or nil when it doesn't have it.
+func schemaKeyword(node *OrderedMap, key string) any {
+	value, _ := node.Get(key)
+	return value
+}
+
+func (s *schemaValue) generateObject(node *OrderedMap, path string, mocker mocker.Mocker, depth int) (any, error) {
+	required := make(map[string]bool)
+	if requiredList, ok := schemaKeyword(node, "required").([]any); ok {
+		for _, item := range requiredList {
+			if property, ok := item.(string); ok {
+				required[property] = true
+			}
+		}
+	}
+	minProperties := schemaInt(node, "minProperties", 0)
+	properties, _ := schemaKeyword(node, "properties").(*OrderedMap)
+	if properties == nil {
+		properties = NewOrderedMap()
+	}
+
+	// Required properties are always generated, and optional ones half of the times
+	included := make(map[string]bool)
+	count := 0
+	for _, property := range properties.Keys() {
+		if required[property] || (depth < schemaOptionalDepth && randOf(mocker).Intn(2) == 1) {
+			included[property] = true
+			count++
+		}
+	}
+	for _, property := range properties.Keys() {
+		if count >= minProperties {
+			break
+		}
+		if !included[property] {
+			included[property] = true
+			count++
+		}
+	}
+
+	instance := NewOrderedMap()
+	for _, property := range properties.Keys() {
+		if !included[property] {
+			continue
+		}
+		propertySchema, _ := properties.Get(property)
+		if s.skipsSchema(propertySchema, path) {
+			continue
+		}
+		value, err := s.generate(propertySchema, path, mocker, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", property, err)
+		}
+		instance.Set(property, value)
+	}
+	// Required properties without a schema are still generated
+	for property := range required {
+		if !instance.Has(property) && !properties.Has(property) {
+			value, err := mocker.Generate("Lorem.word", nil)
+			if err != nil {
+				return nil, err
+			}
+			instance.Set(property, value)
+		}
+	}
+	return instance, nil
+}
+
+// Returns whether a property schema has the `skipKeyword`, following its `$ref`s.
+func (s *schemaValue) skipsSchema(schema any, path string) bool {
+	schemaMap, ok := schema.(*OrderedMap)
+	if s.skipKeyword == "" || !ok {
+		return false
+	}
+	resolved, _, err := s.resolve(schemaMap, path, 0)
+	if err != nil {
+		return false
+	}
+	skip, _ := schemaKeyword(resolved, s.skipKeyword).(bool)
+	return skip
+}
+
+func (s *schemaValue) generateArray(node *OrderedMap, path string, mocker mocker.Mocker, depth int) (any, error) {
+	minItems := schemaInt(node, "minItems", 0)
+	maxItems := schemaInt(node, "maxItems", max(minItems, 1)+schemaDefaultMaxItems-1)
+	if maxItems < minItems {
+		return nil, fmt.Errorf("'maxItems' must not be lower than 'minItems'")
+	}
+	size := minItems
+	if depth < schemaOptionalDepth {
+		size = max(minItems, 1) + randOf(mocker).Intn(maxItems-max(minItems, 1)+1)
+		if maxItems == 0 {
+			size = 0
+		}
+	}
+
+	// Tuples (`prefixItems`, or `items` as an array in older drafts) have a schema per position
+	var prefixItems []any
+	if prefix, ok := schemaKeyword(node, "prefixItems").([]any); ok {
+		prefixItems = prefix
+	} else if prefix, ok := schemaKeyword(node, "items").([]any); ok {
+		prefixItems = prefix
+	}
+	var itemSchema any = true
+	if items, ok := node.Get("items"); ok {
+		if _, isTuple := items.([]any); !isTuple {
+			itemSchema = items
+		}
+	}
+	uniqueItems, _ := schemaKeyword(node, "uniqueItems").(bool)
+
+	instance := make([]any, 0, size)
+	seen := make(map[string]bool)
+	for idx := 0; len(instance) < size; idx++ {
+		if idx >= size*10+10 {
+			return nil, fmt.Errorf("failed to generate %d unique items", size)
+		}
+		schema := itemSchema
+		if len(instance) < len(prefixItems) {
+			schema = prefixItems[len(instance)]
+		}
+		item, err := s.generate(schema, path, mocker, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %w", len(instance), err)
+		}
+		if uniqueItems {
+			encoded, _ := json.Marshal(item)
+			if seen[string(encoded)] {
+				continue
+			}
+			seen[string(encoded)] = true
+		}
+		instance = append(instance, item)
+	}
+	return instance, nil
+}
+
+// Picks one of the `oneOf` (or `anyOf`) subschemas, merged with the rest of the schema.
+func (s *schemaValue) pickSubschema(node *OrderedMap, path string, mocker mocker.Mocker, depth int) (*OrderedMap, error) {
+	for _, keyword := range []string{"oneOf", "anyOf"} {
+		subschemas, ok := schemaKeyword(node, keyword).([]any)
+		if !ok || len(subschemas) == 0 {
+			continue
+		}
+		base := node.Clone()
+		base.Remove(keyword)
+		subMap, ok := subschemas[randOf(mocker).Intn(len(subschemas))].(*OrderedMap)
+		if !ok {
+			return base, nil
+		}
+		resolved, _, err := s.resolve(subMap, path, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return mergeSchemas(base, resolved), nil
+	}
+	return node, nil
+}
+
+func generateSchemaNumber(node *OrderedMap, mocker mocker.Mocker, integer bool) (any, error) {
+	minimum, hasMinimum := schemaFloat(node, "minimum")
+	maximum, hasMaximum := schemaFloat(node, "maximum")
+	// Draft 6+ has numeric exclusive bounds, draft 4 has booleans making `minimum`/`maximum` exclusive
+	exclusiveMinimum, exclusiveMaximum := false, false
+	if value, ok := schemaFloat(node, "exclusiveMinimum"); ok {
+		minimum, hasMinimum, exclusiveMinimum = value, true, true
+	} else if value, ok := schemaKeyword(node, "exclusiveMinimum").(bool); ok {
+		exclusiveMinimum = value
+	}
+	if value, ok := schemaFloat(node, "exclusiveMaximum"); ok {
+		maximum, hasMaximum, exclusiveMaximum = value, true, true
+	} else if value, ok := schemaKeyword(node, "exclusiveMaximum").(bool); ok {
+		exclusiveMaximum = value
+	}
+	switch {
+	case !hasMinimum && !hasMaximum:
+		minimum, maximum = 0, schemaDefaultNumberRange
+	case !hasMinimum:
+		minimum = maximum - schemaDefaultNumberRange
+	case !hasMaximum:
+		maximum = minimum + schemaDefaultNumberRange
+	}
+
+	multipleOf, hasMultipleOf := schemaFloat(node, "multipleOf")
+	if integer && (!hasMultipleOf || multipleOf < 1) {
+		multipleOf, hasMultipleOf = 1, true
+	}
+	if hasMultipleOf {
+		if multipleOf <= 0 {
+			return nil, fmt.Errorf("'multipleOf' must be greater than 0")
+		}
+		lowest := math.Ceil(minimum / multipleOf)
+		highest := math.Floor(maximum / multipleOf)
+		if exclusiveMinimum && lowest*multipleOf <= minimum {
+			lowest++
+		}
+		if exclusiveMaximum && highest*multipleOf >= maximum {
+			highest--
+		}
+		if highest < lowest {
+			return nil, fmt.Errorf("no number between 'minimum' and 'maximum' is a multiple of %v", multipleOf)
+		}
+		// The multiples are counted in an int64, and only the ones of up to 2^53 are exact in a float64
+		if math.Abs(lowest) > schemaMaxMultiples || math.Abs(highest) > schemaMaxMultiples {
+			return nil, fmt.Errorf("'minimum' and 'maximum' are too far from 0 for a 'multipleOf' of %v (at most %d multiples)", multipleOf, int64(schemaMaxMultiples))
+		}
+		if integer && math.Max(math.Abs(lowest*multipleOf), math.Abs(highest*multipleOf)) >= math.MaxInt64 {
+			return nil, fmt.Errorf("'minimum' and 'maximum' must be integers of at most %d", int64(math.MaxInt64))
+		}
+		value := (lowest + float64(randOf(mocker).Int63n(int64(highest-lowest)+1))) * multipleOf
+		if integer {
+			return float64(int64(value)), nil
+		}
+		return value, nil
+	}
+
+	if maximum < minimum || (maximum == minimum && (exclusiveMinimum || exclusiveMaximum)) {
+		return nil, fmt.Errorf("'maximum' must be greater than 'minimum'")
+	}
+	for range 100 {
+		value := math.Round((minimum+randOf(mocker).Float64()*(maximum-minimum))*100) / 100
+		if value < minimum || value > maximum || (exclusiveMinimum && value == minimum) || (exclusiveMaximum && value == maximum) {
+			continue
+		}
+		return value, nil
+	}
+	return (minimum + maximum) / 2, nil
+}
+
+// Mock functions (and their parameters) generating the string formats of JSON Schema.
+var schemaStringFormats = map[string][]string{
+	"email":         {"Internet.email"},
+	"idn-email":     {"Internet.email"},
+	"uuid":          {"UUID.uuidv4"},
+	"date-time":     {"Time.date", "datetime"},
+	"date":          {"Time.date", "date"},
+	"time":          {"Time.date", "time"},
+	"uri":           {"Internet.url"},
+	"uri-reference": {"Internet.url"},
+	"iri":           {"Internet.url"},
+	"url":           {"Internet.url"},
+	"hostname":      {"Internet.domain"},
+	"idn-hostname":  {"Internet.domain"},
+	"ipv4":          {"Internet.ipv4"},
+	"ipv6":          {"Regex.regex", "/[0-9a-f]{1,4}(:[0-9a-f]{1,4}){7}/"},
+	"password":      {"Internet.password"},
+}
+
+func generateSchemaString(node *OrderedMap, mocker mocker.Mocker) (any, error) {
+	minLength := schemaInt(node, "minLength", 0)
+	maxLength := schemaInt(node, "maxLength", -1)
+	if maxLength >= 0 && maxLength < minLength {
+		return nil, fmt.Errorf("'maxLength' must not be lower than 'minLength'")
+	}
+
+	generateOne := func() (string, error) {
+		if pattern, ok := schemaKeyword(node, "pattern").(string); ok {
+			return mocker.Generate("Regex.regex", []string{"/" + strings.ReplaceAll(pattern, "/", `\/`) + "/"})
+		}
+		if format, ok := schemaKeyword(node, "format").(string); ok {
+			if function, ok := schemaStringFormats[format]; ok {
+				return mocker.Generate(function[0], function[1:])
+			}
+		}
+		words := make([]string, 1+randOf(mocker).Intn(3))
+		for idx := range words {
+			word, err := mocker.Generate("Lorem.word", nil)
+			if err != nil {
+				return "", err
+			}
+			words[idx] = word
+		}
+		return strings.Join(words, " "), nil
+	}
+
+	// Patterns and formats can't be changed to fit the length (it would break them), so they are generated again
+	pattern, hasPattern := schemaKeyword(node, "pattern").(string)
+	format, hasFormat := schemaKeyword(node, "format").(string)
+	_, knownFormat := schemaStringFormats[format]
+	attempts := 1
+	if hasPattern || (hasFormat && knownFormat) {
+		attempts = schemaStringAttempts
+	}
+	value := ""
+	for range attempts {
+		generated, err := generateOne()
+		if err != nil {
+			return nil, err
+		}
+		value = generated
+		length := len([]rune(value))
+		if length >= minLength && (maxLength < 0 || length <= maxLength) {
+			return value, nil
+		}
+	}
+	if hasPattern {
+		return nil, fmt.Errorf("failed to generate a string of 'pattern' '%s' %s (in %d attempts)", pattern, schemaLengthBounds(minLength, maxLength), attempts)
+	}
+	if hasFormat && knownFormat {
+		return nil, fmt.Errorf("failed to generate a string of 'format' '%s' %s (in %d attempts)", format, schemaLengthBounds(minLength, maxLength), attempts)
+	}
+	for len([]rune(value)) < minLength {
+		word, err := mocker.Generate("Lorem.word", nil)
+		if err != nil {
+			return nil, err
+		}
+		value += word
+	}
+	if maxLength >= 0 && len([]rune(value)) > maxLength {
+		value = string([]rune(value)[:maxLength])
+	}
+	return value, nil
+}
+
+// Describes the 'minLength' and 'maxLength' of a string (a `maxLength` lower than 0 is unset).
+func schemaLengthBounds(minLength int, maxLength int) string {
+	switch {
+	case maxLength < 0:
+		return fmt.Sprintf("of at least %d characters", minLength)
+	case minLength == maxLength:
+		return fmt.Sprintf("of %d characters", minLength)
+	default:
+		return fmt.Sprintf("of %d to %d characters", minLength, maxLength)
+	}
+}
+
+func schemaFloat(node *OrderedMap, key string) (float64, bool) {
+	value, ok := schemaKeyword(node, key).(float64)
+	return value, ok
+}
+
+func schemaInt(node *OrderedMap, key string, fallback int) int {
+	if value, ok := schemaFloat(node, key); ok {
+		return int(value)
+	}
+	return fallback
+}
+
+// Converts the result of an annotation (mock functions always generate strings) into the type of the schema.
+func CoerceSchemaValue(value any, valueType string) any {
+	text, ok := value.(string)
+	if !ok {
+		return value
+	}
+	switch valueType {
+	case "integer", "number":
+		if number, err := strconv.ParseFloat(text, 64); err == nil {
+			return number
+		}
+	case "boolean":
+		if boolean, err := strconv.ParseBool(text); err == nil {
+			return boolean
+		}
+	}
+	return value
+}
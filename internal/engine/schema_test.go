@@ -0,0 +1,263 @@
+package engine
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lfsc09/k-test-n-stress/mocker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SchemaTestSuite struct {
+	suite.Suite
+}
+
+func TestSchemaTestSuite(t *testing.T) {
+	suite.Run(t, new(SchemaTestSuite))
+}
+
+// Generates instances of a JSON Schema (passed as JSON) with different seeds.
+func (suite *SchemaTestSuite) generateInstances(schemaJSON string, amount int) ([]any, error) {
+	schema, err := UnmarshalOrdered([]byte(schemaJSON))
+	suite.Require().NoError(err)
+	value := newSchemaValueFrom(schema, map[string]any{"schema.json": schema}, "schema.json")
+	instances := make([]any, 0, amount)
+	for seed := range amount {
+		instance, err := value.generateValue(mocker.NewWithSeed(int64(seed)))
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+func (suite *SchemaTestSuite) TestSchemaValue_ValidInstances() {
+	tests := []struct {
+		testName string
+		schema   string
+		isValid  func(instance any) bool
+	}{
+		{
+			testName: "string with length bounds",
+			schema:   `{ "type": "string", "minLength": 12, "maxLength": 15 }`,
+			isValid: func(instance any) bool {
+				length := len([]rune(instance.(string)))
+				return length >= 12 && length <= 15
+			},
+		},
+		{
+			testName: "string with pattern",
+			schema:   `{ "type": "string", "pattern": "^[A-Z]{3}/[0-9]{4}$" }`,
+			isValid: func(instance any) bool {
+				return len(instance.(string)) == 8 && instance.(string)[3] == '/'
+			},
+		},
+		{
+			testName: "email format",
+			schema:   `{ "type": "string", "format": "email" }`,
+			isValid:  func(instance any) bool { return strings.Contains(instance.(string), "@") },
+		},
+		{
+			testName: "email format with length bounds",
+			schema:   `{ "type": "string", "format": "email", "minLength": 10, "maxLength": 20 }`,
+			isValid: func(instance any) bool {
+				length := len([]rune(instance.(string)))
+				return strings.Contains(instance.(string), "@") && length >= 10 && length <= 20
+			},
+		},
+		{
+			testName: "date-time format",
+			schema:   `{ "type": "string", "format": "date-time" }`,
+			isValid: func(instance any) bool {
+				return len(instance.(string)) == len("2006-01-02T15:04:05Z") && strings.HasSuffix(instance.(string), "Z")
+			},
+		},
+		{
+			testName: "integer with bounds",
+			schema:   `{ "type": "integer", "minimum": 5, "maximum": 7 }`,
+			isValid: func(instance any) bool {
+				number := instance.(float64)
+				return number >= 5 && number <= 7 && number == math.Trunc(number)
+			},
+		},
+		{
+			testName: "number with exclusive bounds and multipleOf",
+			schema:   `{ "type": "number", "exclusiveMinimum": 0, "exclusiveMaximum": 1, "multipleOf": 0.25 }`,
+			isValid: func(instance any) bool {
+				number := instance.(float64)
+				return number == 0.25 || number == 0.5 || number == 0.75
+			},
+		},
+		{
+			testName: "draft 4 exclusive bounds",
+			schema:   `{ "type": "integer", "minimum": 1, "maximum": 3, "exclusiveMinimum": true, "exclusiveMaximum": true }`,
+			isValid:  func(instance any) bool { return instance.(float64) == 2 },
+		},
+		{
+			testName: "enum",
+			schema:   `{ "enum": ["a", 1, null] }`,
+			isValid:  func(instance any) bool { return instance == "a" || instance == 1.0 || instance == nil },
+		},
+		{
+			testName: "const",
+			schema:   `{ "const": { "fixed": true } }`,
+			isValid: func(instance any) bool {
+				encoded, _ := json.Marshal(instance)
+				return string(encoded) == `{"fixed":true}`
+			},
+		},
+		{
+			testName: "object with required properties",
+			schema:   `{ "type": "object", "required": ["id", "extra"], "properties": { "id": { "type": "integer" }, "tag": { "type": "string" } } }`,
+			isValid: func(instance any) bool {
+				object := instance.(*OrderedMap)
+				_, isNumber := schemaKeyword(object, "id").(float64)
+				_, isString := schemaKeyword(object, "extra").(string)
+				return isNumber && isString
+			},
+		},
+		{
+			testName: "array with unique items",
+			schema:   `{ "type": "array", "minItems": 3, "maxItems": 3, "uniqueItems": true, "items": { "enum": ["a", "b", "c"] } }`,
+			isValid: func(instance any) bool {
+				items := instance.([]any)
+				return len(items) == 3 && items[0] != items[1] && items[1] != items[2] && items[0] != items[2]
+			},
+		},
+		{
+			testName: "tuple",
+			schema:   `{ "type": "array", "prefixItems": [{ "const": "x" }, { "type": "boolean" }], "minItems": 2, "maxItems": 2 }`,
+			isValid: func(instance any) bool {
+				items := instance.([]any)
+				_, isBool := items[1].(bool)
+				return len(items) == 2 && items[0] == "x" && isBool
+			},
+		},
+		{
+			testName: "oneOf",
+			schema:   `{ "oneOf": [{ "type": "boolean" }, { "type": "null" }] }`,
+			isValid: func(instance any) bool {
+				_, isBool := instance.(bool)
+				return isBool || instance == nil
+			},
+		},
+		{
+			testName: "allOf",
+			schema:   `{ "allOf": [{ "required": ["a"], "properties": { "a": { "const": 1 } } }, { "required": ["b"], "properties": { "b": { "const": 2 } } }] }`,
+			isValid: func(instance any) bool {
+				object := instance.(*OrderedMap)
+				return schemaKeyword(object, "a") == 1.0 && schemaKeyword(object, "b") == 2.0
+			},
+		},
+		{
+			testName: "local $ref with sibling keywords",
+			schema:   `{ "$defs": { "code": { "type": "string", "pattern": "^[a-z]{6}$" } }, "type": "object", "required": ["code"], "properties": { "code": { "$ref": "#/$defs/code", "maxLength": 6 } } }`,
+			isValid: func(instance any) bool {
+				code, _ := schemaKeyword(instance.(*OrderedMap), "code").(string)
+				return len(code) == 6 && strings.ToLower(code) == code
+			},
+		},
+		{
+			testName: "recursive $ref stops at optional properties",
+			schema:   `{ "$defs": { "node": { "type": "object", "properties": { "child": { "$ref": "#/$defs/node" } } } }, "$ref": "#/$defs/node" }`,
+			isValid:  func(instance any) bool { return instance != nil },
+		},
+		{
+			testName: "x-ktns annotation coerced into the type",
+			schema:   `{ "type": "integer", "x-ktns": "{{ Number.number::1:10 }}" }`,
+			isValid: func(instance any) bool {
+				number, ok := instance.(float64)
+				return ok && number >= 1 && number <= 10
+			},
+		},
+	}
+
+	for _, test := range tests {
+		instances, err := suite.generateInstances(test.schema, 20)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", test.testName)
+		for _, instance := range instances {
+			assert.True(suite.T(), test.isValid(instance), "Test case '%s' failed with '%v'", test.testName, instance)
+		}
+	}
+}
+
+func (suite *SchemaTestSuite) TestSchemaValue_InvalidSchemas() {
+	tests := []struct {
+		testName      string
+		schema        string
+		expectedError string
+	}{
+		{
+			testName:      "false schema",
+			schema:        `false`,
+			expectedError: "schema 'false' has no valid instances",
+		},
+		{
+			testName:      "missing $ref",
+			schema:        `{ "$ref": "#/$defs/missing" }`,
+			expectedError: "failed to resolve '$ref' '#/$defs/missing' ''$defs' not found'",
+		},
+		{
+			testName:      "required recursive $ref",
+			schema:        `{ "$defs": { "node": { "type": "object", "required": ["child"], "properties": { "child": { "$ref": "#/$defs/node" } } } }, "$ref": "#/$defs/node" }`,
+			expectedError: "schema is nested too deep (is a required $ref recursive?)",
+		},
+		{
+			testName:      "impossible bounds",
+			schema:        `{ "type": "integer", "minimum": 2, "maximum": 1 }`,
+			expectedError: "no number between 'minimum' and 'maximum' is a multiple of 1",
+		},
+		{
+			testName:      "too many multiples",
+			schema:        `{ "type": "number", "minimum": 0, "maximum": 1e300, "multipleOf": 0.001 }`,
+			expectedError: "'minimum' and 'maximum' are too far from 0 for a 'multipleOf' of 0.001 (at most 9007199254740992 multiples)",
+		},
+		{
+			testName:      "integers beyond int64",
+			schema:        `{ "type": "integer", "minimum": 1e19, "maximum": 1e19, "multipleOf": 1e19 }`,
+			expectedError: "'minimum' and 'maximum' must be integers of at most 9223372036854775807",
+		},
+		{
+			testName:      "empty enum",
+			schema:        `{ "enum": [] }`,
+			expectedError: "'enum' must be a non-empty array",
+		},
+		{
+			testName:      "format that can't fit the length",
+			schema:        `{ "type": "string", "minLength": 3, "maxLength": 4, "format": "email" }`,
+			expectedError: "failed to generate a string of 'format' 'email' of 3 to 4 characters (in 100 attempts)",
+		},
+		{
+			testName:      "pattern that can't fit the length",
+			schema:        `{ "type": "string", "minLength": 10, "pattern": "^[a-z]{3}$" }`,
+			expectedError: "failed to generate a string of 'pattern' '^[a-z]{3}$' of at least 10 characters (in 100 attempts)",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := suite.generateInstances(test.schema, 1)
+		assert.ErrorContains(suite.T(), err, test.expectedError, "Test case '%s' failed", test.testName)
+	}
+}
+
+func (suite *SchemaTestSuite) TestNewSchemaValue_LoadsReferencedFiles() {
+	dir := suite.T().TempDir()
+	suite.Require().NoError(os.WriteFile(filepath.Join(dir, "order.schema.json"), []byte(`{ "type": "object", "required": ["item"], "properties": { "item": { "$ref": "defs/item.schema.yaml#/$defs/item" } } }`), 0644))
+	suite.Require().NoError(os.MkdirAll(filepath.Join(dir, "defs"), 0755))
+	suite.Require().NoError(os.WriteFile(filepath.Join(dir, "defs", "item.schema.yaml"), []byte("$defs:\n  item:\n    const: widget\n"), 0644))
+
+	value, err := NewSchemaValue(filepath.Join(dir, "order.schema.json"))
+	suite.Require().NoError(err)
+	instance, err := value.generateValue(mocker.NewWithSeed(1))
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "widget", schemaKeyword(instance.(*OrderedMap), "item"))
+
+	_, err = NewSchemaValue(filepath.Join(dir, "missing.schema.json"))
+	assert.ErrorContains(suite.T(), err, "failed to read schema")
+}
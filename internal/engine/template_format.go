@@ -0,0 +1,396 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Languages the template files can be written in.
+const (
+	templateLanguageJson  = "JSON"
+	templateLanguageYaml  = "YAML"
+	templateLanguageJson5 = "JSON5"
+)
+
+// Returns the languages templates can be written in ("JSON", "YAML" and "JSON5").
+func TemplateLanguages() []string {
+	return []string{templateLanguageJson, templateLanguageYaml, templateLanguageJson5}
+}
+
+// Returns the extensions of the template files (e.g. ".template.json").
+func TemplateExtensions() []string {
+	return []string{".template.json", ".template.yaml", ".template.yml", ".template.json5", ".template.jsonc"}
+}
+
+// Returns whether a file is a template file, by its extension.
+func IsTemplateFile(path string) bool {
+	return TemplateExtension(path) != ""
+}
+
+// Returns the template extension of a file (e.g. ".template.yaml"), or an empty string when it isn't a template file.
+func TemplateExtension(path string) string {
+	for _, extension := range TemplateExtensions() {
+		if strings.HasSuffix(path, extension) {
+			return extension
+		}
+	}
+	return ""
+}
+
+// Removes the template extension of a file (e.g. "company[10].template.yaml" -> "company[10]").
+func TrimTemplateExtension(path string) string {
+	return strings.TrimSuffix(path, TemplateExtension(path))
+}
+
+// Returns the language a file is written in, by its extension. Files with other extensions are JSON.
+func TemplateLanguage(path string) string {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		return templateLanguageYaml
+	case ".json5", ".jsonc":
+		return templateLanguageJson5
+	default:
+		return templateLanguageJson
+	}
+}
+
+// Parses the content of a template file in the language of its extension, into the same template as a JSON one.
+func UnmarshalTemplateFile(path string, content []byte) (any, error) {
+	return UnmarshalTemplateLanguage(TemplateLanguage(path), content)
+}
+
+// Parses the content of a template written in a language ("JSON", "YAML" or "JSON5"), into the same template as a JSON one.
+func UnmarshalTemplateLanguage(language string, content []byte) (any, error) {
+	switch language {
+	case templateLanguageYaml:
+		template, err := unmarshalYamlOrdered(content)
+		if err != nil {
+			return nil, err
+		}
+		return checkTemplateRoot(template)
+	case templateLanguageJson5:
+		normalized, err := normalizeJson5(content)
+		if err != nil {
+			return nil, err
+		}
+		return UnmarshalTemplate(normalized)
+	default:
+		return UnmarshalTemplate(content)
+	}
+}
+
+// Decodes a YAML document, keeping the order of the keys of its mappings (decoded as *OrderedMap).
+// Numbers are decoded as float64, as they are when decoding JSON.
+func unmarshalYamlOrdered(content []byte) (any, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		return nil, fmt.Errorf("empty YAML document")
+	}
+	return decodeYamlNode(document.Content[0])
+}
+
+func decodeYamlNode(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return decodeYamlNode(node.Alias)
+	case yaml.MappingNode:
+		parseMap := NewOrderedMap()
+		var merged []*OrderedMap
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			keyNode, valueNode := node.Content[idx], node.Content[idx+1]
+			objValue, err := decodeYamlNode(valueNode)
+			if err != nil {
+				return nil, err
+			}
+			// Merge keys ("<<: *anchor") add the keys of other mappings, without replacing the ones of this mapping
+			if keyNode.Tag == "!!merge" {
+				switch typedValue := objValue.(type) {
+				case *OrderedMap:
+					merged = append(merged, typedValue)
+				case []any:
+					for _, item := range typedValue {
+						if itemMap, ok := item.(*OrderedMap); ok {
+							merged = append(merged, itemMap)
+						}
+					}
+				}
+				continue
+			}
+			if keyNode.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: keys must be strings", keyNode.Line)
+			}
+			parseMap.Set(keyNode.Value, objValue)
+		}
+		for _, mergedMap := range merged {
+			for _, objKey := range mergedMap.Keys() {
+				if !parseMap.Has(objKey) {
+					objValue, _ := mergedMap.Get(objKey)
+					parseMap.Set(objKey, CloneValue(objValue))
+				}
+			}
+		}
+		return parseMap, nil
+	case yaml.SequenceNode:
+		array := []any{}
+		for _, itemNode := range node.Content {
+			item, err := decodeYamlNode(itemNode)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, item)
+		}
+		return array, nil
+	case yaml.ScalarNode:
+		if node.Tag == "!!timestamp" {
+			return node.Value, nil
+		}
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		switch typedValue := value.(type) {
+		case int:
+			return float64(typedValue), nil
+		case int64:
+			return float64(typedValue), nil
+		case uint64:
+			return float64(typedValue), nil
+		default:
+			return value, nil
+		}
+	default:
+		return nil, fmt.Errorf("line %d: unsupported YAML content", node.Line)
+	}
+}
+
+// Rewrites JSON5 (or JSON with comments) as strict JSON: removes comments and trailing commas, quotes the keys,
+// converts single-quoted strings and the numbers JSON doesn't accept (e.g. "0x1F", ".5", "+1").
+func normalizeJson5(content []byte) ([]byte, error) {
+	source := []rune(string(content))
+	var out strings.Builder
+	line := 1
+
+	// Skips whitespace and comments, returning the position of the next significant character
+	skipInsignificant := func(pos int) (int, error) {
+		for pos < len(source) {
+			switch {
+			case source[pos] == '\n':
+				line++
+				pos++
+			case unicode.IsSpace(source[pos]) || source[pos] == '\uFEFF':
+				pos++
+			case source[pos] == '/' && pos+1 < len(source) && source[pos+1] == '/':
+				for pos < len(source) && source[pos] != '\n' {
+					pos++
+				}
+			case source[pos] == '/' && pos+1 < len(source) && source[pos+1] == '*':
+				end := pos + 2
+				for end+1 < len(source) && !(source[end] == '*' && source[end+1] == '/') {
+					if source[end] == '\n' {
+						line++
+					}
+					end++
+				}
+				if end+1 >= len(source) {
+					return pos, fmt.Errorf("line %d: unterminated comment", line)
+				}
+				pos = end + 2
+			default:
+				return pos, nil
+			}
+		}
+		return pos, nil
+	}
+
+	pos := 0
+	for {
+		var err error
+		pos, err = skipInsignificant(pos)
+		if err != nil {
+			return nil, err
+		}
+		if pos >= len(source) {
+			break
+		}
+		char := source[pos]
+		switch {
+		case char == '"' || char == '\'':
+			text, next, err := readJson5String(source, pos, &line)
+			if err != nil {
+				return nil, err
+			}
+			quoted, _ := json.Marshal(text)
+			out.Write(quoted)
+			pos = next
+		case char == ',':
+			next, err := skipInsignificant(pos + 1)
+			if err != nil {
+				return nil, err
+			}
+			// Trailing commas are dropped
+			if next < len(source) && (source[next] == '}' || source[next] == ']') {
+				pos = next
+				continue
+			}
+			out.WriteRune(',')
+			pos = next
+		case char == '{' || char == '}' || char == '[' || char == ']' || char == ':':
+			out.WriteRune(char)
+			pos++
+		case char == '+' || char == '-' || char == '.' || unicode.IsDigit(char):
+			number, next, err := readJson5Number(source, pos, line)
+			if err != nil {
+				return nil, err
+			}
+			out.WriteString(number)
+			pos = next
+		case char == '_' || char == '$' || unicode.IsLetter(char):
+			next := pos
+			for next < len(source) && (source[next] == '_' || source[next] == '$' || unicode.IsLetter(source[next]) || unicode.IsDigit(source[next])) {
+				next++
+			}
+			identifier := string(source[pos:next])
+			switch identifier {
+			case "true", "false", "null":
+				out.WriteString(identifier)
+			case "Infinity", "NaN":
+				return nil, fmt.Errorf("line %d: '%s' is not supported in templates", line, identifier)
+			default:
+				// Unquoted identifiers are only allowed as keys
+				colon, err := skipInsignificant(next)
+				if err != nil {
+					return nil, err
+				}
+				if colon >= len(source) || source[colon] != ':' {
+					return nil, fmt.Errorf("line %d: unexpected identifier '%s'", line, identifier)
+				}
+				quoted, _ := json.Marshal(identifier)
+				out.Write(quoted)
+				next = colon
+			}
+			pos = next
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character '%c'", line, char)
+		}
+	}
+	return []byte(out.String()), nil
+}
+
+// Reads a single or double-quoted JSON5 string starting at `pos`, returning its text and the position after it.
+func readJson5String(source []rune, pos int, line *int) (string, int, error) {
+	quote := source[pos]
+	var text strings.Builder
+	for pos++; pos < len(source); pos++ {
+		char := source[pos]
+		switch {
+		case char == quote:
+			return text.String(), pos + 1, nil
+		case char == '\n':
+			return "", pos, fmt.Errorf("line %d: unterminated string", *line)
+		case char != '\\':
+			text.WriteRune(char)
+			continue
+		}
+
+		pos++
+		if pos >= len(source) {
+			break
+		}
+		switch escaped := source[pos]; escaped {
+		case '\n':
+			// A backslash at the end of a line continues the string in the next one
+			*line++
+		case '\r':
+			if pos+1 < len(source) && source[pos+1] == '\n' {
+				pos++
+			}
+			*line++
+		case 'b':
+			text.WriteRune('\b')
+		case 'f':
+			text.WriteRune('\f')
+		case 'n':
+			text.WriteRune('\n')
+		case 'r':
+			text.WriteRune('\r')
+		case 't':
+			text.WriteRune('\t')
+		case 'v':
+			text.WriteRune('\v')
+		case '0':
+			text.WriteRune(0)
+		case 'x', 'u':
+			size := 2
+			if escaped == 'u' {
+				size = 4
+			}
+			if pos+size >= len(source) {
+				return "", pos, fmt.Errorf("line %d: invalid escape sequence", *line)
+			}
+			code, err := strconv.ParseUint(string(source[pos+1:pos+1+size]), 16, 32)
+			if err != nil {
+				return "", pos, fmt.Errorf("line %d: invalid escape sequence", *line)
+			}
+			pos += size
+			// A high surrogate followed by the escape of a low one is a single character (e.g. "\ud83d\ude00")
+			if escaped == 'u' && utf16.IsSurrogate(rune(code)) && pos+6 < len(source) && source[pos+1] == '\\' && source[pos+2] == 'u' {
+				if low, err := strconv.ParseUint(string(source[pos+3:pos+7]), 16, 32); err == nil {
+					if combined := utf16.DecodeRune(rune(code), rune(low)); combined != unicode.ReplacementChar {
+						text.WriteRune(combined)
+						pos += 6
+						continue
+					}
+				}
+			}
+			text.WriteRune(rune(code))
+		default:
+			text.WriteRune(escaped)
+		}
+	}
+	return "", pos, fmt.Errorf("line %d: unterminated string", *line)
+}
+
+// Reads a JSON5 number starting at `pos`, returning it as a JSON number and the position after it.
+func readJson5Number(source []rune, pos int, line int) (string, int, error) {
+	next := pos
+	for next < len(source) && (strings.ContainsRune("+-.xXabcdefABCDEF", source[next]) || unicode.IsDigit(source[next])) {
+		next++
+	}
+	// The identifiers after a sign (e.g. "-Infinity") are not numbers
+	if next < len(source) && unicode.IsLetter(source[next]) {
+		return "", next, fmt.Errorf("line %d: invalid number '%s'", line, string(source[pos:next+1]))
+	}
+	text := string(source[pos:next])
+	sign := ""
+	if strings.HasPrefix(text, "+") || strings.HasPrefix(text, "-") {
+		if text[0] == '-' {
+			sign = "-"
+		}
+		text = text[1:]
+	}
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+		value, err := strconv.ParseUint(text[2:], 16, 64)
+		if err != nil {
+			return "", next, fmt.Errorf("line %d: invalid number '%s'", line, string(source[pos:next]))
+		}
+		return sign + strconv.FormatUint(value, 10), next, nil
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return "", next, fmt.Errorf("line %d: invalid number '%s'", line, string(source[pos:next]))
+	}
+	if strings.HasPrefix(text, ".") || strings.HasSuffix(text, ".") {
+		text = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return sign + text, next, nil
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TemplateFormatTestSuite struct {
+	suite.Suite
+}
+
+func TestTemplateFormatTestSuite(t *testing.T) {
+	suite.Run(t, new(TemplateFormatTestSuite))
+}
+
+func (suite *TemplateFormatTestSuite) TestUnmarshalTemplateFile_ValidInputs() {
+	tests := []struct {
+		testName     string
+		path         string
+		input        string
+		expectedJSON string
+	}{
+		{
+			testName:     "json",
+			path:         "company.template.json",
+			input:        `{ "b": "{{ Person.name }}", "a": [1] }`,
+			expectedJSON: `{"b":"{{ Person.name }}","a":[1]}`,
+		},
+		{
+			testName: "yaml keeps the key order",
+			path:     "company[2].template.yaml",
+			input: `
+zeta: "{{ Person.name }}"
+alpha:
+  - 1
+  - two
+  - true
+  - null
+date: 2024-01-01
+`,
+			expectedJSON: `{"zeta":"{{ Person.name }}","alpha":[1,"two",true,null],"date":"2024-01-01"}`,
+		},
+		{
+			testName: "yaml anchors and merge keys",
+			path:     "company.template.yml",
+			input: `
+base: &base
+  name: "{{ Company.name }}"
+  kind: base
+other:
+  <<: *base
+  kind: other
+copy: *base
+`,
+			expectedJSON: `{"base":{"name":"{{ Company.name }}","kind":"base"},"other":{"kind":"other","name":"{{ Company.name }}"},"copy":{"name":"{{ Company.name }}","kind":"base"}}`,
+		},
+		{
+			testName:     "yaml root array",
+			path:         "list.template.yaml",
+			input:        "- a\n- b\n",
+			expectedJSON: `["a","b"]`,
+		},
+		{
+			testName: "json with comments and trailing commas",
+			path:     "company.template.jsonc",
+			input: `{
+				// The name of the company
+				"name": "{{ Company.name }}", /* inline */
+				"tags": ["a", "b",],
+			}`,
+			expectedJSON: `{"name":"{{ Company.name }}","tags":["a","b"]}`,
+		},
+		{
+			testName: "json5 unquoted keys, single quotes and numbers",
+			path:     "company.template.json5",
+			input: `{
+				name: 'O\'Brien "Co"',
+				$id: "{{ UUID.uuidv4 }}",
+				multi: 'line \
+continued',
+				hex: 0x1F, half: .5, whole: 5., positive: +1, negative: -2e2,
+			}`,
+			expectedJSON: `{"name":"O'Brien \"Co\"","$id":"{{ UUID.uuidv4 }}","multi":"line continued","hex":31,"half":0.5,"whole":5,"positive":1,"negative":-200}`,
+		},
+		{
+			testName:     "json5 unicode escapes and surrogate pairs",
+			path:         "company.template.json5",
+			input:        `{ accent: 'caf\u00e9', emoji: "\ud83d\ude00!", lone: '\ud83d-' }`,
+			expectedJSON: `{"accent":"café","emoji":"😀!","lone":"�-"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		template, err := UnmarshalTemplateFile(tt.path, []byte(tt.input))
+		suite.Require().NoError(err, "Test case '%s' failed", tt.testName)
+		encoded, err := json.Marshal(template)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", tt.testName)
+		assert.Equal(suite.T(), tt.expectedJSON, string(encoded), "Test case '%s' failed", tt.testName)
+	}
+}
+
+func (suite *TemplateFormatTestSuite) TestUnmarshalTemplateFile_InvalidInputs() {
+	tests := []struct {
+		testName      string
+		path          string
+		input         string
+		expectedError string
+	}{
+		{testName: "json with comments", path: "company.template.json", input: "{ // comment\n }", expectedError: "invalid character '/' looking for beginning of value"},
+		{testName: "yaml scalar root", path: "company.template.yaml", input: "name", expectedError: "template must be a JSON object or a JSON array"},
+		{testName: "empty yaml", path: "company.template.yaml", input: "", expectedError: "empty YAML document"},
+		{testName: "invalid yaml", path: "company.template.yaml", input: "a: [", expectedError: "yaml: line 1: did not find expected node content"},
+		{testName: "json5 unterminated comment", path: "company.template.json5", input: "{\n /* comment", expectedError: "line 2: unterminated comment"},
+		{testName: "json5 unterminated string", path: "company.template.json5", input: "{\n\n a: 'b\n }", expectedError: "line 3: unterminated string"},
+		{testName: "json5 unquoted value", path: "company.template.json5", input: "{ a: b }", expectedError: "line 1: unexpected identifier 'b'"},
+		{testName: "json5 infinity", path: "company.template.json5", input: "{\n a: Infinity }", expectedError: "line 2: 'Infinity' is not supported in templates"},
+	}
+
+	for _, tt := range tests {
+		_, err := UnmarshalTemplateFile(tt.path, []byte(tt.input))
+		assert.EqualError(suite.T(), err, tt.expectedError, "Test case '%s' failed", tt.testName)
+	}
+}
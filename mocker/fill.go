@@ -0,0 +1,354 @@
+package mocker
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Name of the struct tag read by `Fill` (e.g. `ktns:"Person.name"` or `ktns:"Lorem.word,len=3"`).
+const FillTag = "ktns"
+
+// Length of the slices whose tag doesn't set one.
+const fillDefaultLen = 1
+
+// How deep `Fill` goes into nested structs, slices and pointers, so recursive types (e.g. a linked list) end.
+// Deeper pointers and slices are left nil.
+const fillMaxDepth = 8
+
+// Types that generate their own mock values when filled, taking the place of the tags and inferred functions.
+type Generator interface {
+	GenerateMock(mocker Mocker) error
+}
+
+// Fills the exported fields of the struct (or any value) `target` points to with mock data, from a new random mocker.
+// Check `FillWith`.
+func Fill(target any) error {
+	return FillWith(New(), target)
+}
+
+// Fills the exported fields of the struct (or any value) `target` points to with mock data from `mocker`.
+//
+// Fields are filled by the mock function of their `ktns` tag (e.g. `ktns:"Number.number::18:60"`), or by one
+// inferred from their name and type (e.g. "Email" -> Internet.email, int -> Number.number::0:100). The generated
+// values are converted to the type of the field (numbers, booleans, time.Time or encoding.TextUnmarshaler types).
+//
+// Nested structs, arrays, slices (with the length of `len=N` in the tag, e.g. `ktns:",len=3"`) and pointers are
+// filled recursively, types implementing `Generator` fill themselves, and fields tagged `ktns:"-"` are skipped.
+// Maps, interfaces, channels and functions are left untouched.
+func FillWith(mocker Mocker, target any) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer (got %T)", target)
+	}
+	filler := &filler{mocker: mocker}
+	return filler.fill(value.Elem(), fillField{path: value.Elem().Type().Name()}, 0)
+}
+
+// A field being filled: its path (for errors), name (to infer functions) and tag.
+type fillField struct {
+	path     string
+	name     string
+	function string
+	params   []string
+	length   int
+}
+
+type filler struct {
+	mocker Mocker
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	generatorType     = reflect.TypeFor[Generator]()
+	textUnmarshalType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+func (f *filler) fill(value reflect.Value, field fillField, depth int) error {
+	if value.CanAddr() && value.Addr().Type().Implements(generatorType) {
+		if err := value.Addr().Interface().(Generator).GenerateMock(f.mocker); err != nil {
+			return fmt.Errorf("failed to fill '%s' '%w'", field.path, err)
+		}
+		return nil
+	}
+	// Values set from a text are generated as a whole, even when they are arrays or structs (e.g. a [16]byte UUID)
+	if value.Kind() != reflect.Pointer && value.CanAddr() && value.Addr().Type().Implements(textUnmarshalType) {
+		return f.fillScalar(value, field)
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer:
+		if value.IsNil() {
+			// pointers past the depth are left nil, instead of pointing to an empty value
+			if depth >= fillMaxDepth {
+				return nil
+			}
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		return f.fill(value.Elem(), field, depth+1)
+	case reflect.Struct:
+		if value.Type() == timeType || field.function != "" {
+			return f.fillScalar(value, field)
+		}
+		return f.fillStruct(value, field, depth)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 && field.function != "" {
+			return f.fillScalar(value, field)
+		}
+		if depth >= fillMaxDepth {
+			return nil
+		}
+		length := field.length
+		if length == 0 {
+			length = fillDefaultLen
+		}
+		value.Set(reflect.MakeSlice(value.Type(), length, length))
+		return f.fillItems(value, field, depth)
+	case reflect.Array:
+		return f.fillItems(value, field, depth)
+	case reflect.Map, reflect.Interface, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return nil
+	default:
+		return f.fillScalar(value, field)
+	}
+}
+
+func (f *filler) fillStruct(value reflect.Value, field fillField, depth int) error {
+	for idx := range value.NumField() {
+		structField := value.Type().Field(idx)
+		if !structField.IsExported() {
+			continue
+		}
+		tag := structField.Tag.Get(FillTag)
+		if tag == "-" {
+			continue
+		}
+		path := structField.Name
+		if field.path != "" {
+			path = field.path + "." + structField.Name
+		}
+		child, err := parseFillTag(tag)
+		if err != nil {
+			return fmt.Errorf("invalid tag of '%s' '%w'", path, err)
+		}
+		child.path, child.name = path, structField.Name
+		if err := f.fill(value.Field(idx), child, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Fills every item of a slice or array with the function of the field (the length only applies to the outer slice).
+func (f *filler) fillItems(value reflect.Value, field fillField, depth int) error {
+	item := field
+	item.length = 0
+	for idx := range value.Len() {
+		item.path = fmt.Sprintf("%s[%d]", field.path, idx)
+		if err := f.fill(value.Index(idx), item, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Generates the value of the function of the field (or the inferred one) and sets it, converted to the field type.
+func (f *filler) fillScalar(value reflect.Value, field fillField) error {
+	function, params := field.function, field.params
+	if function == "" {
+		function, params = inferFillFunction(field.name, value.Type())
+	}
+	generated, err := f.mocker.Generate(function, params)
+	if err != nil {
+		return fmt.Errorf("failed to fill '%s' '%w'", field.path, err)
+	}
+	if err := setFillValue(value, generated); err != nil {
+		return fmt.Errorf("failed to fill '%s' with '%s' '%w'", field.path, generated, err)
+	}
+	return nil
+}
+
+// Converts a generated value to the type of the field and sets it.
+func setFillValue(value reflect.Value, generated string) error {
+	if value.Type() == timeType {
+		parsed, err := parseFillTime(generated)
+		if err != nil {
+			return err
+		}
+		value.Set(reflect.ValueOf(parsed))
+		return nil
+	}
+	if value.CanAddr() && value.Addr().Type().Implements(textUnmarshalType) {
+		return value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(generated))
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(generated)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(generated)
+		if err != nil {
+			return err
+		}
+		value.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// numbers with decimals are truncated
+		parsed, err := strconv.ParseFloat(generated, 64)
+		if err != nil {
+			return err
+		}
+		if value.OverflowInt(int64(parsed)) {
+			return fmt.Errorf("value overflows %s", value.Type())
+		}
+		value.SetInt(int64(parsed))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		parsed, err := strconv.ParseFloat(generated, 64)
+		if err != nil {
+			return err
+		}
+		if parsed < 0 || value.OverflowUint(uint64(parsed)) {
+			return fmt.Errorf("value overflows %s", value.Type())
+		}
+		value.SetUint(uint64(parsed))
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(generated, 64)
+		if err != nil {
+			return err
+		}
+		value.SetFloat(parsed)
+	case reflect.Slice:
+		// []byte
+		value.SetBytes([]byte(generated))
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+// Parses the dates of Time.date, in any of its formats.
+func parseFillTime(generated string) (time.Time, error) {
+	if unix, err := strconv.ParseInt(generated, 10, 64); err == nil {
+		return time.Unix(unix, 0).UTC(), nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly, time.TimeOnly} {
+		if parsed, err := time.Parse(layout, generated); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date")
+}
+
+// Parses a tag of format "Function:param1:param2,len=N". Commas and colons inside /regex/ parameters are kept.
+func parseFillTag(tag string) (fillField, error) {
+	var field fillField
+	if tag == "" {
+		return field, nil
+	}
+	parts := splitOutsideRegex(tag, ',')
+	if parts[0] != "" {
+		function := splitOutsideRegex(strings.TrimSpace(parts[0]), ':')
+		field.function, field.params = function[0], function[1:]
+	}
+	for _, option := range parts[1:] {
+		option = strings.TrimSpace(option)
+		rawLength, ok := strings.CutPrefix(option, "len=")
+		if !ok {
+			return field, fmt.Errorf("unknown option '%s' (must be 'len=N')", option)
+		}
+		length, err := strconv.Atoi(rawLength)
+		if err != nil || length < 0 {
+			return field, fmt.Errorf("invalid length '%s' (must be a positive number)", rawLength)
+		}
+		field.length = length
+	}
+	return field, nil
+}
+
+// Splits a string on a separator, except inside regex parameters wrapped with slashes (/.../).
+func splitOutsideRegex(str string, separator rune) []string {
+	var parts []string
+	var buf strings.Builder
+	inRegex := false
+	for _, char := range str {
+		if char == '/' {
+			inRegex = !inRegex
+		}
+		if char == separator && !inRegex {
+			parts = append(parts, buf.String())
+			buf.Reset()
+			continue
+		}
+		buf.WriteRune(char)
+	}
+	return append(parts, buf.String())
+}
+
+// Mock functions of the string fields, by their name (lowercased, without '_').
+var fillNameFunctions = map[string]string{
+	"name":         "Person.name",
+	"fullname":     "Person.name",
+	"firstname":    "Person.firstName",
+	"lastname":     "Person.lastName",
+	"surname":      "Person.lastName",
+	"email":        "Internet.email",
+	"phone":        "Person.phoneNumber",
+	"phonenumber":  "Person.phoneNumber",
+	"password":     "Internet.password",
+	"url":          "Internet.url",
+	"website":      "Internet.url",
+	"domain":       "Internet.domain",
+	"id":           "UUID.uuidv4",
+	"uuid":         "UUID.uuidv4",
+	"cpf":          "Person.cpf",
+	"cnpj":         "Company.cnpj",
+	"company":      "Company.name",
+	"companyname":  "Company.name",
+	"jobtitle":     "Company.jobTitle",
+	"city":         "Address.city",
+	"state":        "Address.state",
+	"country":      "Address.country",
+	"street":       "Address.streetName",
+	"streetname":   "Address.streetName",
+	"zip":          "Address.postCode",
+	"zipcode":      "Address.postCode",
+	"postcode":     "Address.postCode",
+	"postalcode":   "Address.postCode",
+	"title":        "Lorem.sentence:3",
+	"description":  "Lorem.sentence:8",
+	"useragent":    "UserAgent.userAgent",
+	"currency":     "Currency.currencyCode",
+	"filename":     "File.filenameWithExtension",
+	"macaddress":   "Internet.macAddress",
+	"latitude":     "Address.latitude",
+	"longitude":    "Address.longitude",
+	"licenseplate": "Car.plate",
+}
+
+// Infers the mock function of a field without tag, from its name and type.
+func inferFillFunction(name string, valueType reflect.Type) (string, []string) {
+	key := strings.ToLower(strings.ReplaceAll(name, "_", ""))
+	switch {
+	case valueType == timeType:
+		return "Time.date", []string{"datetime"}
+	case valueType.Kind() == reflect.Bool:
+		return "Boolean.boolean", nil
+	case valueType.Kind() == reflect.Float32 || valueType.Kind() == reflect.Float64:
+		if key == "latitude" || key == "longitude" {
+			return "Address." + key, nil
+		}
+		return "Number.number", []string{"2", "0", "100"}
+	case valueType.Kind() >= reflect.Int && valueType.Kind() <= reflect.Uintptr:
+		if key == "age" {
+			return "Number.number", []string{"", "18", "90"}
+		}
+		return "Number.number", []string{"", "0", "100"}
+	}
+	if function, ok := fillNameFunctions[key]; ok {
+		parts := splitOutsideRegex(function, ':')
+		return parts[0], parts[1:]
+	}
+	return "Lorem.word", nil
+}
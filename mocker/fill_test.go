@@ -0,0 +1,201 @@
+package mocker
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type FillTestSuite struct {
+	suite.Suite
+}
+
+func TestFillTestSuite(t *testing.T) {
+	suite.Run(t, new(FillTestSuite))
+}
+
+type fillAddress struct {
+	City    string
+	ZipCode string `ktns:"Regex.regex:/[0-9]{5}-[0-9]{3}/"`
+}
+
+type fillMoney struct {
+	Cents    int64
+	Currency string
+}
+
+func (m *fillMoney) GenerateMock(mocker Mocker) error {
+	m.Cents = int64(RandOf(mocker).Intn(1000)) * 100
+	m.Currency = "BRL"
+	return nil
+}
+
+type fillUser struct {
+	ID        string
+	Name      string `ktns:"Person.name"`
+	Email     string
+	Age       int `ktns:"Number.number::18:60"`
+	Score     float64
+	Active    bool
+	Tags      []string `ktns:"Lorem.word,len=3"`
+	Codes     []string `ktns:"Regex.regex:/[A-Z]{2,3}/,len=2"`
+	Address   fillAddress
+	Previous  []fillAddress `ktns:",len=2"`
+	Manager   *fillUser
+	CreatedAt time.Time
+	Birthday  *time.Time `ktns:"Time.date:date"`
+	Balance   fillMoney
+	IP        netip.Addr `ktns:"Internet.ipv4"`
+	Notes     string     `ktns:"-"`
+	Extra     map[string]string
+	internal  string
+}
+
+func (suite *FillTestSuite) TestFillWith() {
+	var user fillUser
+	user.Notes = "kept"
+	suite.Require().NoError(FillWith(NewWithSeed(1), &user))
+
+	assert.Regexp(suite.T(), `^[0-9a-f]{8}-[0-9a-f]{4}-4`, user.ID, "'ID' is inferred as an UUID")
+	assert.NotEmpty(suite.T(), user.Name)
+	assert.Contains(suite.T(), user.Email, "@", "'Email' is inferred as an email")
+	assert.GreaterOrEqual(suite.T(), user.Age, 18)
+	assert.LessOrEqual(suite.T(), user.Age, 60)
+	assert.GreaterOrEqual(suite.T(), user.Score, 0.0)
+	assert.Len(suite.T(), user.Tags, 3)
+	assert.Len(suite.T(), user.Codes, 2)
+	for _, code := range user.Codes {
+		assert.Regexp(suite.T(), `^[A-Z]{2,3}$`, code, "commas inside regexes are kept")
+	}
+	assert.NotEmpty(suite.T(), user.Address.City)
+	assert.Regexp(suite.T(), `^[0-9]{5}-[0-9]{3}$`, user.Address.ZipCode)
+	assert.Len(suite.T(), user.Previous, 2)
+	assert.NotEmpty(suite.T(), user.Previous[1].City)
+	suite.Require().NotNil(suite.T(), user.Manager)
+	assert.NotEmpty(suite.T(), user.Manager.Name, "pointers are filled")
+	assert.False(suite.T(), user.CreatedAt.IsZero())
+	suite.Require().NotNil(suite.T(), user.Birthday)
+	assert.Equal(suite.T(), time.UTC, user.Birthday.Location())
+	assert.Equal(suite.T(), "BRL", user.Balance.Currency, "generators fill themselves")
+	assert.True(suite.T(), user.IP.Is4(), "text unmarshalers are parsed")
+	assert.Equal(suite.T(), "kept", user.Notes, "'-' fields are skipped")
+	assert.Nil(suite.T(), user.Extra)
+	assert.Empty(suite.T(), user.internal)
+
+	var again fillUser
+	suite.Require().NoError(FillWith(NewWithSeed(1), &again))
+	again.Notes = "kept"
+	assert.Equal(suite.T(), user, again, "the same seed fills the same values")
+}
+
+// An UUID held in its bytes, as uuid.UUID is.
+type fillUUID [16]byte
+
+func (u *fillUUID) UnmarshalText(text []byte) error {
+	decoded, err := hex.DecodeString(strings.ReplaceAll(string(text), "-", ""))
+	if err != nil || len(decoded) != len(u) {
+		return fmt.Errorf("invalid UUID '%s'", text)
+	}
+	copy(u[:], decoded)
+	return nil
+}
+
+func (suite *FillTestSuite) TestFill_TextUnmarshalerArrays() {
+	var order struct {
+		ID      fillUUID   `ktns:"UUID.uuidv4"`
+		Related []fillUUID `ktns:"UUID.uuidv4,len=2"`
+	}
+	suite.Require().NoError(Fill(&order))
+
+	assert.Equal(suite.T(), byte(0x40), order.ID[6]&0xf0, "the array is set from the text of an UUID v4")
+	assert.Len(suite.T(), order.Related, 2)
+	for _, related := range order.Related {
+		assert.Equal(suite.T(), byte(0x40), related[6]&0xf0)
+	}
+}
+
+func (suite *FillTestSuite) TestFill_RecursiveTypes() {
+	type node struct {
+		Value string
+		Next  *node
+	}
+	var list node
+	suite.Require().NoError(Fill(&list))
+
+	depth := 0
+	for current := &list; current != nil; current = current.Next {
+		assert.NotEmpty(suite.T(), current.Value)
+		depth++
+	}
+	assert.LessOrEqual(suite.T(), depth, fillMaxDepth, "recursive types end")
+}
+
+func (suite *FillTestSuite) TestFill_Values() {
+	var names []string
+	suite.Require().NoError(Fill(&names))
+	assert.Len(suite.T(), names, fillDefaultLen)
+
+	var count uint8
+	suite.Require().NoError(Fill(&count))
+	assert.LessOrEqual(suite.T(), count, uint8(100))
+}
+
+func (suite *FillTestSuite) TestFill_InvalidInputs() {
+	var user fillUser
+	tests := []struct {
+		testName      string
+		target        any
+		expectedError string
+	}{
+		{
+			testName:      "not a pointer",
+			target:        user,
+			expectedError: "target must be a non-nil pointer (got mocker.fillUser)",
+		},
+		{
+			testName:      "nil pointer",
+			target:        (*fillUser)(nil),
+			expectedError: "target must be a non-nil pointer (got *mocker.fillUser)",
+		},
+		{
+			testName: "unknown function",
+			target: &struct {
+				Name string `ktns:"Person.nickname"`
+			}{},
+			expectedError: "failed to fill 'Name' 'unknown mock function 'Person.nickname''",
+		},
+		{
+			testName: "value of another type",
+			target: &struct {
+				Age int `ktns:"Person.name"`
+			}{},
+			expectedError: "failed to fill 'Age' with",
+		},
+		{
+			testName: "unknown option",
+			target: &struct {
+				Tags []string `ktns:"Lorem.word,size=3"`
+			}{},
+			expectedError: "invalid tag of 'Tags' 'unknown option 'size=3' (must be 'len=N')'",
+		},
+		{
+			testName: "invalid length",
+			target: &struct {
+				Tags []string `ktns:",len=many"`
+			}{},
+			expectedError: "invalid tag of 'Tags' 'invalid length 'many' (must be a positive number)'",
+		},
+	}
+
+	for _, test := range tests {
+		err := Fill(test.target)
+		suite.Require().Error(err, "Test case '%s' failed", test.testName)
+		assert.True(suite.T(), strings.HasPrefix(err.Error(), test.expectedError), fmt.Sprintf("Test case '%s' failed: %s", test.testName, err))
+	}
+}
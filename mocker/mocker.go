@@ -6,6 +6,7 @@ import (
 	"math/rand"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jaswdr/faker/v2"
 	regen "github.com/zach-klippenstein/goregen"
@@ -16,18 +17,68 @@ type Mocker interface {
 	Generate(mockFunction string, functionParams []string) (string, error)
 }
 
+// Implemented by the mockers that draw every random value from a source, so other random choices (e.g. picked
+// references or branches) can follow the same seed.
+type RandSource interface {
+	Rand() *rand.Rand
+}
+
+// Returns the random source of a mocker, or one drawing from the global source of math/rand (safe for concurrent
+// use) when the mocker doesn't have one, so its choices don't follow a seed.
+func RandOf(m Mocker) *rand.Rand {
+	if source, ok := m.(RandSource); ok {
+		return source.Rand()
+	}
+	return globalRand
+}
+
+var globalRand = rand.New(globalSource{})
+
+// A rand.Source backed by the global functions of math/rand.
+type globalSource struct{}
+
+func (globalSource) Int63() int64    { return rand.Int63() }
+func (globalSource) Uint64() uint64  { return rand.Uint64() }
+func (globalSource) Seed(seed int64) {}
+
+// Locale of the generated data, the only one the mock functions generate for now.
+const DefaultLocale = "en_US"
+
+// Range of the dates generated by Time.date.
+var (
+	dateFrom = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	dateTo   = time.Date(2031, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// A Mock is not safe for concurrent use, each goroutine must have its own.
 type Mock struct {
 	jaswdrFaker *faker.Faker
+	random      *rand.Rand
 }
 
 func New() *Mock {
-	jaswdrFaker := faker.New()
+	return NewWithSeed(rand.Int63())
+}
+
+// Returns a mocker whose generated values only depend on the seed.
+func NewWithSeed(seed int64) *Mock {
+	random := rand.New(rand.NewSource(seed))
 
 	return &Mock{
-		jaswdrFaker: &jaswdrFaker,
+		jaswdrFaker: &faker.Faker{Generator: random},
+		random:      random,
 	}
 }
 
+// Restarts the random sequence of the mocker, as if it was created with `NewWithSeed(seed)`.
+func (m *Mock) Reseed(seed int64) {
+	m.random.Seed(seed)
+}
+
+func (m *Mock) Rand() *rand.Rand {
+	return m.random
+}
+
 func tableLineDivider(colSizes []int) string {
 	var line string
 	for idx, size := range colSizes {
@@ -129,9 +180,11 @@ func (m *Mock) List(out io.Writer) {
 	fmt.Fprintf(out, "%s\n", tableLineData(colSizes, []string{"Person.name", "Generates a random name"}))
 	fmt.Fprintf(out, "%s\n", tableLineData(colSizes, []string{"Person.cpf", "Generates a random valid brazilian cpf"}))
 	fmt.Fprintf(out, "%s\n", tableLineDivider(colSizes))
+	fmt.Fprintf(out, "%s\n", tableLineData(colSizes, []string{"Ref.pick:[template.key]:[unique|each]", "Picks a value generated by another template (--parse-files)"}))
+	fmt.Fprintf(out, "%s\n", tableLineDivider(colSizes))
 	fmt.Fprintf(out, "%s\n", tableLineData(colSizes, []string{"Regex.regex:[regex]", "Generates a random string based on the regex pattern"}))
 	fmt.Fprintf(out, "%s\n", tableLineDivider(colSizes))
-	fmt.Fprintf(out, "%s\n", tableLineData(colSizes, []string{"Time.date", "Generates a random date"}))
+	fmt.Fprintf(out, "%s\n", tableLineData(colSizes, []string{"Time.date:[date|datetime|time|unix]", "Generates a random date (from 2000 to 2030, in UTC)"}))
 	fmt.Fprintf(out, "%s\n", tableLineDivider(colSizes))
 	fmt.Fprintf(out, "%s\n", tableLineData(colSizes, []string{"UUID.uuidv4", "Generates a random UUID v4"}))
 	fmt.Fprintf(out, "%s\n", tableLineDivider(colSizes))
@@ -198,7 +251,7 @@ func (m *Mock) Generate(mockFunction string, functionParams []string) (string, e
 
 		// Generate the first 12 random digits
 		for i := range 12 {
-			cnpj[i] = rand.Intn(10)
+			cnpj[i] = m.random.Intn(10)
 		}
 
 		// Multipliers for checksum digits
@@ -312,7 +365,7 @@ func (m *Mock) Generate(mockFunction string, functionParams []string) (string, e
 	case "Payment.creditCardType":
 		return m.jaswdrFaker.Payment().CreditCardType(), nil
 	case "Payment.creditCardCvv":
-		cvv, err := regen.Generate("[0-9]{3}")
+		cvv, err := m.generateRegex("[0-9]{3}")
 		if err != nil {
 			return "", fmt.Errorf("failed to generate CVV '%w'", err)
 		}
@@ -335,7 +388,7 @@ func (m *Mock) Generate(mockFunction string, functionParams []string) (string, e
 
 		// Generate the first 9 random digits
 		for i := range 9 {
-			cpf[i] = rand.Intn(10)
+			cpf[i] = m.random.Intn(10)
 		}
 
 		// Multipliers for checksum digits
@@ -353,6 +406,11 @@ func (m *Mock) Generate(mockFunction string, functionParams []string) (string, e
 			cpf[6]*100+cpf[7]*10+cpf[8],
 			cpf[9]*10+cpf[10],
 		), nil
+	/*
+		REF
+	*/
+	case "Ref.pick":
+		return "", fmt.Errorf("Ref.pick is only available when generating from template files (--parse-files)")
 	/*
 		REGEX
 	*/
@@ -364,7 +422,7 @@ func (m *Mock) Generate(mockFunction string, functionParams []string) (string, e
 		if err != nil {
 			return "", err
 		}
-		randomRegex, err := regen.Generate(regex)
+		randomRegex, err := m.generateRegex(regex)
 		if err != nil {
 			return "", fmt.Errorf("failed to generate regex '%w'", err)
 		}
@@ -373,12 +431,16 @@ func (m *Mock) Generate(mockFunction string, functionParams []string) (string, e
 		TIME
 	*/
 	case "Time.date":
-		return "", nil
+		layout := "date"
+		if len(functionParams) > 0 && functionParams[0] != "" {
+			layout = functionParams[0]
+		}
+		return m.date(layout)
 	/*
 		UUID
 	*/
 	case "UUID.uuidv4":
-		return m.jaswdrFaker.UUID().V4(), nil
+		return m.uuidV4(), nil
 	/*
 		USER AGENT
 	*/
@@ -388,3 +450,42 @@ func (m *Mock) Generate(mockFunction string, functionParams []string) (string, e
 		return "", fmt.Errorf("unknown mock function '%s'", mockFunction)
 	}
 }
+
+// Generates a string matching the regex, using the mocker's random source.
+func (m *Mock) generateRegex(regex string) (string, error) {
+	generator, err := regen.NewGenerator(regex, &regen.GeneratorArgs{RngSource: m.random})
+	if err != nil {
+		return "", err
+	}
+	return generator.Generate(), nil
+}
+
+// Formats of the dates generated by Time.date.
+var dateLayouts = map[string]string{
+	"date":     "2006-01-02",
+	"datetime": time.RFC3339,
+	"time":     "15:04:05",
+}
+
+// Generates a random date (in UTC) between dateFrom and dateTo, in one of the dateLayouts (or "unix" for the seconds since 1970).
+func (m *Mock) date(layout string) (string, error) {
+	seconds := dateFrom.Unix() + m.random.Int63n(dateTo.Unix()-dateFrom.Unix())
+	date := time.Unix(seconds, 0).UTC()
+	if layout == "unix" {
+		return strconv.FormatInt(seconds, 10), nil
+	}
+	goLayout, ok := dateLayouts[layout]
+	if !ok {
+		return "", fmt.Errorf("invalid date format '%s' (must be one of 'date', 'datetime', 'time' or 'unix')", layout)
+	}
+	return date.Format(goLayout), nil
+}
+
+// Generates a version 4 UUID using the mocker's random source. (The faker's UUID ignores the seed)
+func (m *Mock) uuidV4() string {
+	var uuid [16]byte
+	m.random.Read(uuid[:])
+	uuid[6] = (uuid[6] & 0x0f) | 0x40 // Version 4
+	uuid[8] = (uuid[8] & 0x3f) | 0x80 // Variant RFC4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
@@ -0,0 +1,103 @@
+package mocker
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockerTestSuite struct {
+	suite.Suite
+}
+
+func TestMockerTestSuite(t *testing.T) {
+	suite.Run(t, new(MockerTestSuite))
+}
+
+// Generates one value of each function, in order.
+func (suite *MockerTestSuite) generateAll(mocker *Mock) []string {
+	functions := []struct {
+		name   string
+		params []string
+	}{
+		{name: "Person.name"},
+		{name: "Company.cnpj"},
+		{name: "Person.cpf"},
+		{name: "Payment.creditCardCvv"},
+		{name: "Regex.regex", params: []string{"/[a-z]{8}/"}},
+		{name: "UUID.uuidv4"},
+		{name: "Number.number", params: []string{"2", "1", "100"}},
+		{name: "Time.date", params: []string{"datetime"}},
+	}
+	values := make([]string, 0, len(functions))
+	for _, function := range functions {
+		value, err := mocker.Generate(function.name, function.params)
+		suite.Require().NoError(err)
+		values = append(values, value)
+	}
+	return values
+}
+
+func (suite *MockerTestSuite) TestNewWithSeed_SameSeedSameValues() {
+	first := suite.generateAll(NewWithSeed(42))
+	second := suite.generateAll(NewWithSeed(42))
+	other := suite.generateAll(NewWithSeed(43))
+
+	assert.Equal(suite.T(), first, second)
+	assert.NotEqual(suite.T(), first, other)
+}
+
+func (suite *MockerTestSuite) TestReseed_RestartsTheValues() {
+	mocker := NewWithSeed(1)
+	suite.generateAll(mocker)
+	mocker.Reseed(42)
+
+	assert.Equal(suite.T(), suite.generateAll(NewWithSeed(42)), suite.generateAll(mocker))
+}
+
+func (suite *MockerTestSuite) TestUUIDv4_Format() {
+	value, err := New().Generate("UUID.uuidv4", nil)
+	assert.NoError(suite.T(), err)
+	assert.Regexp(suite.T(), `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, value)
+}
+
+func (suite *MockerTestSuite) TestTimeDate_Formats() {
+	tests := []struct {
+		testName string
+		params   []string
+		expected string
+	}{
+		{testName: "default", params: nil, expected: `^20[0-3]\d-\d{2}-\d{2}$`},
+		{testName: "date", params: []string{"date"}, expected: `^20[0-3]\d-\d{2}-\d{2}$`},
+		{testName: "datetime", params: []string{"datetime"}, expected: `^20[0-3]\d-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z$`},
+		{testName: "time", params: []string{"time"}, expected: `^\d{2}:\d{2}:\d{2}$`},
+		{testName: "unix", params: []string{"unix"}, expected: `^\d{9,10}$`},
+	}
+
+	for _, tt := range tests {
+		value, err := New().Generate("Time.date", tt.params)
+		assert.NoError(suite.T(), err, "Test case '%s' failed", tt.testName)
+		assert.Regexp(suite.T(), tt.expected, value, "Test case '%s' failed", tt.testName)
+	}
+
+	_, err := New().Generate("Time.date", []string{"week"})
+	assert.EqualError(suite.T(), err, "invalid date format 'week' (must be one of 'date', 'datetime', 'time' or 'unix')")
+}
+
+// A mocker implementing only the Mocker interface (as the ones written before RandSource).
+type generateOnlyMocker struct{}
+
+func (generateOnlyMocker) List(out io.Writer) {}
+
+func (generateOnlyMocker) Generate(mockFunction string, functionParams []string) (string, error) {
+	return mockFunction, nil
+}
+
+func (suite *MockerTestSuite) TestRandOf() {
+	seeded := NewWithSeed(3)
+	assert.Same(suite.T(), seeded.Rand(), RandOf(seeded), "the source of a RandSource is used")
+	assert.NotNil(suite.T(), RandOf(generateOnlyMocker{}), "mockers without a source get the global one")
+	assert.Less(suite.T(), RandOf(generateOnlyMocker{}).Intn(10), 10)
+}
@@ -0,0 +1,246 @@
+// Package template renders ktns templates inside Go programs, so fixtures can be generated directly in `go test`
+// (without running the ktns binary).
+//
+// Templates are the same as the ones of `ktns mock`: JSON (or YAML/JSON5) objects or arrays whose strings may hold
+// mock functions (e.g. "{{ Person.name }}"), with "key[N]" arrays, generated keys, "$include", "$ref", "$if" and "$oneOf".
+// Their "$meta" section is ignored, the options take its place.
+//
+//	tmpl, err := template.Compile([]byte(`{ "name": "{{ Person.name }}", "tags[2]": "{{ Lorem.word }}" }`), template.WithSeed(42))
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	var user User
+//	err = tmpl.RenderInto(&user)
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lfsc09/k-test-n-stress/internal/engine"
+	"github.com/lfsc09/k-test-n-stress/mocker"
+)
+
+// Languages a template can be written in.
+type Format string
+
+const (
+	FormatJSON  Format = "JSON"
+	FormatYAML  Format = "YAML"
+	FormatJSON5 Format = "JSON5"
+)
+
+// Locale of the generated data, the only one the mock functions generate.
+const DefaultLocale = mocker.DefaultLocale
+
+// A custom mock function, called with the parameters after its name (e.g. ["1", "5"] for "{{ Custom.fn:1:5 }}").
+// Random choices should come from `random`, so they follow the seed of the template.
+type Function func(random *rand.Rand, params []string) (string, error)
+
+// Configures how templates are compiled and rendered.
+type Option func(*config) error
+
+type config struct {
+	seed      *int64
+	locale    string
+	format    Format
+	baseDir   string
+	mocker    mocker.Mocker
+	functions map[string]Function
+}
+
+// Renders always the same data for the same seed. (Without it, a random seed is used)
+func WithSeed(seed int64) Option {
+	return func(c *config) error {
+		c.seed = &seed
+		return nil
+	}
+}
+
+// Sets the locale of the generated data. Only `DefaultLocale` is available for now.
+func WithLocale(locale string) Option {
+	return func(c *config) error {
+		if locale != DefaultLocale {
+			return fmt.Errorf("unsupported locale '%s' (must be '%s')", locale, DefaultLocale)
+		}
+		c.locale = locale
+		return nil
+	}
+}
+
+// Sets the language of the template passed to `Compile` (defaults to JSON).
+// `CompileFile` picks it from the extension of the file unless it is set.
+func WithFormat(format Format) Option {
+	return func(c *config) error {
+		if !slices.Contains(engine.TemplateLanguages(), string(format)) {
+			return fmt.Errorf("invalid format '%s' (must be one of 'JSON', 'YAML' or 'JSON5')", format)
+		}
+		c.format = format
+		return nil
+	}
+}
+
+// Sets the directory the "$include" paths of the template passed to `Compile` are relative to (defaults to the
+// current directory). `CompileFile` uses the directory of the file.
+func WithBaseDir(dir string) Option {
+	return func(c *config) error {
+		c.baseDir = dir
+		return nil
+	}
+}
+
+// Renders the data with the given mocker instead of a new one (the seed is then ignored).
+// Mockers are not safe for concurrent use, so neither is a template rendering with it.
+func WithMocker(instance mocker.Mocker) Option {
+	return func(c *config) error {
+		if instance == nil {
+			return fmt.Errorf("mocker can't be nil")
+		}
+		c.mocker = instance
+		return nil
+	}
+}
+
+// Adds a custom mock function, used in templates as any other one (e.g. "{{ Custom.sku:3 }}").
+// It takes the place of a mock function with the same name.
+func WithFunction(name string, function Function) Option {
+	return func(c *config) error {
+		if name == "" || strings.ContainsAny(name, ": \t\n{}") {
+			return fmt.Errorf("invalid function name '%s' (it can't be empty or have ':', spaces or braces)", name)
+		}
+		if function == nil {
+			return fmt.Errorf("function '%s' can't be nil", name)
+		}
+		c.functions[name] = function
+		return nil
+	}
+}
+
+func newConfig(opts []Option) (*config, error) {
+	c := &config{locale: DefaultLocale, baseDir: ".", functions: make(map[string]Function)}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Returns the mocker of the options, wrapped with the custom functions (when there are any).
+func (c *config) newMocker() mocker.Mocker {
+	var base mocker.Mocker
+	switch {
+	case c.mocker != nil:
+		base = c.mocker
+	case c.seed != nil:
+		base = mocker.NewWithSeed(*c.seed)
+	default:
+		base = mocker.New()
+	}
+	if len(c.functions) == 0 {
+		return base
+	}
+	return &functionMocker{Mocker: base, functions: c.functions}
+}
+
+// A mocker that generates the custom functions, leaving every other one to the mocker it wraps.
+type functionMocker struct {
+	mocker.Mocker
+	functions map[string]Function
+}
+
+func (m *functionMocker) Generate(mockFunction string, functionParams []string) (string, error) {
+	function, ok := m.functions[mockFunction]
+	if !ok {
+		return m.Mocker.Generate(mockFunction, functionParams)
+	}
+	return function(m.Rand(), functionParams)
+}
+
+func (m *functionMocker) Rand() *rand.Rand {
+	return mocker.RandOf(m.Mocker)
+}
+
+// A compiled template, rendered as many times as needed. Each render generates new data, following the seed (so
+// the renders of two templates compiled with the same seed are the same).
+// A Template is not safe for concurrent use, each goroutine must compile its own.
+type Template struct {
+	parsed any
+	mocker mocker.Mocker
+}
+
+// Compiles a template (JSON by default, check `WithFormat`).
+func Compile(source []byte, opts ...Option) (*Template, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	format := c.format
+	if format == "" {
+		format = FormatJSON
+	}
+	parsed, err := engine.Parse(source, string(format), c.baseDir)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{parsed: parsed, mocker: c.newMocker()}, nil
+}
+
+// Compiles a template file (e.g. "user.template.yaml"), written in the language of its extension.
+func CompileFile(path string, opts ...Option) (*Template, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template '%w'", err)
+	}
+	fileOpts := []Option{WithFormat(Format(engine.TemplateLanguage(path))), WithBaseDir(filepath.Dir(path))}
+	return Compile(source, append(fileOpts, opts...)...)
+}
+
+// Renders the template, returning the generated data as JSON (keeping the key order of the template).
+func (t *Template) Render() ([]byte, error) {
+	record, err := engine.Render(t.parsed, t.mocker)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render template '%w'", err)
+	}
+	return json.Marshal(record)
+}
+
+// Renders the template into `v`, as `json.Unmarshal` would decode the generated data into it.
+// Mock functions generate strings (as in `ktns mock`), so numeric and boolean fields need the ",string" tag option.
+func (t *Template) RenderInto(v any) error {
+	rendered, err := t.Render()
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(rendered, v); err != nil {
+		return fmt.Errorf("failed to decode rendered template '%w'", err)
+	}
+	return nil
+}
+
+// Compiles and renders a template once, returning the generated data as JSON.
+func Render(source []byte, opts ...Option) ([]byte, error) {
+	tmpl, err := Compile(source, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.Render()
+}
+
+// Replaces every mock function of a string (e.g. "Hi {{ Person.firstName }}") by its generated value.
+func RenderString(str string, opts ...Option) (string, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return "", err
+	}
+	rendered, err := engine.RenderStr(str, c.newMocker())
+	if err != nil {
+		return "", fmt.Errorf("failed to render string '%w'", err)
+	}
+	return rendered, nil
+}
@@ -0,0 +1,174 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lfsc09/k-test-n-stress/mocker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TemplateTestSuite struct {
+	suite.Suite
+}
+
+func TestTemplateTestSuite(t *testing.T) {
+	suite.Run(t, new(TemplateTestSuite))
+}
+
+func (suite *TemplateTestSuite) TestCompile_InvalidInputs() {
+	tests := []struct {
+		testName      string
+		source        string
+		opts          []Option
+		expectedError string
+	}{
+		{
+			testName:      "invalid JSON",
+			source:        `{ "name": `,
+			expectedError: "failed to parse JSON template 'unexpected EOF'",
+		},
+		{
+			testName:      "root is not an object or array",
+			source:        `"{{ Person.name }}"`,
+			expectedError: "failed to parse JSON template 'template must be a JSON object or a JSON array'",
+		},
+		{
+			testName:      "invalid format",
+			source:        `{}`,
+			opts:          []Option{WithFormat("TOML")},
+			expectedError: "invalid format 'TOML' (must be one of 'JSON', 'YAML' or 'JSON5')",
+		},
+		{
+			testName:      "unsupported locale",
+			source:        `{}`,
+			opts:          []Option{WithLocale("pt_BR")},
+			expectedError: "unsupported locale 'pt_BR' (must be 'en_US')",
+		},
+		{
+			testName:      "invalid function name",
+			source:        `{}`,
+			opts:          []Option{WithFunction("Custom.sku:3", func(random *rand.Rand, params []string) (string, error) { return "", nil })},
+			expectedError: "invalid function name 'Custom.sku:3' (it can't be empty or have ':', spaces or braces)",
+		},
+		{
+			testName:      "nil mocker",
+			source:        `{}`,
+			opts:          []Option{WithMocker(nil)},
+			expectedError: "mocker can't be nil",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := Compile([]byte(test.source), test.opts...)
+		assert.EqualError(suite.T(), err, test.expectedError, "Test case '%s' failed", test.testName)
+	}
+}
+
+func (suite *TemplateTestSuite) TestRender_Seed() {
+	source := []byte(`{ "name": "{{ Person.name }}", "tags[3]": "{{ Lorem.word }}", "age": "{{ Number.number::18:99 }}" }`)
+
+	first, err := Render(source, WithSeed(42))
+	suite.Require().NoError(err)
+	second, err := Render(source, WithSeed(42))
+	suite.Require().NoError(err)
+	other, err := Render(source, WithSeed(7))
+	suite.Require().NoError(err)
+
+	assert.Equal(suite.T(), string(first), string(second), "the same seed renders the same data")
+	assert.NotEqual(suite.T(), string(first), string(other), "another seed renders other data")
+	assert.True(suite.T(), strings.HasPrefix(string(first), `{"name":"`), "the key order of the template is kept")
+
+	var record map[string]any
+	assert.NoError(suite.T(), json.Unmarshal(first, &record))
+	assert.Len(suite.T(), record["tags"], 3, "'key[N]' keys are sanitized")
+	assert.Regexp(suite.T(), `^[0-9]+$`, record["age"], "generated values are strings, as in 'ktns mock'")
+}
+
+func (suite *TemplateTestSuite) TestTemplate_RenderInto() {
+	type user struct {
+		Name   string   `json:"name"`
+		Emails []string `json:"emails"`
+		Active bool     `json:"active,string"`
+	}
+	tmpl, err := Compile([]byte(`{ "name": "{{ Person.name }}", "emails[2]": "{{ Person.email }}", "active": "{{ Boolean.boolean }}" }`), WithSeed(1))
+	suite.Require().NoError(err)
+
+	var first, second user
+	assert.NoError(suite.T(), tmpl.RenderInto(&first))
+	assert.NoError(suite.T(), tmpl.RenderInto(&second))
+	assert.NotEmpty(suite.T(), first.Name)
+	assert.Len(suite.T(), first.Emails, 2)
+	assert.NotEqual(suite.T(), first, second, "each render generates new data")
+
+	var invalid []string
+	assert.ErrorContains(suite.T(), tmpl.RenderInto(&invalid), "failed to decode rendered template")
+}
+
+func (suite *TemplateTestSuite) TestWithFunction() {
+	sku := func(random *rand.Rand, params []string) (string, error) {
+		if len(params) == 0 {
+			return "", fmt.Errorf("Custom.sku requires a prefix")
+		}
+		return fmt.Sprintf("%s-%04d", params[0], random.Intn(10000)), nil
+	}
+
+	rendered, err := Render([]byte(`{ "sku": "{{ Custom.sku:AB }}", "name": "{{ Person.firstName }}" }`), WithSeed(3), WithFunction("Custom.sku", sku))
+	assert.NoError(suite.T(), err)
+	assert.Regexp(suite.T(), `^\{"sku":"AB-\d{4}","name":"[^"]+"\}$`, string(rendered))
+
+	_, err = Render([]byte(`{ "sku": "{{ Custom.sku }}" }`), WithFunction("Custom.sku", sku))
+	assert.EqualError(suite.T(), err, "failed to render template 'Custom.sku requires a prefix'")
+
+	overridden, err := RenderString("{{ Person.name }}", WithFunction("Person.name", func(random *rand.Rand, params []string) (string, error) {
+		return "Ada", nil
+	}))
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "Ada", overridden, "custom functions take the place of the ones with the same name")
+}
+
+func (suite *TemplateTestSuite) TestWithMocker() {
+	source := []byte(`{ "id": "{{ UUID.uuidv4 }}" }`)
+	withMocker, err := Render(source, WithMocker(mocker.NewWithSeed(5)), WithSeed(6))
+	suite.Require().NoError(err)
+	withSeed, err := Render(source, WithSeed(5))
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), string(withSeed), string(withMocker), "the mocker takes the place of the seed")
+}
+
+func (suite *TemplateTestSuite) TestRenderString() {
+	rendered, err := RenderString("Hi {{ Person.firstName }}, your code is {{ Regex.regex:/[0-9][0-9][0-9][0-9]/ }}", WithSeed(9))
+	assert.NoError(suite.T(), err)
+	assert.Regexp(suite.T(), `^Hi \S+, your code is [0-9]{4}$`, rendered)
+
+	_, err = RenderString("{{ Person.unknown }}")
+	assert.ErrorContains(suite.T(), err, "failed to render string")
+}
+
+func (suite *TemplateTestSuite) TestCompileFile() {
+	dir := suite.T().TempDir()
+	suite.Require().NoError(os.WriteFile(filepath.Join(dir, "address.template.json"), []byte(`{ "city": "{{ Address.city }}" }`), 0644))
+	path := filepath.Join(dir, "user.template.yaml")
+	suite.Require().NoError(os.WriteFile(path, []byte("name: \"{{ Person.name }}\"\naddress:\n  $include: address.template.json\n"), 0644))
+
+	tmpl, err := CompileFile(path, WithSeed(2))
+	suite.Require().NoError(err)
+	var record struct {
+		Name    string `json:"name"`
+		Address struct {
+			City string `json:"city"`
+		} `json:"address"`
+	}
+	assert.NoError(suite.T(), tmpl.RenderInto(&record))
+	assert.NotEmpty(suite.T(), record.Name)
+	assert.NotEmpty(suite.T(), record.Address.City, "includes are relative to the template file")
+
+	_, err = CompileFile(filepath.Join(dir, "missing.template.json"))
+	assert.ErrorContains(suite.T(), err, "failed to read template")
+}